@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/drewnorman/jt-slackbot/core/internal/configuration"
+	"github.com/drewnorman/jt-slackbot/core/internal/events"
+	"github.com/drewnorman/jt-slackbot/core/internal/health"
+	"go.uber.org/zap"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// usage describes the available subcommands.
+const usage = `usage: jt-slackbot-core [command]
+
+With no command, runs the bot.
+
+commands:
+  check-config          print the effective configuration
+  dead-letters list     list events that failed processing
+  dead-letters replay   process dead-lettered events again
+  healthcheck           exit 0 if the running bot is ready
+  healthcheck --live    exit 0 if the running bot is connected,
+                        whether or not the dialog service is up
+`
+
+// runCommand runs the subcommand named by the
+// first of the given arguments and returns the
+// exit code for the process.
+func runCommand(
+	ctx context.Context,
+	config *configuration.Configuration,
+	logger *zap.Logger,
+	args []string,
+) int {
+	switch args[0] {
+	case "dead-letters":
+		return runDeadLetters(ctx, config, logger, args[1:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+}
+
+// runDeadLetters lists or replays the events
+// in the dead letter store.
+func runDeadLetters(
+	ctx context.Context,
+	config *configuration.Configuration,
+	logger *zap.Logger,
+	args []string,
+) int {
+	if len(args) != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "list":
+		store, err := events.NewDeadLetterStore(config.DeadLetterPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to open dead letters: %s\n", err)
+			return 1
+		}
+		letters, err := store.List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to list dead letters: %s\n", err)
+			return 1
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "EVENT ID\tTYPE\tATTEMPTS\tFAILED AT\tERROR")
+		for _, letter := range letters {
+			fmt.Fprintf(
+				writer,
+				"%s\t%s\t%d\t%s\t%s\n",
+				letter.EventId,
+				letter.EventType,
+				letter.Attempts,
+				letter.FailedAt.Format(time.RFC3339),
+				letter.Error,
+			)
+		}
+		_ = writer.Flush()
+		return 0
+	case "replay":
+		slackBot, err := newBot(config, logger)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create bot: %s\n", err)
+			return 1
+		}
+		replayed, remaining, err := slackBot.ReplayDeadLetters(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to replay dead letters: %s\n", err)
+			return 1
+		}
+		fmt.Printf(
+			"replayed %d dead letters, %d remaining\n",
+			replayed,
+			remaining,
+		)
+		if remaining > 0 {
+			return 1
+		}
+		return 0
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+}
+
+// healthcheckTimeout defines the duration of
+// time to wait for the running bot to report
+// whether it is ready.
+const healthcheckTimeout = 5 * time.Second
+
+// runHealthcheck asks the health server of the
+// running bot whether it is ready, or with
+// --live only whether it is alive and
+// connected to Slack. Only the address of the
+// health server is loaded from the given
+// configuration.
+func runHealthcheck(
+	ctx context.Context,
+	config *configuration.Configuration,
+	args []string,
+) int {
+	path := "/readyz"
+	switch {
+	case len(args) == 0:
+	case len(args) == 1 && args[0] == "--live":
+		path = "/livez"
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	err := config.LoadHealthAddr()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load configuration: %s\n", err)
+		return 1
+	}
+	if config.HealthAddr == "" {
+		fmt.Fprintln(os.Stderr, "health server is disabled, set HEALTH_ADDR")
+		return 1
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthcheckTimeout)
+	defer cancel()
+	err = health.Probe(ctx, config.HealthAddr, path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "not ready: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runCheckConfig prints the effective value of
+// every setting in the form of the configuration
+// file, noting where each came from, followed by
+// any problems found loading the configuration.
+// Secrets are redacted.
+func runCheckConfig(
+	config *configuration.Configuration,
+	loadErr error,
+) int {
+	fmt.Printf("# configuration file: %s\n", config.ConfigFile)
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 1, ' ', 0)
+	for _, setting := range config.Settings() {
+		fmt.Fprintf(
+			writer,
+			"%s: %s\t# %s\n",
+			strings.ToLower(setting.Key),
+			strconv.Quote(setting.Value),
+			setting.Source,
+		)
+	}
+	_ = writer.Flush()
+
+	if loadErr == nil {
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, "invalid configuration:")
+	problems, ok := loadErr.(*configuration.ValidationError)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "  %s\n", loadErr)
+		return 1
+	}
+	for _, problem := range problems.Problems {
+		fmt.Fprintf(os.Stderr, "  %s\n", problem)
+	}
+	return 1
+}
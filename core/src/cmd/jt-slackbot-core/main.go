@@ -1,74 +1,379 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/drewnorman/jt-slackbot/core/internal/admin"
 	"github.com/drewnorman/jt-slackbot/core/internal/bot"
 	"github.com/drewnorman/jt-slackbot/core/internal/configuration"
+	"github.com/drewnorman/jt-slackbot/core/internal/events"
+	"github.com/drewnorman/jt-slackbot/core/internal/health"
+	"github.com/drewnorman/jt-slackbot/core/internal/leader"
 	"github.com/drewnorman/jt-slackbot/core/internal/logging"
+	"github.com/drewnorman/jt-slackbot/core/internal/metrics"
+	"github.com/drewnorman/jt-slackbot/core/internal/tracing"
 	"go.uber.org/zap"
 	"gopkg.in/natefinch/lumberjack.v2"
 	"io"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
-// maxLogFileSizeInMb, maxLogBackups, and
-// maxLogAgeInDays determine the settings
-// for log file management
-const (
-	maxLogFileSizeInMb = 10
-	maxLogBackups      = 3
-	maxLogAgeInDays    = 28
-)
+// tracingShutdownTimeout defines the duration
+// of time to wait for remaining spans to be
+// exported on shutdown.
+const tracingShutdownTimeout = 5 * time.Second
 
 // main loads a configuration from which it creates
-// a bot that runs until failure or signal interrupt
+// a bot that runs until failure or signal interrupt,
+// reloading the configuration on hangup, or runs
+// the subcommand named by the first argument.
+// check-config runs even if the configuration
+// is not valid, to report why, and healthcheck
+// only reads the address of the health server.
 func main() {
 	config := configuration.NewConfiguration()
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		ctx, cancel := interruptContext()
+		code := runHealthcheck(ctx, config, os.Args[2:])
+		cancel()
+		os.Exit(code)
+	}
 	err := config.Load()
+	if len(os.Args) > 1 && os.Args[1] == "check-config" {
+		os.Exit(runCheckConfig(config, err))
+	}
 	if err != nil {
 		log.Fatalf("failed to load configuration: %s", err)
 	}
 
+	logLevel := zap.NewAtomicLevelAt(config.LogLevel)
 	logger := logging.NewLogger(
 		logging.LoggerParameters{
-			Level: config.LogLevel,
+			AtomicLevel: &logLevel,
 			Writers: []io.Writer{
 				&lumberjack.Logger{
-					Filename:   "/var/log/jt-slackbot-core/main.log",
-					MaxSize:    maxLogFileSizeInMb,
-					MaxBackups: maxLogBackups,
-					MaxAge:     maxLogAgeInDays,
+					Filename:   config.LogFilePath,
+					MaxSize:    config.LogMaxSize,
+					MaxBackups: config.LogMaxBackups,
+					MaxAge:     config.LogMaxAge,
 				},
 			},
 		},
 	)
 
+	ctx, cancel := interruptContext()
+
+	if len(os.Args) > 1 {
+		code := runCommand(ctx, config, logger, os.Args[1:])
+		cancel()
+		os.Exit(code)
+	}
+
+	tracingProvider, err := tracing.NewProvider(
+		&tracing.ProviderParameters{
+			Exporter: config.TracingExporter,
+			FilePath: config.TracingFilePath,
+		},
+	)
+	if err != nil {
+		logger.Error(
+			"failed to set up tracing",
+			zap.String("err", err.Error()),
+		)
+		cancel()
+		os.Exit(1)
+	}
+
 	logger.Info("creating new bot")
-	slackBot, err := bot.New(&bot.Parameters{
-		Logger:             logger,
-		ApiUrl:             config.ApiUrl,
-		AppToken:           config.AppToken,
-		BotToken:           config.BotToken,
-		MaxConnectAttempts: config.MaxConnectAttempts,
-		DebugWssReconnects: config.DebugWssReconnects,
-	})
+	botMetrics := metrics.New()
+	elector, err := newElector(config, logger, botMetrics)
+	if err != nil {
+		logger.Error(
+			"failed to set up leader election",
+			zap.String("err", err.Error()),
+		)
+		cancel()
+		stopTracing(tracingProvider, logger)
+		os.Exit(1)
+	}
+	params := newParameters(config, logger)
+	params.LogLevel = &logLevel
+	params.Metrics = botMetrics
+	params.Elector = elector
+	params.LoadParameters = func() (*bot.Parameters, error) {
+		err := config.Reload()
+		if err != nil {
+			return nil, err
+		}
+		reloaded := newParameters(config, logger)
+		level := zap.NewAtomicLevelAt(config.LogLevel)
+		reloaded.LogLevel = &level
+		reloaded.Metrics = botMetrics
+		reloaded.Elector = elector
+		return reloaded, nil
+	}
+	slackBot, err := bot.New(params)
 	if err != nil {
 		logger.Error(
 			"failed to create bot",
 			zap.String("err", err.Error()),
 		)
+		cancel()
+		stopTracing(tracingProvider, logger)
 		os.Exit(1)
 	}
 
+	if config.HealthAddr != "" {
+		go serveHealth(ctx, config, logger, slackBot, botMetrics)
+	}
+	if config.AdminApiAddr != "" {
+		go serveAdmin(ctx, config, logger, slackBot)
+	}
+
 	logger.Info("starting bot")
-	err = slackBot.Run()
-	if err != nil {
+	err = slackBot.Run(ctx)
+	cancel()
+	if err != nil && !errors.Is(err, context.Canceled) {
 		logger.Error(
 			"error during bot execution",
 			zap.String("err", err.Error()),
 		)
 	}
 	logger.Info("stopped bot")
+	stopTracing(tracingProvider, logger)
 
 	os.Exit(0)
 }
+
+// newElector returns the leader elector named
+// by the configuration, or nil if leader
+// election is turned off. The hostname and
+// process ID identify the replica holding
+// the lease.
+func newElector(
+	config *configuration.Configuration,
+	logger *zap.Logger,
+	botMetrics *metrics.Metrics,
+) (*leader.Elector, error) {
+	if config.LeaderElection != "file" {
+		return nil, nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	lease, err := leader.NewFileLease(
+		config.LeaderLockPath,
+		fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return leader.NewElector(
+		&leader.ElectorParameters{
+			Logger:        logger,
+			Lease:         lease,
+			RetryInterval: config.LeaderRetryInterval,
+			Metrics:       botMetrics,
+		},
+	)
+}
+
+// stopTracing exports the remaining spans of
+// the given tracing provider and stops it.
+func stopTracing(provider *tracing.Provider, logger *zap.Logger) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		tracingShutdownTimeout,
+	)
+	defer cancel()
+	err := provider.Shutdown(ctx)
+	if err != nil {
+		logger.Warn(
+			"failed to stop tracing",
+			zap.String("err", err.Error()),
+		)
+	}
+}
+
+// serveHealth serves the health endpoints and
+// the metrics of the given bot until the given
+// context is done, logging any failure of
+// the server.
+func serveHealth(
+	ctx context.Context,
+	config *configuration.Configuration,
+	logger *zap.Logger,
+	slackBot *bot.Bot,
+	botMetrics *metrics.Metrics,
+) {
+	server, err := health.NewServer(
+		&health.ServerParameters{
+			Logger: logger,
+			Addr:   config.HealthAddr,
+			Checks: map[string]health.Check{
+				"authentication": slackBot.CheckAuthentication,
+				"socketMode":     slackBot.CheckConnection,
+				"dialog":         slackBot.CheckDialog,
+			},
+			LiveChecks: []string{"socketMode"},
+			Metrics:    botMetrics.Handler(),
+		},
+	)
+	if err == nil {
+		err = server.Run(ctx)
+	}
+	if err != nil {
+		logger.Error(
+			"failed to serve health endpoints",
+			zap.String("err", err.Error()),
+		)
+	}
+}
+
+// serveAdmin serves the admin API operating
+// the given bot until the given context is
+// done, logging any failure of the server.
+func serveAdmin(
+	ctx context.Context,
+	config *configuration.Configuration,
+	logger *zap.Logger,
+	slackBot *bot.Bot,
+) {
+	server, err := admin.NewServer(
+		&admin.ServerParameters{
+			Logger:   logger,
+			Addr:     config.AdminApiAddr,
+			Token:    config.AdminApiToken,
+			Operator: slackBot,
+		},
+	)
+	if err == nil {
+		err = server.Run(ctx)
+	}
+	if err != nil {
+		logger.Error(
+			"failed to serve admin api",
+			zap.String("err", err.Error()),
+		)
+	}
+}
+
+// interruptContext returns a context that is
+// cancelled when the process is interrupted or
+// terminated, or when the returned function
+// is called.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+		cancel()
+	}()
+	return ctx, cancel
+}
+
+// newBot returns a new bot according to
+// the given configuration.
+func newBot(
+	config *configuration.Configuration,
+	logger *zap.Logger,
+) (*bot.Bot, error) {
+	return bot.New(newParameters(config, logger))
+}
+
+// newParameters returns the parameters for a
+// new bot according to the given configuration.
+func newParameters(
+	config *configuration.Configuration,
+	logger *zap.Logger,
+) *bot.Parameters {
+	return &bot.Parameters{
+		Logger:                 logger,
+		ApiUrl:                 config.ApiUrl,
+		AppToken:               config.AppToken,
+		BotToken:               config.BotToken,
+		MaxConnectAttempts:     config.MaxConnectAttempts,
+		DebugWssReconnects:     config.DebugWssReconnects,
+		IgnoreBots:             config.IgnoreBots,
+		AllowedBotIds:          config.AllowedBotIds,
+		BotLoopThreshold:       config.BotLoopThreshold,
+		BotLoopWindow:          config.BotLoopWindow,
+		SyncReplies:            config.SyncReplies,
+		SyncRepliesByChannel:   config.SyncRepliesByChannel,
+		EventMaxAttempts:       config.EventMaxAttempts,
+		EventRetryDelay:        config.EventRetryDelay,
+		DeadLetterPath:         config.DeadLetterPath,
+		PendingEventsPath:      config.PendingEventsPath,
+		DrainGracePeriod:       config.DrainGracePeriod,
+		SlackTimeout:           config.SlackTimeout,
+		ProcessedEventsSize:    config.ProcessedEventsSize,
+		EventTimeout:           config.EventTimeout,
+		UserRateLimit:          rateLimit(config.UserRateLimit),
+		ChannelRateLimit:       rateLimit(config.ChannelRateLimit),
+		GlobalRateLimit:        rateLimit(config.GlobalRateLimit),
+		DialogUrl:              config.DialogUrl,
+		DialogTimeout:          config.DialogTimeout,
+		DialogMaxRetries:       config.DialogMaxRetries,
+		DialogHistoryLength:    config.DialogHistoryLength,
+		DialogMaxIdleConns:     config.DialogMaxIdleConns,
+		ConfidencePolicy:       confidencePolicy(config),
+		FeedbackLogPath:        config.FeedbackLogPath,
+		ForwardFeedback:        config.ForwardFeedback,
+		PositiveReactions:      config.PositiveReactions,
+		NegativeReactions:      config.NegativeReactions,
+		TrainerUserIds:         config.TrainerUserIds,
+		Responder:              config.Responder,
+		ResponderByChannel:     config.ResponderByChannel,
+		RulesPath:              config.RulesPath,
+		ChatCompletionsUrl:     config.ChatCompletionsUrl,
+		ChatCompletionsKey:     config.ChatCompletionsKey,
+		ChatCompletionsModel:   config.ChatCompletionsModel,
+		ChatCompletionsPrompt:  config.ChatCompletionsPrompt,
+		DialogBreakerThreshold: config.DialogBreakerThreshold,
+		DialogBreakerCooldown:  config.DialogBreakerCooldown,
+		DialogBreakerProbes:    config.DialogBreakerProbes,
+		FallbackResponder:      config.FallbackResponder,
+		FallbackReply:          config.FallbackReply,
+		MaintenanceReply:       config.MaintenanceReply,
+		FallbackRecallSize:     config.FallbackRecallSize,
+		ResponseCacheTtl:       config.ResponseCacheTtl,
+		ResponseCacheSize:      config.ResponseCacheSize,
+		ResponseCacheByChannel: config.ResponseCacheScope == "channel",
+		AdminUserIds:           config.AdminUserIds,
+		IntentDetection:        config.IntentDetection,
+		IntentResponders:       config.IntentResponders,
+		HelpKeywords:           config.HelpKeywords,
+		GreetingKeywords:       config.GreetingKeywords,
+	}
+}
+
+// confidencePolicy returns the events confidence
+// policy described by the given configuration.
+func confidencePolicy(config *configuration.Configuration) events.ConfidencePolicy {
+	return events.ConfidencePolicy{
+		Threshold:          config.ConfidenceThreshold,
+		ThresholdByChannel: config.ConfidenceThresholdByChannel,
+		Action:             config.LowConfidenceAction,
+		Clarification:      config.ClarifyReply,
+	}
+}
+
+// rateLimit converts the given configured rate
+// limit into an events rate limit.
+func rateLimit(limit configuration.RateLimit) events.RateLimit {
+	return events.RateLimit{
+		Count:  limit.Count,
+		Period: limit.Period,
+	}
+}
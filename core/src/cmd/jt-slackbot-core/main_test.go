@@ -0,0 +1,98 @@
+package main
+
+import (
+	"github.com/drewnorman/jt-slackbot/core/internal/configuration"
+	"github.com/drewnorman/jt-slackbot/core/internal/secrets"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"reflect"
+	"testing"
+)
+
+func fakeZapLogger() *zap.Logger {
+	return zap.New(
+		zapcore.NewCore(
+			zapcore.NewJSONEncoder(zap.NewDevelopmentEncoderConfig()),
+			zapcore.AddSync(nil),
+			zap.DebugLevel,
+		),
+	)
+}
+
+// fill sets every settable field of the given
+// value to a value other than its zero value.
+func fill(value reflect.Value) {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString("channel")
+	case reflect.Bool:
+		value.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value.SetInt(1)
+	case reflect.Float32, reflect.Float64:
+		value.SetFloat(0.5)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(value.Type(), 1, 1)
+		fill(slice.Index(0))
+		value.Set(slice)
+	case reflect.Map:
+		item := reflect.New(value.Type().Elem()).Elem()
+		fill(item)
+		mapValue := reflect.MakeMap(value.Type())
+		mapValue.SetMapIndex(reflect.ValueOf("C0123"), item)
+		value.Set(mapValue)
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if value.Field(i).CanSet() {
+				fill(value.Field(i))
+			}
+		}
+	}
+}
+
+// zeroFields returns the names of the exported
+// fields of the given struct, and of the structs
+// it holds, that are left at their zero value.
+func zeroFields(value reflect.Value, prefix string) []string {
+	var names []string
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := prefix + field.Name
+		if field.Type.Kind() == reflect.Struct && field.Type.NumField() > 0 &&
+			field.Type.Field(0).PkgPath == "" {
+			names = append(names, zeroFields(value.Field(i), name+".")...)
+			continue
+		}
+		if value.Field(i).IsZero() {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func TestNewParameters(t *testing.T) {
+	config := &configuration.Configuration{}
+	fill(reflect.ValueOf(config).Elem())
+	config.AppToken = secrets.New("xapp-token")
+	config.BotToken = secrets.New("xoxb-token")
+	config.ChatCompletionsKey = secrets.New("api-key")
+
+	params := newParameters(config, fakeZapLogger())
+
+	// Set by main rather than from the
+	// configuration.
+	setElsewhere := map[string]bool{
+		"LogLevel":       true,
+		"LoadParameters": true,
+		"Metrics":        true,
+		"Elector":        true,
+	}
+	for _, name := range zeroFields(reflect.ValueOf(params).Elem(), "") {
+		if !setElsewhere[name] {
+			t.Errorf("newParameters() left %s unset", name)
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/drewnorman/jt-slackbot/core/internal/events"
+	"github.com/drewnorman/jt-slackbot/core/internal/secrets"
+	"github.com/drewnorman/jt-slackbot/core/internal/slack"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// An Operator is the running bot operated
+// through the admin API.
+type Operator interface {
+	JoinedChannels(ctx context.Context) ([]*slack.Channel, error)
+	Reconnect() error
+	PrepareWorkspace(ctx context.Context) error
+	PostMessage(ctx context.Context, channelId string, message string) (string, error)
+	LogLevel() zapcore.Level
+	SetLogLevel(level zapcore.Level) error
+	ProcessedEvents() []string
+	DeadLetters() ([]events.DeadLetter, error)
+	Maintenance() bool
+	SetMaintenance(enabled bool)
+}
+
+// An admin.Server exposes an HTTP API under
+// /admin/ for operating the running bot. Every
+// request must carry the configured token as
+// a bearer token.
+type Server struct {
+	logger   *zap.Logger
+	addr     string
+	token    secrets.Secret
+	operator Operator
+}
+
+// admin.ServerParameters describe how to
+// create a new admin.Server.
+type ServerParameters struct {
+	Logger   *zap.Logger
+	Addr     string
+	Token    secrets.Secret
+	Operator Operator
+}
+
+// A Channel describes a channel the bot
+// is a member of.
+type Channel struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// A MessageRequest describes a message to
+// post as the bot.
+type MessageRequest struct {
+	Channel string `json:"channel"`
+	Text    string `json:"text"`
+}
+
+// A MessageResponse identifies a message
+// posted as the bot.
+type MessageResponse struct {
+	Channel string `json:"channel"`
+	Ts      string `json:"ts"`
+}
+
+// A LogLevel describes the lowest level of
+// the messages being logged.
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+// A Maintenance describes whether maintenance
+// mode is on.
+type Maintenance struct {
+	Enabled bool `json:"enabled"`
+}
+
+// An Error describes why a request failed.
+type Error struct {
+	Error string `json:"error"`
+}
+
+// shutdownTimeout defines the duration of time
+// to wait for open requests when the server
+// is stopped.
+const shutdownTimeout = 5 * time.Second
+
+// maxRequestSize limits the size of the
+// request bodies read by the server.
+const maxRequestSize = 64 * 1024
+
+// NewServer returns a new admin.Server
+// according to the given parameters.
+func NewServer(params *ServerParameters) (*Server, error) {
+	if params.Logger == nil {
+		return nil, errors.New("missing logger")
+	}
+	if params.Addr == "" {
+		return nil, errors.New("missing address")
+	}
+	if params.Token.Empty() {
+		return nil, errors.New("missing token")
+	}
+	if params.Operator == nil {
+		return nil, errors.New("missing operator")
+	}
+	return &Server{
+		logger:   params.Logger,
+		addr:     params.Addr,
+		token:    params.Token,
+		operator: params.Operator,
+	}, nil
+}
+
+// Run serves the admin API until the given
+// context is done or the server fails.
+func (server *Server) Run(ctx context.Context) error {
+	httpServer := &http.Server{
+		Addr:    server.addr,
+		Handler: server.Handler(),
+	}
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(
+				context.Background(),
+				shutdownTimeout,
+			)
+			defer cancel()
+			_ = httpServer.Shutdown(shutdownCtx)
+		case <-stopped:
+		}
+	}()
+
+	server.logger.Info(
+		"serving admin api",
+		zap.String("addr", server.addr),
+	)
+	err := httpServer.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Handler returns the HTTP handler serving
+// the admin API.
+func (server *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/channels", server.serveChannels)
+	mux.HandleFunc("/admin/reconnect", server.serveReconnect)
+	mux.HandleFunc("/admin/workspace/prepare", server.servePrepareWorkspace)
+	mux.HandleFunc("/admin/messages", server.serveMessages)
+	mux.HandleFunc("/admin/log-level", server.serveLogLevel)
+	mux.HandleFunc("/admin/processed-events", server.serveProcessedEvents)
+	mux.HandleFunc("/admin/dead-letters", server.serveDeadLetters)
+	mux.HandleFunc("/admin/maintenance", server.serveMaintenance)
+	return server.authenticate(mux)
+}
+
+// bearerPrefix starts the Authorization header
+// of authenticated requests.
+const bearerPrefix = "Bearer "
+
+// authenticate rejects requests that do not
+// carry the token of the server as a bearer
+// token before passing them to the given
+// handler.
+func (server *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			authorization := r.Header.Get("Authorization")
+			token := strings.TrimPrefix(authorization, bearerPrefix)
+			if token == authorization ||
+				subtle.ConstantTimeCompare([]byte(token), []byte(server.token.Reveal())) != 1 {
+				server.logger.Warn(
+					"rejected unauthenticated admin request",
+					zap.String("path", r.URL.Path),
+					zap.String("remoteAddr", r.RemoteAddr),
+				)
+				server.writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+				return
+			}
+			server.logger.Info(
+				"serving admin request",
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+			)
+			next.ServeHTTP(w, r)
+		},
+	)
+}
+
+// serveChannels lists the channels the bot
+// is a member of.
+func (server *Server) serveChannels(w http.ResponseWriter, r *http.Request) {
+	if !server.allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	joined, err := server.operator.JoinedChannels(r.Context())
+	if err != nil {
+		server.writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	channels := make([]Channel, 0, len(joined))
+	for _, channel := range joined {
+		channels = append(channels, Channel{
+			Id:   channel.Id,
+			Name: channel.Name,
+			Type: channel.Type,
+		})
+	}
+	server.writeJson(w, http.StatusOK, channels)
+}
+
+// serveReconnect forces the bot to open a new
+// Socket Mode connection.
+func (server *Server) serveReconnect(w http.ResponseWriter, r *http.Request) {
+	if !server.allowMethods(w, r, http.MethodPost) {
+		return
+	}
+	err := server.operator.Reconnect()
+	if err != nil {
+		server.writeError(w, http.StatusConflict, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// servePrepareWorkspace makes the bot try to
+// join every public channel again.
+func (server *Server) servePrepareWorkspace(w http.ResponseWriter, r *http.Request) {
+	if !server.allowMethods(w, r, http.MethodPost) {
+		return
+	}
+	err := server.operator.PrepareWorkspace(r.Context())
+	if err != nil {
+		server.writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveMessages posts a message as the bot.
+func (server *Server) serveMessages(w http.ResponseWriter, r *http.Request) {
+	if !server.allowMethods(w, r, http.MethodPost) {
+		return
+	}
+	var message MessageRequest
+	if !server.readJson(w, r, &message) {
+		return
+	}
+	if message.Channel == "" || message.Text == "" {
+		server.writeError(w, http.StatusBadRequest, errors.New("missing channel or text"))
+		return
+	}
+	ts, err := server.operator.PostMessage(r.Context(), message.Channel, message.Text)
+	if err != nil {
+		server.writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	server.writeJson(w, http.StatusCreated, MessageResponse{
+		Channel: message.Channel,
+		Ts:      ts,
+	})
+}
+
+// serveLogLevel reports or changes the lowest
+// level of the messages being logged.
+func (server *Server) serveLogLevel(w http.ResponseWriter, r *http.Request) {
+	if !server.allowMethods(w, r, http.MethodGet, http.MethodPut) {
+		return
+	}
+	if r.Method == http.MethodPut {
+		var logLevel LogLevel
+		if !server.readJson(w, r, &logLevel) {
+			return
+		}
+		var level zapcore.Level
+		err := level.UnmarshalText([]byte(logLevel.Level))
+		if err != nil {
+			server.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		err = server.operator.SetLogLevel(level)
+		if err != nil {
+			server.writeError(w, http.StatusConflict, err)
+			return
+		}
+	}
+	server.writeJson(w, http.StatusOK, LogLevel{
+		Level: server.operator.LogLevel().String(),
+	})
+}
+
+// serveProcessedEvents lists the IDs of the
+// events most recently processed, which are
+// used to ignore duplicate deliveries.
+func (server *Server) serveProcessedEvents(w http.ResponseWriter, r *http.Request) {
+	if !server.allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	server.writeJson(w, http.StatusOK, server.operator.ProcessedEvents())
+}
+
+// serveDeadLetters lists the events that could
+// not be processed.
+func (server *Server) serveDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if !server.allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	letters, err := server.operator.DeadLetters()
+	if err != nil {
+		server.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	server.writeJson(w, http.StatusOK, letters)
+}
+
+// serveMaintenance reports or toggles
+// maintenance mode.
+func (server *Server) serveMaintenance(w http.ResponseWriter, r *http.Request) {
+	if !server.allowMethods(w, r, http.MethodGet, http.MethodPut) {
+		return
+	}
+	if r.Method == http.MethodPut {
+		var maintenance Maintenance
+		if !server.readJson(w, r, &maintenance) {
+			return
+		}
+		server.operator.SetMaintenance(maintenance.Enabled)
+	}
+	server.writeJson(w, http.StatusOK, Maintenance{
+		Enabled: server.operator.Maintenance(),
+	})
+}
+
+// allowMethods returns true if the request uses
+// one of the given methods, or else responds
+// that the method is not allowed.
+func (server *Server) allowMethods(
+	w http.ResponseWriter,
+	r *http.Request,
+	methods ...string,
+) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	server.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	return false
+}
+
+// readJson decodes the body of the request into
+// the given value, or else responds that the
+// request is invalid and returns false.
+func (server *Server) readJson(
+	w http.ResponseWriter,
+	r *http.Request,
+	value interface{},
+) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(value)
+	if err != nil {
+		server.writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+// writeError writes the given error as JSON
+// with the given status code.
+func (server *Server) writeError(
+	w http.ResponseWriter,
+	statusCode int,
+	err error,
+) {
+	server.writeJson(w, statusCode, Error{Error: err.Error()})
+}
+
+// writeJson writes the given value as JSON
+// with the given status code.
+func (server *Server) writeJson(
+	w http.ResponseWriter,
+	statusCode int,
+	value interface{},
+) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		server.logger.Warn(
+			"failed to write admin response",
+			zap.String("err", err.Error()),
+		)
+	}
+}
@@ -0,0 +1,429 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/drewnorman/jt-slackbot/core/internal/events"
+	"github.com/drewnorman/jt-slackbot/core/internal/secrets"
+	"github.com/drewnorman/jt-slackbot/core/internal/slack"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func fakeZapLogger() *zap.Logger {
+	return zap.New(
+		zapcore.NewCore(
+			zapcore.NewJSONEncoder(
+				zap.NewProductionEncoderConfig(),
+			),
+			zapcore.AddSync(
+				os.NewFile(0, os.DevNull),
+			),
+			zap.FatalLevel,
+		),
+	)
+}
+
+type fakeOperator struct {
+	err         error
+	reconnected bool
+	prepared    bool
+	posted      []string
+	level       zapcore.Level
+	maintenance bool
+}
+
+func (operator *fakeOperator) JoinedChannels(ctx context.Context) ([]*slack.Channel, error) {
+	return []*slack.Channel{
+		{Id: "C1", Name: "general", Type: "channel"},
+	}, operator.err
+}
+
+func (operator *fakeOperator) Reconnect() error {
+	operator.reconnected = operator.err == nil
+	return operator.err
+}
+
+func (operator *fakeOperator) PrepareWorkspace(ctx context.Context) error {
+	operator.prepared = operator.err == nil
+	return operator.err
+}
+
+func (operator *fakeOperator) PostMessage(
+	ctx context.Context,
+	channelId string,
+	message string,
+) (string, error) {
+	operator.posted = append(operator.posted, channelId, message)
+	return "1234.5678", operator.err
+}
+
+func (operator *fakeOperator) LogLevel() zapcore.Level {
+	return operator.level
+}
+
+func (operator *fakeOperator) SetLogLevel(level zapcore.Level) error {
+	if operator.err != nil {
+		return operator.err
+	}
+	operator.level = level
+	return nil
+}
+
+func (operator *fakeOperator) ProcessedEvents() []string {
+	return []string{"Ev1"}
+}
+
+func (operator *fakeOperator) DeadLetters() ([]events.DeadLetter, error) {
+	return []events.DeadLetter{{EventId: "Ev2"}}, operator.err
+}
+
+func (operator *fakeOperator) Maintenance() bool {
+	return operator.maintenance
+}
+
+func (operator *fakeOperator) SetMaintenance(enabled bool) {
+	operator.maintenance = enabled
+}
+
+func TestNewServer(t *testing.T) {
+	type args struct {
+		params *ServerParameters
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "ReturnsServer",
+			args: args{
+				params: &ServerParameters{
+					Logger:   fakeZapLogger(),
+					Addr:     ":8081",
+					Token:    secrets.New(gofakeit.UUID()),
+					Operator: &fakeOperator{},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingLogger",
+			args: args{
+				params: &ServerParameters{
+					Addr:     ":8081",
+					Token:    secrets.New(gofakeit.UUID()),
+					Operator: &fakeOperator{},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingAddr",
+			args: args{
+				params: &ServerParameters{
+					Logger:   fakeZapLogger(),
+					Token:    secrets.New(gofakeit.UUID()),
+					Operator: &fakeOperator{},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingToken",
+			args: args{
+				params: &ServerParameters{
+					Logger:   fakeZapLogger(),
+					Addr:     ":8081",
+					Operator: &fakeOperator{},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingOperator",
+			args: args{
+				params: &ServerParameters{
+					Logger: fakeZapLogger(),
+					Addr:   ":8081",
+					Token:  secrets.New(gofakeit.UUID()),
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewServer(tt.args.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewServer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServer_Handler(t *testing.T) {
+	token := gofakeit.UUID()
+	type args struct {
+		method        string
+		path          string
+		authorization string
+		body          string
+		operator      *fakeOperator
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantCode int
+		wantBody string
+		check    func(operator *fakeOperator) bool
+	}{
+		{
+			name: "MissingToken",
+			args: args{
+				method:   http.MethodGet,
+				path:     "/admin/channels",
+				operator: &fakeOperator{},
+			},
+			wantCode: http.StatusUnauthorized,
+			wantBody: `"error":"unauthorized"`,
+		},
+		{
+			name: "InvalidToken",
+			args: args{
+				method:        http.MethodGet,
+				path:          "/admin/channels",
+				authorization: "Bearer " + gofakeit.UUID(),
+				operator:      &fakeOperator{},
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "TokenWithoutBearerPrefix",
+			args: args{
+				method:        http.MethodGet,
+				path:          "/admin/channels",
+				authorization: token,
+				operator:      &fakeOperator{},
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "OtherScheme",
+			args: args{
+				method:        http.MethodGet,
+				path:          "/admin/channels",
+				authorization: "Basic " + token,
+				operator:      &fakeOperator{},
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "ListsChannels",
+			args: args{
+				method:        http.MethodGet,
+				path:          "/admin/channels",
+				authorization: "Bearer " + token,
+				operator:      &fakeOperator{},
+			},
+			wantCode: http.StatusOK,
+			wantBody: `[{"id":"C1","name":"general","type":"channel"}]`,
+		},
+		{
+			name: "FailsToListChannels",
+			args: args{
+				method:        http.MethodGet,
+				path:          "/admin/channels",
+				authorization: "Bearer " + token,
+				operator:      &fakeOperator{err: errors.New("not_authed")},
+			},
+			wantCode: http.StatusBadGateway,
+			wantBody: `"error":"not_authed"`,
+		},
+		{
+			name: "MethodNotAllowed",
+			args: args{
+				method:        http.MethodGet,
+				path:          "/admin/reconnect",
+				authorization: "Bearer " + token,
+				operator:      &fakeOperator{},
+			},
+			wantCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name: "Reconnects",
+			args: args{
+				method:        http.MethodPost,
+				path:          "/admin/reconnect",
+				authorization: "Bearer " + token,
+				operator:      &fakeOperator{},
+			},
+			wantCode: http.StatusAccepted,
+			check: func(operator *fakeOperator) bool {
+				return operator.reconnected
+			},
+		},
+		{
+			name: "ReconnectsWhileDisconnected",
+			args: args{
+				method:        http.MethodPost,
+				path:          "/admin/reconnect",
+				authorization: "Bearer " + token,
+				operator:      &fakeOperator{err: errors.New("not connected to slack")},
+			},
+			wantCode: http.StatusConflict,
+		},
+		{
+			name: "PreparesWorkspace",
+			args: args{
+				method:        http.MethodPost,
+				path:          "/admin/workspace/prepare",
+				authorization: "Bearer " + token,
+				operator:      &fakeOperator{},
+			},
+			wantCode: http.StatusNoContent,
+			check: func(operator *fakeOperator) bool {
+				return operator.prepared
+			},
+		},
+		{
+			name: "PostsMessage",
+			args: args{
+				method:        http.MethodPost,
+				path:          "/admin/messages",
+				authorization: "Bearer " + token,
+				body:          `{"channel":"C1","text":"Woof!"}`,
+				operator:      &fakeOperator{},
+			},
+			wantCode: http.StatusCreated,
+			wantBody: `{"channel":"C1","ts":"1234.5678"}`,
+			check: func(operator *fakeOperator) bool {
+				return len(operator.posted) == 2 && operator.posted[1] == "Woof!"
+			},
+		},
+		{
+			name: "MissingMessageText",
+			args: args{
+				method:        http.MethodPost,
+				path:          "/admin/messages",
+				authorization: "Bearer " + token,
+				body:          `{"channel":"C1"}`,
+				operator:      &fakeOperator{},
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "ReportsLogLevel",
+			args: args{
+				method:        http.MethodGet,
+				path:          "/admin/log-level",
+				authorization: "Bearer " + token,
+				operator:      &fakeOperator{level: zapcore.WarnLevel},
+			},
+			wantCode: http.StatusOK,
+			wantBody: `{"level":"warn"}`,
+		},
+		{
+			name: "ChangesLogLevel",
+			args: args{
+				method:        http.MethodPut,
+				path:          "/admin/log-level",
+				authorization: "Bearer " + token,
+				body:          `{"level":"debug"}`,
+				operator:      &fakeOperator{level: zapcore.InfoLevel},
+			},
+			wantCode: http.StatusOK,
+			wantBody: `{"level":"debug"}`,
+		},
+		{
+			name: "InvalidLogLevel",
+			args: args{
+				method:        http.MethodPut,
+				path:          "/admin/log-level",
+				authorization: "Bearer " + token,
+				body:          `{"level":"loud"}`,
+				operator:      &fakeOperator{level: zapcore.InfoLevel},
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "ListsProcessedEvents",
+			args: args{
+				method:        http.MethodGet,
+				path:          "/admin/processed-events",
+				authorization: "Bearer " + token,
+				operator:      &fakeOperator{},
+			},
+			wantCode: http.StatusOK,
+			wantBody: `["Ev1"]`,
+		},
+		{
+			name: "ListsDeadLetters",
+			args: args{
+				method:        http.MethodGet,
+				path:          "/admin/dead-letters",
+				authorization: "Bearer " + token,
+				operator:      &fakeOperator{},
+			},
+			wantCode: http.StatusOK,
+			wantBody: `"event_id":"Ev2"`,
+		},
+		{
+			name: "TogglesMaintenance",
+			args: args{
+				method:        http.MethodPut,
+				path:          "/admin/maintenance",
+				authorization: "Bearer " + token,
+				body:          `{"enabled":true}`,
+				operator:      &fakeOperator{},
+			},
+			wantCode: http.StatusOK,
+			wantBody: `{"enabled":true}`,
+			check: func(operator *fakeOperator) bool {
+				return operator.maintenance
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := NewServer(
+				&ServerParameters{
+					Logger:   fakeZapLogger(),
+					Addr:     ":8081",
+					Token:    secrets.New(token),
+					Operator: tt.args.operator,
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			request := httptest.NewRequest(
+				tt.args.method,
+				tt.args.path,
+				strings.NewReader(tt.args.body),
+			)
+			if tt.args.authorization != "" {
+				request.Header.Set("Authorization", tt.args.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			server.Handler().ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantCode {
+				t.Errorf("Handler() code = %v, want %v", recorder.Code, tt.wantCode)
+			}
+			if !strings.Contains(recorder.Body.String(), tt.wantBody) {
+				t.Errorf("Handler() body = %v, want %v", recorder.Body.String(), tt.wantBody)
+			}
+			if tt.check != nil && !tt.check(tt.args.operator) {
+				t.Errorf("Handler() did not operate the bot as expected")
+			}
+		})
+	}
+}
@@ -1,41 +1,144 @@
 package bot
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"github.com/drewnorman/jt-slackbot/core/internal/dialog"
 	"github.com/drewnorman/jt-slackbot/core/internal/events"
+	"github.com/drewnorman/jt-slackbot/core/internal/leader"
+	"github.com/drewnorman/jt-slackbot/core/internal/metrics"
+	"github.com/drewnorman/jt-slackbot/core/internal/responders"
+	"github.com/drewnorman/jt-slackbot/core/internal/secrets"
 	"github.com/drewnorman/jt-slackbot/core/internal/slack"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"os"
 	"os/signal"
+	"reflect"
+	"sync"
 	"syscall"
 	"time"
 )
 
 // A Bot manages a Slack WebSocket connection and
-// processes events until failure or interrupt.
+// processes events until failure or until it
+// is stopped by its context.
 type Bot struct {
-	logger             *zap.Logger
-	apiUrl             string
-	appToken           string
-	botToken           string
-	maxConnectAttempts int
-	debugWssReconnects bool
-	httpClient         *slack.HttpClient
-	wsClient           *slack.WsClient
-	handler            *events.Handler
-	interrupt          chan os.Signal
+	logger               *zap.Logger
+	logLevel             *zap.AtomicLevel
+	loadParameters       func() (*Parameters, error)
+	metrics              *metrics.Metrics
+	elector              *leader.Elector
+	apiUrl               string
+	appToken             secrets.Secret
+	botToken             secrets.Secret
+	maxConnectAttempts   int
+	debugWssReconnects   bool
+	ignoreBots           bool
+	allowedBotIds        []string
+	botLoopThreshold     int
+	botLoopWindow        time.Duration
+	syncReplies          bool
+	syncRepliesByChannel map[string]bool
+	eventMaxAttempts     int
+	eventRetryDelay      time.Duration
+	eventTimeout         time.Duration
+	userRateLimit        events.RateLimit
+	channelRateLimit     events.RateLimit
+	globalRateLimit      events.RateLimit
+	deadLetters          *events.DeadLetterStore
+	pendingEvents        *events.DeadLetterStore
+	drainGracePeriod     time.Duration
+	slackTimeout         time.Duration
+	processedEventsSize  int
+	identity             *slack.Identity
+	httpClient           *slack.HttpClient
+	dialogClient         *dialog.Client
+	responder            responders.Responder
+	dialogHistoryLength  int
+	confidencePolicy     events.ConfidencePolicy
+	feedbackSinks        []events.FeedbackSink
+	positiveReactions    []string
+	negativeReactions    []string
+	trainerUserIds       []string
+	dialogResponder      *responders.DialogResponder
+	guardedDialog        *responders.RecallingResponder
+	responseCache        *responders.CachingResponder
+	maintenance          *responders.MaintenanceResponder
+	adminUserIds         []string
+	wsClient             *slack.WsClient
+	handler              *events.Handler
+	params               *Parameters
+	connected            bool
+	reconnect            chan struct{}
+	mutex                sync.Mutex
+	reloadMutex          sync.Mutex
 }
 
 // Parameters describe the configuration for
 // a new Bot.
 type Parameters struct {
-	Logger             *zap.Logger
-	ApiUrl             string
-	AppToken           string
-	BotToken           string
-	MaxConnectAttempts int
-	DebugWssReconnects bool
+	Logger                 *zap.Logger
+	LogLevel               *zap.AtomicLevel
+	LoadParameters         func() (*Parameters, error)
+	Metrics                *metrics.Metrics
+	Elector                *leader.Elector
+	ApiUrl                 string
+	AppToken               secrets.Secret
+	BotToken               secrets.Secret
+	MaxConnectAttempts     int
+	DebugWssReconnects     bool
+	IgnoreBots             bool
+	AllowedBotIds          []string
+	BotLoopThreshold       int
+	BotLoopWindow          time.Duration
+	SyncReplies            bool
+	SyncRepliesByChannel   map[string]bool
+	EventMaxAttempts       int
+	EventRetryDelay        time.Duration
+	DeadLetterPath         string
+	PendingEventsPath      string
+	DrainGracePeriod       time.Duration
+	SlackTimeout           time.Duration
+	ProcessedEventsSize    int
+	EventTimeout           time.Duration
+	UserRateLimit          events.RateLimit
+	ChannelRateLimit       events.RateLimit
+	GlobalRateLimit        events.RateLimit
+	DialogUrl              string
+	DialogTimeout          time.Duration
+	DialogMaxRetries       int
+	DialogMaxIdleConns     int
+	DialogHistoryLength    int
+	ConfidencePolicy       events.ConfidencePolicy
+	FeedbackLogPath        string
+	ForwardFeedback        bool
+	PositiveReactions      []string
+	NegativeReactions      []string
+	TrainerUserIds         []string
+	Responder              string
+	ResponderByChannel     map[string]string
+	RulesPath              string
+	ChatCompletionsUrl     string
+	ChatCompletionsKey     secrets.Secret
+	ChatCompletionsModel   string
+	ChatCompletionsPrompt  string
+	DialogBreakerThreshold int
+	DialogBreakerCooldown  time.Duration
+	DialogBreakerProbes    int
+	FallbackResponder      string
+	FallbackReply          string
+	FallbackRecallSize     int
+	ResponseCacheTtl       time.Duration
+	ResponseCacheSize      int
+	ResponseCacheByChannel bool
+	MaintenanceReply       string
+	AdminUserIds           []string
+	IntentDetection        bool
+	IntentResponders       map[string]string
+	HelpKeywords           []string
+	GreetingKeywords       []string
 }
 
 // defaultMaxConnectAttempts determines the
@@ -43,25 +146,77 @@ type Parameters struct {
 // Slack WebSocket connection process
 const defaultMaxConnectAttempts = 3
 
-// defaultEventProcessingTimeout defines the
-// duration of time to wait for event processing
-// to complete before stopping the bot entirely
-const defaultEventProcessingTimeout = 3 * time.Second
+// defaultDialogUrl defines the base URL of the
+// dialog service if none is specified
+const defaultDialogUrl = "http://localhost:5000/"
+
+// Names of the responders that may be
+// selected by default or per channel.
+const (
+	dialogResponderName          = "dialog"
+	rulesResponderName           = "rules"
+	chatCompletionsResponderName = "chat-completions"
+)
+
+// Defaults for the circuit breaker and fallback
+// chain protecting the dialog responder.
+const (
+	defaultDialogBreakerThreshold = 5
+	defaultDialogBreakerCooldown  = 30 * time.Second
+	defaultFallbackRecallSize     = 100
+	defaultFallbackReply          = "Sorry, I can't think straight right now. Try me again in a bit."
+)
+
+// Names of the built-in responders handling
+// deterministic intents.
+const (
+	helpResponderName     = "help"
+	greetingResponderName = "greeting"
+	commandResponderName  = "command"
+	faqResponderName      = "faq"
+)
+
+// defaultIntentResponders names the responders
+// handling each intent if none are specified.
+// Other intents go to the routed responders.
+var defaultIntentResponders = map[string]string{
+	responders.IntentHelp:     helpResponderName,
+	responders.IntentGreeting: greetingResponderName,
+	responders.IntentCommand:  commandResponderName,
+	responders.IntentQuestion: faqResponderName,
+}
+
+// Replies of the built-in help, command and
+// FAQ responders. The FAQ responder answers
+// from the rules first, if there are any.
+const (
+	defaultHelpReply = "Woof! I'm J.T. Mention me to chat, and I'll do my best to answer. " +
+		"React to my replies with :+1: or :-1: to let me know how I did."
+	defaultFaqReply = "Good question! I don't know that one yet. " +
+		"Mention me with `help` to see what I can do."
+	defaultCommandReply = "That looks like a command, but I only know `learn:`, `forget:` and `cache:`."
+)
+
+// defaultMaintenanceReply is the reply given
+// to every message while maintenance mode is
+// on if none is specified.
+const defaultMaintenanceReply = "J.T. is at the groomer right now. Try me again in a bit."
+
+// defaultResponseCacheSize determines the number
+// of dialog responses cached if none is specified.
+const defaultResponseCacheSize = 500
+
+// defaultDrainGracePeriod defines the duration
+// of time to wait for in-flight events to be
+// processed before abandoning them on shutdown.
+const defaultDrainGracePeriod = 30 * time.Second
 
 // New returns a new instance of Bot according
 // to the given parameters.
 func New(params *Parameters) (*Bot, error) {
-	if params.Logger == nil {
-		return nil, errors.New("missing logger")
-	}
-	if params.ApiUrl == "" {
-		return nil, errors.New("missing api url")
-	}
-	if params.AppToken == "" {
-		return nil, errors.New("missing app token")
-	}
-	if params.BotToken == "" {
-		return nil, errors.New("missing bot token")
+	err := validate(params)
+	if err != nil {
+		return nil, err
 	}
 
 	maxConnectAttempts := defaultMaxConnectAttempts
@@ -75,19 +230,41 @@ func New(params *Parameters) (*Bot, error) {
 
 	bot := &Bot{
 		logger:             params.Logger,
+		logLevel:           params.LogLevel,
+		loadParameters:     params.LoadParameters,
+		metrics:            params.Metrics,
+		elector:            params.Elector,
 		apiUrl:             params.ApiUrl,
 		appToken:           params.AppToken,
 		botToken:           params.BotToken,
 		maxConnectAttempts: maxConnectAttempts,
 		debugWssReconnects: debugWssReconnects,
+		slackTimeout:       params.SlackTimeout,
+		reconnect:          make(chan struct{}, 1),
+	}
+
+	bot.deadLetters, err = newDeadLetterStore(params.DeadLetterPath)
+	if err != nil {
+		return nil, err
+	}
+	bot.pendingEvents, err = newDeadLetterStore(params.PendingEventsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	bot.drainGracePeriod = defaultDrainGracePeriod
+	if params.DrainGracePeriod > 0 {
+		bot.drainGracePeriod = params.DrainGracePeriod
 	}
 
 	httpClient, err := slack.NewHttpClient(
 		&slack.HttpClientParameters{
 			Logger:   bot.logger,
 			ApiUrl:   bot.apiUrl,
-			AppToken: bot.appToken,
-			BotToken: bot.botToken,
+			AppToken: bot.appToken.Reveal(),
+			BotToken: bot.botToken.Reveal(),
+			Timeout:  bot.slackTimeout,
+			Metrics:  bot.metrics,
 		},
 	)
 	if err != nil {
@@ -95,40 +272,144 @@ func New(params *Parameters) (*Bot, error) {
 	}
 	bot.httpClient = httpClient
 
-	bot.interrupt = make(chan os.Signal, 1)
-	signal.Notify(
-		bot.interrupt,
-		syscall.SIGINT,
-		syscall.SIGTERM,
+	dialogClient, err := newDialogClient(params, bot.metrics)
+	if err != nil {
+		return nil, err
+	}
+	bot.dialogClient = dialogClient
+	bot.dialogResponder, err = responders.NewDialogResponder(dialogClient)
+	if err != nil {
+		return nil, err
+	}
+
+	bot.feedbackSinks, err = newFeedbackSinks(params, dialogClient)
+	if err != nil {
+		return nil, err
+	}
+
+	bot.guardedDialog, bot.responseCache, err = newGuardedDialogResponder(
+		params,
+		bot.dialogResponder,
+		bot.metrics,
 	)
+	if err != nil {
+		return nil, err
+	}
+	responder, err := newResponder(params, bot.guardedDialog)
+	if err != nil {
+		return nil, err
+	}
+	bot.maintenance, err = newMaintenanceResponder(params, responder)
+	if err != nil {
+		return nil, err
+	}
+	bot.responder = bot.maintenance
 
+	bot.applySettings(params)
 	return bot, nil
 }
 
-// Run connects to Slack, prepares the workspace,
-// and executes the main sequence until it
-// encounters an error or is explicitly told to
-// stop and not restart
-func (bot *Bot) Run() error {
+// validate returns an error if the given
+// parameters are missing a required setting.
+func validate(params *Parameters) error {
+	if params.Logger == nil {
+		return errors.New("missing logger")
+	}
+	if params.ApiUrl == "" {
+		return errors.New("missing api url")
+	}
+	if params.AppToken.Empty() {
+		return errors.New("missing app token")
+	}
+	if params.BotToken.Empty() {
+		return errors.New("missing bot token")
+	}
+	return nil
+}
+
+// applySettings sets the settings of the Bot
+// that can change while it is running and
+// keeps the given parameters to compare with
+// those of the next reload.
+func (bot *Bot) applySettings(params *Parameters) {
+	bot.ignoreBots = params.IgnoreBots
+	bot.allowedBotIds = params.AllowedBotIds
+	bot.botLoopThreshold = params.BotLoopThreshold
+	bot.botLoopWindow = params.BotLoopWindow
+	bot.syncReplies = params.SyncReplies
+	bot.syncRepliesByChannel = params.SyncRepliesByChannel
+	bot.eventMaxAttempts = params.EventMaxAttempts
+	bot.eventRetryDelay = params.EventRetryDelay
+	bot.eventTimeout = params.EventTimeout
+	bot.processedEventsSize = params.ProcessedEventsSize
+	bot.dialogHistoryLength = params.DialogHistoryLength
+	bot.confidencePolicy = params.ConfidencePolicy
+	bot.positiveReactions = params.PositiveReactions
+	bot.negativeReactions = params.NegativeReactions
+	bot.trainerUserIds = params.TrainerUserIds
+	bot.adminUserIds = params.AdminUserIds
+	bot.userRateLimit = params.UserRateLimit
+	bot.channelRateLimit = params.ChannelRateLimit
+	bot.globalRateLimit = params.GlobalRateLimit
+	bot.params = params
+}
+
+// Run validates authentication, prepares the
+// workspace, connects to Slack, and executes
+// the main sequence until it encounters an
+// error or the given context is done. With
+// leader election, the Bot only connects while
+// it leads, and otherwise stands by with the
+// workspace prepared, ready to take over.
+func (bot *Bot) Run(ctx context.Context) error {
+	err := bot.validateAuthentication(ctx)
+	if err != nil {
+		return err
+	}
+
+	bot.logger.Debug("creating events handler")
+	bot.mutex.Lock()
+	handler, err := bot.newHandler()
+	bot.handler = handler
+	bot.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	bot.logger.Debug("created events handler")
+
+	go bot.watchHangups(ctx)
+	go bot.campaign(ctx)
+
 	restart := true
-	var err error
 	for restart {
 		bot.logger.Info("preparing workspace")
-		err = bot.prepareWorkspace()
+		err = bot.prepareWorkspace(ctx)
 		if err != nil {
 			return err
 		}
 		bot.logger.Info("prepared workspace")
 
+		leadershipChanged, leading := bot.awaitLeadership(ctx)
+		if !leading {
+			return nil
+		}
+
+		err = bot.resumePendingEvents(ctx)
+		if err != nil {
+			return err
+		}
+
 		bot.logger.Info("connecting to slack")
-		err = bot.attemptToConnect()
+		err = bot.attemptToConnect(ctx)
 		if err != nil {
 			return err
 		}
+		bot.setConnected(true)
+		bot.metrics.Connected()
 		bot.logger.Info("connected to slack")
 
 		bot.logger.Info("executing main sequence")
-		restart, err = bot.executeMainSequence()
+		restart, err = bot.executeMainSequence(ctx, leadershipChanged)
 		if err != nil {
 			return err
 		}
@@ -141,17 +422,119 @@ func (bot *Bot) Run() error {
 	return err
 }
 
+// ReplayDeadLetters validates authentication
+// and processes every dead-lettered event again,
+// keeping only the events that still fail. It
+// returns the number of replayed and remaining
+// dead letters.
+func (bot *Bot) ReplayDeadLetters(ctx context.Context) (int, int, error) {
+	if bot.deadLetters == nil {
+		return 0, 0, errors.New("missing dead letter store")
+	}
+
+	letters, err := bot.deadLetters.List()
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(letters) == 0 {
+		return 0, 0, nil
+	}
+
+	err = bot.validateAuthentication(ctx)
+	if err != nil {
+		return 0, len(letters), err
+	}
+
+	handler, err := bot.newHandler()
+	if err != nil {
+		return 0, len(letters), err
+	}
+
+	remaining := handler.Replay(ctx, letters)
+	err = bot.deadLetters.Replace(remaining)
+	if err != nil {
+		return 0, len(letters), err
+	}
+	return len(letters) - len(remaining), len(remaining), nil
+}
+
+// resumePendingEvents processes the events that
+// were abandoned the last time the bot, or the
+// previous leader, stopped or disconnected,
+// dead-lettering any that still fail.
+func (bot *Bot) resumePendingEvents(ctx context.Context) error {
+	if bot.pendingEvents == nil {
+		return nil
+	}
+	letters, err := bot.pendingEvents.List()
+	if err != nil {
+		return err
+	}
+	if len(letters) == 0 {
+		return nil
+	}
+
+	bot.logger.Info(
+		"resuming pending events",
+		zap.Int("count", len(letters)),
+	)
+	remaining := bot.handler.Replay(ctx, letters)
+	if ctx.Err() != nil {
+		return bot.pendingEvents.Replace(remaining)
+	}
+	for _, letter := range remaining {
+		if bot.deadLetters == nil {
+			break
+		}
+		err = bot.deadLetters.Add(letter)
+		if err != nil {
+			bot.logger.Error(
+				"failed to write dead letter",
+				zap.String("err", err.Error()),
+				zap.String("eventId", letter.EventId),
+			)
+		}
+	}
+	bot.logger.Info(
+		"resumed pending events",
+		zap.Int("processed", len(letters)-len(remaining)),
+		zap.Int("failed", len(remaining)),
+	)
+	return bot.pendingEvents.Replace(nil)
+}
+
+// validateAuthentication retrieves the identity
+// of the app from Slack, failing if the bot
+// token is not valid.
+func (bot *Bot) validateAuthentication(ctx context.Context) error {
+	bot.logger.Info("validating authentication")
+	identity, err := bot.httpClient.Identity(ctx)
+	if err != nil {
+		return err
+	}
+	bot.mutex.Lock()
+	bot.identity = identity
+	bot.mutex.Unlock()
+	bot.logger.Info(
+		"validated authentication",
+		zap.String("botUserId", identity.UserId),
+		zap.String("botId", identity.BotId),
+	)
+	return nil
+}
+
 // attemptToConnect requests a Slack WebSocket URL
 // and attempts to connect with it, retrying until
 // the max attempts specified for the Bot have
 // been reached.
-func (bot *Bot) attemptToConnect() error {
+func (bot *Bot) attemptToConnect(ctx context.Context) error {
 	wssUrl := ""
 	attemptsLeft := bot.maxConnectAttempts
 	for {
 		var err error
 		bot.logger.Debug("requesting slack wss url")
 		wssUrl, err = bot.httpClient.RequestWssUrl(
+			ctx,
 			bot.debugWssReconnects,
 		)
 		if err != nil {
@@ -160,6 +543,9 @@ func (bot *Bot) attemptToConnect() error {
 				zap.String("err", err.Error()),
 			)
 			attemptsLeft -= 1
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			if attemptsLeft > 0 {
 				bot.logger.Debug(
 					"retrying slack wss url request",
@@ -187,7 +573,8 @@ func (bot *Bot) attemptToConnect() error {
 	bot.logger.Debug("creating new slack ws client")
 	bot.wsClient, err = slack.NewWsClient(
 		slack.WsClientParameters{
-			Logger: bot.logger,
+			Logger:  bot.logger,
+			Metrics: bot.metrics,
 		},
 	)
 	if err != nil {
@@ -198,13 +585,16 @@ func (bot *Bot) attemptToConnect() error {
 	attemptsLeft = bot.maxConnectAttempts
 	for attemptsLeft > 0 {
 		bot.logger.Debug("connecting to slack wss")
-		err := bot.wsClient.Connect(wssUrl)
+		err := bot.wsClient.Connect(ctx, wssUrl)
 		if err != nil {
 			bot.logger.Warn(
 				"failed connecting to slack wss",
 				zap.String("err", err.Error()),
 			)
 			attemptsLeft -= 1
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			if attemptsLeft == 0 {
 				return fmt.Errorf(
 					"failed to connect to slack wss after %d attempts",
@@ -226,20 +616,25 @@ func (bot *Bot) attemptToConnect() error {
 // prepareWorkspace retrieves all public channels
 // for the workspace and tries to join them one
 // at a time.
-func (bot *Bot) prepareWorkspace() error {
-	channels, err := bot.httpClient.PublicChannels()
+func (bot *Bot) prepareWorkspace(ctx context.Context) error {
+	channels, err := bot.httpClient.PublicChannels(ctx)
 	if err != nil {
 		return err
 	}
 	bot.logger.Debug("retrieved public channels for workspace")
 
 	for _, channel := range channels {
-		channelId, ok := channel.(map[string]interface{})["id"].(string)
+		channelData, ok := channel.(map[string]interface{})
+		if !ok {
+			bot.logger.Warn("failed to determine channel data")
+			continue
+		}
+		channelId, ok := channelData["id"].(string)
 		if !ok {
 			bot.logger.Warn("failed to determine channel id")
 			continue
 		}
-		err = bot.httpClient.JoinChannel(channelId)
+		err = bot.httpClient.JoinChannel(ctx, channelId)
 		if err != nil {
 			bot.logger.Warn(
 				"failed to join channel",
@@ -256,50 +651,986 @@ func (bot *Bot) prepareWorkspace() error {
 	return nil
 }
 
-// executeMainSequence creates an event handler and begins
-// concurrent listening and processing of
-// Slack events.
-func (bot *Bot) executeMainSequence() (bool, error) {
-	var err error
-	bot.logger.Debug("creating events handler")
-	bot.handler, err = events.NewHandler(
-		&events.Parameters{
-			Logger:          bot.logger,
-			SlackHttpClient: bot.httpClient,
-		},
-	)
-	if err != nil {
-		return false, err
+// executeMainSequence begins concurrent listening
+// and processing of Slack events with the events
+// handler of the Bot, which is kept across
+// reconnects so that replies stay tracked. It
+// stops once the given channel is closed, when
+// the Bot stops leading.
+func (bot *Bot) executeMainSequence(
+	ctx context.Context,
+	leadershipChanged <-chan struct{},
+) (bool, error) {
+	abandonedBefore := len(bot.handler.Abandoned())
+
+	select {
+	case <-bot.reconnect:
+	default:
 	}
-	bot.logger.Debug("created events handler")
 
 	eventsStream := make(chan map[string]interface{})
 	processingComplete := make(chan struct{})
+	processingCtx, abandonProcessing := context.WithCancel(detach(ctx))
+	defer abandonProcessing()
+	listeningCtx, stopListening := context.WithCancel(ctx)
+	defer stopListening()
 
 	bot.logger.Debug("starting event listening and handling")
-	go bot.wsClient.Listen(eventsStream)
-	go bot.handler.Process(eventsStream, processingComplete)
+	go bot.wsClient.Listen(listeningCtx, eventsStream)
+	go bot.handler.Process(processingCtx, eventsStream, processingComplete)
 	bot.logger.Debug("started event listening and handling")
 
 	restart := true
+	disconnectReason := "shutdown"
 	select {
-	case <-bot.interrupt:
-		bot.logger.Info("received interrupt signal")
+	case <-ctx.Done():
+		bot.logger.Info("stopping bot", zap.String("reason", ctx.Err().Error()))
 		restart = false
+	case <-bot.reconnect:
+		bot.logger.Info("reconnect requested")
+		disconnectReason = "reconnect_requested"
+		stopListening()
+	case <-leadershipChanged:
+		bot.logger.Info("stopped leading")
+		disconnectReason = "leadership_lost"
+		stopListening()
 	case <-processingComplete:
 		bot.logger.Info("event handling completed")
+		disconnectReason = bot.wsClient.DisconnectReason()
+		if disconnectReason == "" {
+			disconnectReason = "connection_lost"
+		}
 	}
+	bot.setConnected(false)
+	bot.metrics.Disconnected(disconnectReason)
 
-	bot.logger.Debug("closing ws client")
-	_, err = bot.wsClient.Close(
-		processingComplete,
-		defaultEventProcessingTimeout,
+	bot.logger.Info(
+		"draining in-flight events",
+		zap.Duration("gracePeriod", bot.drainGracePeriod),
+	)
+	drainCtx, cancelDrain := context.WithTimeout(
+		context.Background(),
+		bot.drainGracePeriod,
 	)
+	defer cancelDrain()
+	timedOut, err := bot.wsClient.Close(drainCtx, processingComplete)
 	if err != nil {
 		return false, err
 	}
-	bot.logger.Debug("closed ws client")
+	if timedOut {
+		abandonProcessing()
+		<-processingComplete
+	}
+	bot.reportDrain(abandonedBefore)
 
 	bot.logger.Debug("disconnecting from wss")
 	return restart, bot.wsClient.Disconnect()
 }
+
+// reportDrain logs the events that were
+// abandoned while draining, if any, skipping
+// the given number of events abandoned by
+// earlier connections.
+func (bot *Bot) reportDrain(abandonedBefore int) {
+	abandoned := bot.handler.Abandoned()[abandonedBefore:]
+	if len(abandoned) == 0 {
+		bot.logger.Info("drained in-flight events")
+		return
+	}
+	bot.logger.Warn(
+		"abandoned events while draining",
+		zap.Int("count", len(abandoned)),
+		zap.Strings("eventIds", abandoned),
+		zap.Bool("saved", bot.pendingEvents != nil),
+	)
+}
+
+// Reload applies the settings of the given
+// parameters that can change while the Bot is
+// running: the log level, the policies of the
+// events handler, the responders, the dialog
+// service and the rate limits. The Slack client
+// is kept, as are the circuit breaker and caches
+// of the dialog responder unless their own
+// settings changed. It returns the names of
+// the changed settings that only take effect
+// on restart. Nothing is applied if the
+// parameters are not valid.
+func (bot *Bot) Reload(params *Parameters) ([]string, error) {
+	err := validate(params)
+	if err != nil {
+		return nil, err
+	}
+
+	bot.reloadMutex.Lock()
+	defer bot.reloadMutex.Unlock()
+
+	restartRequired, handlerParams, err := bot.applyParameters(params)
+	if err != nil || handlerParams == nil {
+		return restartRequired, err
+	}
+
+	// The events handler waits for the event in
+	// flight, so it is reconfigured without
+	// holding the mutex that health checks need.
+	return restartRequired, bot.handler.Reconfigure(handlerParams)
+}
+
+// applyParameters replaces the components of the
+// Bot whose settings changed in the given
+// parameters and applies the other settings,
+// returning the names of the changed settings
+// that only take effect on restart and the
+// parameters for its events handler, if any.
+func (bot *Bot) applyParameters(
+	params *Parameters,
+) ([]string, *events.Parameters, error) {
+	bot.mutex.Lock()
+	defer bot.mutex.Unlock()
+
+	var err error
+	dialogClient := bot.dialogClient
+	dialogChanged := newDialogSettings(params) != newDialogSettings(bot.params)
+	if dialogChanged {
+		dialogClient, err = newDialogClient(params, bot.metrics)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	guardedDialog := bot.guardedDialog
+	responseCache := bot.responseCache
+	if newGuardSettings(params) != newGuardSettings(bot.params) {
+		guardedDialog, responseCache, err = newGuardedDialogResponder(
+			params,
+			bot.dialogResponder,
+			bot.metrics,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	responder, err := newResponder(params, guardedDialog)
+	if err != nil {
+		return nil, nil, err
+	}
+	maintenance, err := newMaintenanceResponder(params, responder)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	feedbackSinks := bot.feedbackSinks
+	if dialogChanged ||
+		params.FeedbackLogPath != bot.params.FeedbackLogPath ||
+		params.ForwardFeedback != bot.params.ForwardFeedback ||
+		!reflect.DeepEqual(params.TrainerUserIds, bot.params.TrainerUserIds) {
+		feedbackSinks, err = newFeedbackSinks(params, dialogClient)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	deadLetters := bot.deadLetters
+	if params.DeadLetterPath != bot.params.DeadLetterPath {
+		deadLetters, err = newDeadLetterStore(params.DeadLetterPath)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	pendingEvents := bot.pendingEvents
+	if params.PendingEventsPath != bot.params.PendingEventsPath {
+		pendingEvents, err = newDeadLetterStore(params.PendingEventsPath)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	restartRequired := bot.restartRequired(params)
+
+	if bot.logLevel != nil && params.LogLevel != nil {
+		bot.logLevel.SetLevel(params.LogLevel.Level())
+	}
+	if dialogChanged {
+		bot.dialogResponder.SetClient(dialogClient)
+		bot.dialogClient.CloseIdleConnections()
+		bot.dialogClient = dialogClient
+	}
+	bot.guardedDialog = guardedDialog
+	bot.responseCache = responseCache
+	bot.responder = maintenance
+	maintenance.SetEnabled(bot.maintenance.Enabled())
+	bot.maintenance = maintenance
+	bot.feedbackSinks = feedbackSinks
+	bot.deadLetters = deadLetters
+	bot.pendingEvents = pendingEvents
+	bot.applySettings(params)
+
+	if bot.handler == nil {
+		return restartRequired, nil, nil
+	}
+	return restartRequired, bot.handlerParameters(), nil
+}
+
+// restartRequired returns the names of the
+// settings of the given parameters that differ
+// from those of the Bot but only take effect
+// on restart.
+func (bot *Bot) restartRequired(params *Parameters) []string {
+	maxConnectAttempts := defaultMaxConnectAttempts
+	if params.MaxConnectAttempts != maxConnectAttempts {
+		maxConnectAttempts = params.MaxConnectAttempts
+	}
+	drainGracePeriod := defaultDrainGracePeriod
+	if params.DrainGracePeriod > 0 {
+		drainGracePeriod = params.DrainGracePeriod
+	}
+
+	var restartRequired []string
+	if params.ApiUrl != bot.apiUrl {
+		restartRequired = append(restartRequired, "ApiUrl")
+	}
+	if params.AppToken != bot.appToken {
+		restartRequired = append(restartRequired, "AppToken")
+	}
+	if params.BotToken != bot.botToken {
+		restartRequired = append(restartRequired, "BotToken")
+	}
+	if maxConnectAttempts != bot.maxConnectAttempts {
+		restartRequired = append(restartRequired, "MaxConnectAttempts")
+	}
+	if params.DebugWssReconnects != bot.debugWssReconnects {
+		restartRequired = append(restartRequired, "DebugWssReconnects")
+	}
+	if drainGracePeriod != bot.drainGracePeriod {
+		restartRequired = append(restartRequired, "DrainGracePeriod")
+	}
+	if params.SlackTimeout != bot.slackTimeout {
+		restartRequired = append(restartRequired, "SlackTimeout")
+	}
+	return restartRequired
+}
+
+// CheckAuthentication returns an error unless
+// the bot token has been validated.
+func (bot *Bot) CheckAuthentication(ctx context.Context) error {
+	bot.mutex.Lock()
+	defer bot.mutex.Unlock()
+	if bot.identity == nil {
+		return errors.New("authentication not validated")
+	}
+	return nil
+}
+
+// CheckConnection returns an error unless the
+// Bot is connected to Slack in Socket Mode or
+// is standing by for the leader.
+func (bot *Bot) CheckConnection(ctx context.Context) error {
+	bot.mutex.Lock()
+	defer bot.mutex.Unlock()
+	if !bot.connected && bot.leading() {
+		return errors.New("not connected to slack")
+	}
+	return nil
+}
+
+// CheckDialog returns an error unless the
+// dialog service is reachable.
+func (bot *Bot) CheckDialog(ctx context.Context) error {
+	bot.mutex.Lock()
+	dialogClient := bot.dialogClient
+	bot.mutex.Unlock()
+	return dialogClient.Ping(ctx)
+}
+
+// JoinedChannels returns the channels the
+// Bot is a member of.
+func (bot *Bot) JoinedChannels(ctx context.Context) ([]*slack.Channel, error) {
+	return bot.httpClient.JoinedChannels(ctx)
+}
+
+// Reconnect asks the Bot to drain its events,
+// close its Socket Mode connection and open a
+// new one. It returns an error if the Bot is
+// not connected.
+func (bot *Bot) Reconnect() error {
+	bot.mutex.Lock()
+	connected := bot.connected
+	bot.mutex.Unlock()
+	if !connected {
+		return errors.New("not connected to slack")
+	}
+	select {
+	case bot.reconnect <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// PrepareWorkspace tries to join every public
+// channel of the workspace again.
+func (bot *Bot) PrepareWorkspace(ctx context.Context) error {
+	bot.logger.Info("preparing workspace")
+	err := bot.prepareWorkspace(ctx)
+	if err != nil {
+		return err
+	}
+	bot.logger.Info("prepared workspace")
+	return nil
+}
+
+// PostMessage sends the given message on
+// behalf of the Bot to the channel matching
+// the given channelId, returning the timestamp
+// of the posted message.
+func (bot *Bot) PostMessage(
+	ctx context.Context,
+	channelId string,
+	message string,
+) (string, error) {
+	return bot.httpClient.SendMessageToChannel(ctx, message, channelId)
+}
+
+// LogLevel returns the lowest level of the
+// messages being logged.
+func (bot *Bot) LogLevel() zapcore.Level {
+	if bot.logLevel != nil {
+		return bot.logLevel.Level()
+	}
+	level := zapcore.DebugLevel
+	for level < zapcore.FatalLevel && !bot.logger.Core().Enabled(level) {
+		level++
+	}
+	return level
+}
+
+// SetLogLevel changes the lowest level of the
+// messages being logged until the next reload.
+func (bot *Bot) SetLogLevel(level zapcore.Level) error {
+	if bot.logLevel == nil {
+		return errors.New("log level cannot be changed")
+	}
+	bot.logLevel.SetLevel(level)
+	bot.logger.Info("changed log level", zap.String("level", level.String()))
+	return nil
+}
+
+// ProcessedEvents returns the IDs of the events
+// most recently processed, newest first, or
+// nothing if the Bot is not processing events.
+func (bot *Bot) ProcessedEvents() []string {
+	bot.mutex.Lock()
+	handler := bot.handler
+	bot.mutex.Unlock()
+	if handler == nil {
+		return []string{}
+	}
+	return handler.Processed()
+}
+
+// DeadLetters returns the events that could not
+// be processed, or nothing if dead-lettering
+// is turned off.
+func (bot *Bot) DeadLetters() ([]events.DeadLetter, error) {
+	bot.mutex.Lock()
+	deadLetters := bot.deadLetters
+	bot.mutex.Unlock()
+	if deadLetters == nil {
+		return []events.DeadLetter{}, nil
+	}
+	return deadLetters.List()
+}
+
+// Maintenance returns true if maintenance mode
+// is on.
+func (bot *Bot) Maintenance() bool {
+	bot.mutex.Lock()
+	defer bot.mutex.Unlock()
+	return bot.maintenance.Enabled()
+}
+
+// SetMaintenance turns maintenance mode on or
+// off. While it is on, every message gets the
+// maintenance reply instead of a response.
+func (bot *Bot) SetMaintenance(enabled bool) {
+	bot.mutex.Lock()
+	defer bot.mutex.Unlock()
+	bot.maintenance.SetEnabled(enabled)
+	bot.logger.Info("changed maintenance mode", zap.Bool("enabled", enabled))
+}
+
+// setConnected records whether the Bot is
+// connected to Slack.
+func (bot *Bot) setConnected(connected bool) {
+	bot.mutex.Lock()
+	defer bot.mutex.Unlock()
+	bot.connected = connected
+}
+
+// campaign runs the leader election of the Bot,
+// if any, until the given context is done.
+func (bot *Bot) campaign(ctx context.Context) {
+	if bot.elector == nil {
+		return
+	}
+	err := bot.elector.Run(ctx)
+	if err != nil {
+		bot.logger.Warn(
+			"failed to release leader lease",
+			zap.String("err", err.Error()),
+		)
+	}
+}
+
+// leading returns true unless the Bot takes
+// part in leader election and another replica
+// leads.
+func (bot *Bot) leading() bool {
+	return bot.elector == nil || bot.elector.Leading()
+}
+
+// leadershipChanged returns a channel that is
+// closed the next time the Bot starts or stops
+// leading, or nil if it takes no part in
+// leader election.
+func (bot *Bot) leadershipChanged() <-chan struct{} {
+	if bot.elector == nil {
+		return nil
+	}
+	return bot.elector.Changed()
+}
+
+// awaitLeadership stands by until the Bot leads,
+// returning false if the given context is done
+// first. It also returns a channel that is closed
+// once the Bot stops leading.
+func (bot *Bot) awaitLeadership(
+	ctx context.Context,
+) (<-chan struct{}, bool) {
+	for {
+		changed := bot.leadershipChanged()
+		if bot.leading() {
+			return changed, true
+		}
+		bot.logger.Info("waiting to lead before connecting to slack")
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-changed:
+		}
+	}
+}
+
+// watchHangups reloads the parameters of the
+// Bot each time the process receives SIGHUP,
+// until the given context is done. Hangups are
+// left alone if the Bot has no way to load
+// its parameters.
+func (bot *Bot) watchHangups(ctx context.Context) {
+	if bot.loadParameters == nil {
+		return
+	}
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+			bot.reload()
+		}
+	}
+}
+
+// reload loads the parameters of the Bot again
+// and applies them, logging the outcome.
+func (bot *Bot) reload() {
+	bot.logger.Info("reloading configuration")
+	params, err := bot.loadParameters()
+	if err != nil {
+		bot.logger.Error(
+			"failed to reload configuration",
+			zap.String("err", err.Error()),
+		)
+		return
+	}
+	restartRequired, err := bot.Reload(params)
+	if err != nil {
+		bot.logger.Error(
+			"failed to reload configuration",
+			zap.String("err", err.Error()),
+		)
+		return
+	}
+	if len(restartRequired) > 0 {
+		bot.logger.Warn(
+			"changed settings require restart",
+			zap.Strings("settings", restartRequired),
+		)
+	}
+	bot.logger.Info("reloaded configuration")
+}
+
+// newHandler returns a new events handler
+// configured for the Bot.
+func (bot *Bot) newHandler() (*events.Handler, error) {
+	return events.NewHandler(bot.handlerParameters())
+}
+
+// handlerParameters returns the parameters for
+// an events handler configured for the Bot.
+func (bot *Bot) handlerParameters() *events.Parameters {
+	var responseCache events.ResponseCache
+	if bot.responseCache != nil {
+		responseCache = bot.responseCache
+	}
+	return &events.Parameters{
+		Logger:               bot.logger,
+		SlackHttpClient:      bot.httpClient,
+		Responder:            bot.responder,
+		HistoryLength:        bot.dialogHistoryLength,
+		ConfidencePolicy:     bot.confidencePolicy,
+		FeedbackSinks:        bot.feedbackSinks,
+		PositiveReactions:    bot.positiveReactions,
+		NegativeReactions:    bot.negativeReactions,
+		Trainer:              bot.dialogClient,
+		TrainerUserIds:       bot.trainerUserIds,
+		ResponseCache:        responseCache,
+		RecalledReplies:      bot.guardedDialog,
+		AdminUserIds:         bot.adminUserIds,
+		BotUserId:            bot.identity.UserId,
+		BotId:                bot.identity.BotId,
+		IgnoreBots:           bot.ignoreBots,
+		AllowedBotIds:        bot.allowedBotIds,
+		BotLoopThreshold:     bot.botLoopThreshold,
+		BotLoopWindow:        bot.botLoopWindow,
+		SyncReplies:          bot.syncReplies,
+		SyncRepliesByChannel: bot.syncRepliesByChannel,
+		MaxAttempts:          bot.eventMaxAttempts,
+		RetryDelay:           bot.eventRetryDelay,
+		DeadLetters:          bot.deadLetters,
+		PendingEvents:        bot.pendingEvents,
+		EventTimeout:         bot.eventTimeout,
+		ProcessedQueueSize:   bot.processedEventsSize,
+		UserRateLimit:        bot.userRateLimit,
+		ChannelRateLimit:     bot.channelRateLimit,
+		GlobalRateLimit:      bot.globalRateLimit,
+		Metrics:              bot.metrics,
+	}
+}
+
+// newResponder returns a responder routing each
+// message to the responder named for its channel
+// or to the default responder. The dialog service
+// is always available through the given guarded
+// dialog responder and a fallback chain, while
+// the rules and chat completions responders are
+// only available if they are configured.
+func newResponder(
+	params *Parameters,
+	guardedDialog responders.Responder,
+) (responders.Responder, error) {
+	available := make(map[string]responders.Responder)
+
+	if params.RulesPath != "" {
+		rules, err := responders.LoadRules(params.RulesPath)
+		if err != nil {
+			return nil, err
+		}
+		rulesResponder, err := responders.NewRuleResponder(rules)
+		if err != nil {
+			return nil, err
+		}
+		available[rulesResponderName] = rulesResponder
+	}
+
+	if params.ChatCompletionsUrl != "" {
+		chatCompletionsResponder, err := responders.NewChatCompletionsResponder(
+			&responders.ChatCompletionsResponderParameters{
+				BaseUrl:      params.ChatCompletionsUrl,
+				ApiKey:       params.ChatCompletionsKey.Reveal(),
+				Model:        params.ChatCompletionsModel,
+				SystemPrompt: params.ChatCompletionsPrompt,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		available[chatCompletionsResponderName] = chatCompletionsResponder
+	}
+
+	dialogResponder, err := newDialogResponder(
+		params,
+		guardedDialog,
+		available,
+	)
+	if err != nil {
+		return nil, err
+	}
+	available[dialogResponderName] = dialogResponder
+
+	defaultResponder := dialogResponderName
+	if params.Responder != "" {
+		defaultResponder = params.Responder
+	}
+	router, err := responders.NewRouter(
+		&responders.RouterParameters{
+			Responders: available,
+			Default:    defaultResponder,
+			ByChannel:  params.ResponderByChannel,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !params.IntentDetection {
+		return router, nil
+	}
+	return newIntentResponder(params, router, available)
+}
+
+// newIntentResponder returns a responder sending
+// help requests, greetings, commands and
+// questions to the built-in responders, any intents named in the
+// parameters to the given available responders,
+// and everything else to the given router. An
+// intent named with no responder goes to the
+// router too.
+func newIntentResponder(
+	params *Parameters,
+	router responders.Responder,
+	available map[string]responders.Responder,
+) (responders.Responder, error) {
+	classifier, err := responders.NewIntentClassifier(
+		&responders.IntentClassifierParameters{
+			HelpKeywords:     params.HelpKeywords,
+			GreetingKeywords: params.GreetingKeywords,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	help, err := responders.NewStaticResponder(defaultHelpReply)
+	if err != nil {
+		return nil, err
+	}
+	command, err := responders.NewStaticResponder(defaultCommandReply)
+	if err != nil {
+		return nil, err
+	}
+	faq, err := responders.NewFaqResponder(
+		&responders.FaqResponderParameters{
+			Rules: available[rulesResponderName],
+			Reply: defaultFaqReply,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	handlers := map[string]responders.Responder{
+		helpResponderName:     help,
+		greetingResponderName: responders.NewGreetingResponder(),
+		commandResponderName:  command,
+		faqResponderName:      faq,
+	}
+	for name, responder := range available {
+		handlers[name] = responder
+	}
+
+	names := make(map[string]string)
+	for intent, name := range defaultIntentResponders {
+		names[intent] = name
+	}
+	for intent, name := range params.IntentResponders {
+		names[intent] = name
+	}
+	byIntent := make(map[string]responders.Responder)
+	for intent, name := range names {
+		if name == "" {
+			continue
+		}
+		handler, ok := handlers[name]
+		if !ok {
+			return nil, fmt.Errorf(
+				"unknown responder %q for intent %q",
+				name,
+				intent,
+			)
+		}
+		byIntent[intent] = handler
+	}
+
+	return responders.NewIntentResponder(
+		&responders.IntentResponderParameters{
+			Logger:     params.Logger,
+			Classifier: classifier,
+			Responders: byIntent,
+			Default:    router,
+		},
+	)
+}
+
+// guardSettings are the settings of the circuit
+// breaker and caches guarding the dialog service,
+// which keep their state across reloads unless
+// these settings change.
+type guardSettings struct {
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	breakerProbes    int
+	recallSize       int
+	cacheTtl         time.Duration
+	cacheSize        int
+	cacheByChannel   bool
+}
+
+// newGuardSettings returns the guard settings
+// of the given parameters.
+func newGuardSettings(params *Parameters) guardSettings {
+	return guardSettings{
+		breakerThreshold: params.DialogBreakerThreshold,
+		breakerCooldown:  params.DialogBreakerCooldown,
+		breakerProbes:    params.DialogBreakerProbes,
+		recallSize:       params.FallbackRecallSize,
+		cacheTtl:         params.ResponseCacheTtl,
+		cacheSize:        params.ResponseCacheSize,
+		cacheByChannel:   params.ResponseCacheByChannel,
+	}
+}
+
+// dialogSettings are the settings of the client
+// of the dialog service, which is replaced on
+// reload only if these settings change.
+type dialogSettings struct {
+	url          string
+	timeout      time.Duration
+	maxRetries   int
+	maxIdleConns int
+}
+
+// newDialogSettings returns the dialog settings
+// of the given parameters.
+func newDialogSettings(params *Parameters) dialogSettings {
+	return dialogSettings{
+		url:          params.DialogUrl,
+		timeout:      params.DialogTimeout,
+		maxRetries:   params.DialogMaxRetries,
+		maxIdleConns: params.DialogMaxIdleConns,
+	}
+}
+
+// newDialogClient returns a client of the dialog
+// service configured by the given parameters.
+func newDialogClient(
+	params *Parameters,
+	metrics *metrics.Metrics,
+) (*dialog.Client, error) {
+	dialogUrl := defaultDialogUrl
+	if params.DialogUrl != "" {
+		dialogUrl = params.DialogUrl
+	}
+	return dialog.NewClient(
+		&dialog.ClientParameters{
+			Logger:       params.Logger,
+			BaseUrl:      dialogUrl,
+			Timeout:      params.DialogTimeout,
+			MaxRetries:   params.DialogMaxRetries,
+			MaxIdleConns: params.DialogMaxIdleConns,
+			Metrics:      metrics,
+		},
+	)
+}
+
+// newGuardedDialogResponder returns a responder
+// using the given dialog responder behind a
+// circuit breaker and, if a cache TTL is given,
+// a response cache, remembering its replies in
+// case the dialog service fails. The response
+// cache is also returned if there is one.
+func newGuardedDialogResponder(
+	params *Parameters,
+	dialogResponder *responders.DialogResponder,
+	metrics *metrics.Metrics,
+) (*responders.RecallingResponder, *responders.CachingResponder, error) {
+	threshold := defaultDialogBreakerThreshold
+	if params.DialogBreakerThreshold > 0 {
+		threshold = params.DialogBreakerThreshold
+	}
+	cooldown := defaultDialogBreakerCooldown
+	if params.DialogBreakerCooldown > 0 {
+		cooldown = params.DialogBreakerCooldown
+	}
+	breaker, err := responders.NewCircuitBreaker(
+		&responders.CircuitBreakerParameters{
+			Logger:           params.Logger,
+			Responder:        dialogResponder,
+			FailureThreshold: threshold,
+			Cooldown:         cooldown,
+			Probes:           params.DialogBreakerProbes,
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recallSize := defaultFallbackRecallSize
+	if params.FallbackRecallSize > 0 {
+		recallSize = params.FallbackRecallSize
+	}
+	var cached responders.Responder = breaker
+	var responseCache *responders.CachingResponder
+	if params.ResponseCacheTtl > 0 {
+		cacheSize := defaultResponseCacheSize
+		if params.ResponseCacheSize > 0 {
+			cacheSize = params.ResponseCacheSize
+		}
+		responseCache, err = responders.NewCachingResponder(
+			&responders.CachingResponderParameters{
+				Responder:      breaker,
+				Ttl:            params.ResponseCacheTtl,
+				Size:           cacheSize,
+				ScopeByChannel: params.ResponseCacheByChannel,
+				Metrics:        metrics,
+			},
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		cached = responseCache
+	}
+
+	recalling, err := responders.NewRecallingResponder(cached, recallSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	return recalling, responseCache, nil
+}
+
+// newDialogResponder returns a responder using
+// the given guarded dialog responder. If it
+// fails, the reply of the fallback responder,
+// if any, is given, and finally an apology.
+func newDialogResponder(
+	params *Parameters,
+	guardedDialog responders.Responder,
+	available map[string]responders.Responder,
+) (responders.Responder, error) {
+	chain := []responders.Responder{guardedDialog}
+
+	if params.FallbackResponder != "" {
+		fallback, ok := available[params.FallbackResponder]
+		if !ok {
+			return nil, fmt.Errorf(
+				"unknown fallback responder %q",
+				params.FallbackResponder,
+			)
+		}
+		chain = append(chain, fallback)
+	}
+
+	reply := defaultFallbackReply
+	if params.FallbackReply != "" {
+		reply = params.FallbackReply
+	}
+	apology, err := responders.NewStaticResponder(reply)
+	if err != nil {
+		return nil, err
+	}
+	chain = append(chain, apology)
+
+	return responders.NewFallbackResponder(
+		&responders.FallbackResponderParameters{
+			Logger:     params.Logger,
+			Responders: chain,
+		},
+	)
+}
+
+// newMaintenanceResponder returns a responder
+// giving the maintenance reply while maintenance
+// mode is on, or else the reply of the given
+// responder.
+func newMaintenanceResponder(
+	params *Parameters,
+	responder responders.Responder,
+) (*responders.MaintenanceResponder, error) {
+	maintenanceReply := defaultMaintenanceReply
+	if params.MaintenanceReply != "" {
+		maintenanceReply = params.MaintenanceReply
+	}
+	return responders.NewMaintenanceResponder(responder, maintenanceReply)
+}
+
+// newFeedbackSinks returns the sinks recording
+// feedback on replies: the feedback log and the
+// dialog service, if either is turned on.
+func newFeedbackSinks(
+	params *Parameters,
+	dialogClient *dialog.Client,
+) ([]events.FeedbackSink, error) {
+	var feedbackSinks []events.FeedbackSink
+	if params.FeedbackLogPath != "" {
+		feedbackLog, err := events.NewFeedbackLog(params.FeedbackLogPath)
+		if err != nil {
+			return nil, err
+		}
+		feedbackSinks = append(feedbackSinks, feedbackLog)
+	}
+	if params.ForwardFeedback {
+		dialogFeedback, err := events.NewDialogFeedbackSink(
+			&events.DialogFeedbackSinkParameters{
+				DialogClient:   dialogClient,
+				TrainerUserIds: params.TrainerUserIds,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		feedbackSinks = append(feedbackSinks, dialogFeedback)
+	}
+	return feedbackSinks, nil
+}
+
+// newDeadLetterStore returns a store of events
+// at the given path, or nothing if the path
+// is empty.
+func newDeadLetterStore(path string) (*events.DeadLetterStore, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return events.NewDeadLetterStore(path)
+}
+
+// A detachedContext carries the values of its
+// parent but is never done, so events can keep
+// processing after the bot is told to stop.
+type detachedContext struct {
+	parent context.Context
+}
+
+// detach returns a context carrying the values
+// of the given context that is never done.
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+// Deadline returns no deadline.
+func (ctx detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done returns a nil channel, which never closes.
+func (ctx detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err always returns nil.
+func (ctx detachedContext) Err() error {
+	return nil
+}
+
+// Value returns the value of the parent context
+// associated with the given key.
+func (ctx detachedContext) Value(key interface{}) interface{} {
+	return ctx.parent.Value(key)
+}
@@ -1,11 +1,25 @@
 package bot
 
 import (
+	"context"
+	"errors"
 	"github.com/brianvoe/gofakeit/v6"
+	"github.com/drewnorman/jt-slackbot/core/internal/leader"
+	"github.com/drewnorman/jt-slackbot/core/internal/responders"
+	"github.com/drewnorman/jt-slackbot/core/internal/secrets"
+	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func fakeZapLogger() *zap.Logger {
@@ -22,6 +36,59 @@ func fakeZapLogger() *zap.Logger {
 	)
 }
 
+func fakeAtomicLevel(level zapcore.Level) *zap.AtomicLevel {
+	atomicLevel := zap.NewAtomicLevelAt(level)
+	return &atomicLevel
+}
+
+// fakeSlackServer serves the Slack API methods
+// the Bot calls while running and a Socket Mode
+// endpoint that sends each new connection to
+// the given channel after greeting it.
+func fakeSlackServer(connections chan *websocket.Conn) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	var server *httptest.Server
+	server = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				switch strings.TrimPrefix(r.URL.Path, "/") {
+				case "socket":
+					conn, err := upgrader.Upgrade(w, r, nil)
+					if err != nil {
+						return
+					}
+					_ = conn.WriteJSON(map[string]interface{}{"type": "hello"})
+					connections <- conn
+					return
+				case "auth.test":
+					_, _ = w.Write([]byte(`{"ok": true, "user_id": "U0BOT", "bot_id": "B0BOT"}`))
+				case "apps.connections.open":
+					_, _ = w.Write([]byte(`{"ok": true, "url": "ws` +
+						strings.TrimPrefix(server.URL, "http") + `/socket"}`))
+				case "conversations.list":
+					_, _ = w.Write([]byte(`{"ok": true, "channels": []}`))
+				default:
+					_, _ = w.Write([]byte(`{"ok": true}`))
+				}
+			},
+		),
+	)
+	return server
+}
+
+// serveConnection reads from the given
+// connection until it is closed, so that
+// close messages are answered.
+func serveConnection(conn *websocket.Conn) {
+	for {
+		_, _, err := conn.ReadMessage()
+		if err != nil {
+			_ = conn.Close()
+			return
+		}
+	}
+}
+
 func TestNew(t *testing.T) {
 	type args struct {
 		params *Parameters
@@ -37,19 +104,107 @@ func TestNew(t *testing.T) {
 				params: &Parameters{
 					Logger:   fakeZapLogger(),
 					ApiUrl:   gofakeit.URL(),
-					AppToken: gofakeit.UUID(),
-					BotToken: gofakeit.UUID(),
+					AppToken: secrets.New(gofakeit.UUID()),
+					BotToken: secrets.New(gofakeit.UUID()),
 				},
 			},
 			wantErr: false,
 		},
+		{
+			name: "UnknownResponder",
+			args: args{
+				params: &Parameters{
+					Logger:    fakeZapLogger(),
+					ApiUrl:    gofakeit.URL(),
+					AppToken:  secrets.New(gofakeit.UUID()),
+					BotToken:  secrets.New(gofakeit.UUID()),
+					Responder: "rules",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ChatCompletionsResponderForChannel",
+			args: args{
+				params: &Parameters{
+					Logger:               fakeZapLogger(),
+					ApiUrl:               gofakeit.URL(),
+					AppToken:             secrets.New(gofakeit.UUID()),
+					BotToken:             secrets.New(gofakeit.UUID()),
+					ChatCompletionsUrl:   gofakeit.URL(),
+					ChatCompletionsModel: "fake-model",
+					ResponderByChannel: map[string]string{
+						"C0123": "chat-completions",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "UnknownFallbackResponder",
+			args: args{
+				params: &Parameters{
+					Logger:            fakeZapLogger(),
+					ApiUrl:            gofakeit.URL(),
+					AppToken:          secrets.New(gofakeit.UUID()),
+					BotToken:          secrets.New(gofakeit.UUID()),
+					FallbackResponder: "rules",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ResponseCache",
+			args: args{
+				params: &Parameters{
+					Logger:                 fakeZapLogger(),
+					ApiUrl:                 gofakeit.URL(),
+					AppToken:               secrets.New(gofakeit.UUID()),
+					BotToken:               secrets.New(gofakeit.UUID()),
+					ResponseCacheTtl:       time.Minute,
+					ResponseCacheByChannel: true,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "IntentDetection",
+			args: args{
+				params: &Parameters{
+					Logger:          fakeZapLogger(),
+					ApiUrl:          gofakeit.URL(),
+					AppToken:        secrets.New(gofakeit.UUID()),
+					BotToken:        secrets.New(gofakeit.UUID()),
+					IntentDetection: true,
+					IntentResponders: map[string]string{
+						"question": "dialog",
+						"greeting": "",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "UnknownIntentResponder",
+			args: args{
+				params: &Parameters{
+					Logger:           fakeZapLogger(),
+					ApiUrl:           gofakeit.URL(),
+					AppToken:         secrets.New(gofakeit.UUID()),
+					BotToken:         secrets.New(gofakeit.UUID()),
+					IntentDetection:  true,
+					IntentResponders: map[string]string{"question": "rules"},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "MissingLogger",
 			args: args{
 				params: &Parameters{
 					ApiUrl:   gofakeit.URL(),
-					AppToken: gofakeit.UUID(),
-					BotToken: gofakeit.UUID(),
+					AppToken: secrets.New(gofakeit.UUID()),
+					BotToken: secrets.New(gofakeit.UUID()),
 				},
 			},
 			wantErr: true,
@@ -59,8 +214,8 @@ func TestNew(t *testing.T) {
 			args: args{
 				params: &Parameters{
 					Logger:   fakeZapLogger(),
-					AppToken: gofakeit.UUID(),
-					BotToken: gofakeit.UUID(),
+					AppToken: secrets.New(gofakeit.UUID()),
+					BotToken: secrets.New(gofakeit.UUID()),
 				},
 			},
 			wantErr: true,
@@ -71,7 +226,7 @@ func TestNew(t *testing.T) {
 				params: &Parameters{
 					Logger:   fakeZapLogger(),
 					ApiUrl:   gofakeit.URL(),
-					BotToken: gofakeit.UUID(),
+					BotToken: secrets.New(gofakeit.UUID()),
 				},
 			},
 			wantErr: true,
@@ -82,7 +237,7 @@ func TestNew(t *testing.T) {
 				params: &Parameters{
 					Logger:   fakeZapLogger(),
 					ApiUrl:   gofakeit.URL(),
-					AppToken: gofakeit.UUID(),
+					AppToken: secrets.New(gofakeit.UUID()),
 				},
 			},
 			wantErr: true,
@@ -98,3 +253,706 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+func TestBot_Reload(t *testing.T) {
+	apiUrl := gofakeit.URL()
+	appToken := gofakeit.UUID()
+	botToken := gofakeit.UUID()
+	type args struct {
+		params *Parameters
+	}
+	tests := []struct {
+		name                string
+		args                args
+		wantRestartRequired []string
+		wantLevel           zapcore.Level
+		wantErr             bool
+	}{
+		{
+			name: "AppliesReloadableSettings",
+			args: args{
+				params: &Parameters{
+					Logger:              fakeZapLogger(),
+					LogLevel:            fakeAtomicLevel(zapcore.DebugLevel),
+					ApiUrl:              apiUrl,
+					AppToken:            secrets.New(appToken),
+					BotToken:            secrets.New(botToken),
+					DialogHistoryLength: 3,
+				},
+			},
+			wantRestartRequired: nil,
+			wantLevel:           zapcore.DebugLevel,
+			wantErr:             false,
+		},
+		{
+			name: "FlagsSettingsRequiringRestart",
+			args: args{
+				params: &Parameters{
+					Logger:    fakeZapLogger(),
+					ApiUrl:    gofakeit.URL(),
+					AppToken:  secrets.New(appToken),
+					BotToken:  secrets.New(gofakeit.UUID()),
+					DialogUrl: gofakeit.URL(),
+				},
+			},
+			wantRestartRequired: []string{"ApiUrl", "BotToken"},
+			wantLevel:           zapcore.InfoLevel,
+			wantErr:             false,
+		},
+		{
+			name: "InvalidParameters",
+			args: args{
+				params: &Parameters{
+					Logger:    fakeZapLogger(),
+					LogLevel:  fakeAtomicLevel(zapcore.DebugLevel),
+					ApiUrl:    apiUrl,
+					AppToken:  secrets.New(appToken),
+					BotToken:  secrets.New(botToken),
+					Responder: "rules",
+				},
+			},
+			wantRestartRequired: nil,
+			wantLevel:           zapcore.InfoLevel,
+			wantErr:             true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slackBot, err := New(
+				&Parameters{
+					Logger:   fakeZapLogger(),
+					LogLevel: fakeAtomicLevel(zapcore.InfoLevel),
+					ApiUrl:   apiUrl,
+					AppToken: secrets.New(appToken),
+					BotToken: secrets.New(botToken),
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			responder := slackBot.responder
+
+			restartRequired, err := slackBot.Reload(tt.args.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Reload() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(restartRequired, tt.wantRestartRequired) {
+				t.Errorf("Reload() = %v, want %v", restartRequired, tt.wantRestartRequired)
+			}
+			if slackBot.logLevel.Level() != tt.wantLevel {
+				t.Errorf("Reload() = %v, want %v", slackBot.logLevel.Level(), tt.wantLevel)
+			}
+			if slackBot.apiUrl != apiUrl || slackBot.botToken.Reveal() != botToken {
+				t.Errorf("Reload() error = %v, wantErr %v", errors.New("replaced settings requiring restart"), false)
+			}
+			if (slackBot.responder != responder) == tt.wantErr {
+				t.Errorf("Reload() error = %v, wantErr %v", errors.New("unexpected responder"), false)
+			}
+		})
+	}
+}
+
+func TestBot_ReloadKeepsDialogGuards(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		reloaded     func(params Parameters) *Parameters
+		wantRequests int
+	}{
+		{
+			name:   "KeepsOpenCircuit",
+			status: http.StatusInternalServerError,
+			reloaded: func(params Parameters) *Parameters {
+				params.DialogHistoryLength = 3
+				return &params
+			},
+			wantRequests: 1,
+		},
+		{
+			name:   "KeepsCachedResponses",
+			status: http.StatusOK,
+			reloaded: func(params Parameters) *Parameters {
+				params.DialogHistoryLength = 3
+				return &params
+			},
+			wantRequests: 1,
+		},
+		{
+			name:   "ReplacesChangedCache",
+			status: http.StatusOK,
+			reloaded: func(params Parameters) *Parameters {
+				params.ResponseCacheTtl = 2 * time.Hour
+				return &params
+			},
+			wantRequests: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(
+				http.HandlerFunc(
+					func(w http.ResponseWriter, r *http.Request) {
+						requests++
+						w.Header().Set("Content-Type", "application/json")
+						w.WriteHeader(tt.status)
+						_, _ = w.Write([]byte(`{"reply": "Woof!", "confidence": 1}`))
+					},
+				),
+			)
+			defer server.Close()
+
+			params := Parameters{
+				Logger:                 fakeZapLogger(),
+				ApiUrl:                 gofakeit.URL(),
+				AppToken:               secrets.New(gofakeit.UUID()),
+				BotToken:               secrets.New(gofakeit.UUID()),
+				DialogUrl:              server.URL + "/",
+				DialogBreakerThreshold: 1,
+				DialogBreakerCooldown:  time.Hour,
+				ResponseCacheTtl:       time.Hour,
+			}
+			slackBot, err := New(&params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			request := &responders.Request{Text: "Who is a good dog?", ChannelId: "C1"}
+			_, err = slackBot.responder.Respond(context.Background(), request)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = slackBot.Reload(tt.reloaded(params))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = slackBot.responder.Respond(context.Background(), request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("Respond() requests = %v, want %v", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestBot_ReloadSwitchesDialogUrl(t *testing.T) {
+	newDialogServer := func(reply string, requests *int) *httptest.Server {
+		return httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					*requests++
+					w.Header().Set("Content-Type", "application/json")
+					_, _ = w.Write([]byte(`{"reply": "` + reply + `", "confidence": 1}`))
+				},
+			),
+		)
+	}
+	oldRequests, newRequests := 0, 0
+	oldServer := newDialogServer("Woof!", &oldRequests)
+	defer oldServer.Close()
+	newServer := newDialogServer("Bark!", &newRequests)
+	defer newServer.Close()
+
+	params := Parameters{
+		Logger:                 fakeZapLogger(),
+		ApiUrl:                 gofakeit.URL(),
+		AppToken:               secrets.New(gofakeit.UUID()),
+		BotToken:               secrets.New(gofakeit.UUID()),
+		DialogUrl:              oldServer.URL + "/",
+		DialogBreakerThreshold: 1,
+		DialogBreakerCooldown:  time.Hour,
+	}
+	slackBot, err := New(&params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guardedDialog := slackBot.guardedDialog
+	oldClient := slackBot.dialogClient
+
+	reloaded := params
+	reloaded.DialogUrl = newServer.URL + "/"
+	restartRequired, err := slackBot.Reload(&reloaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restartRequired != nil {
+		t.Errorf("Reload() = %v, want %v", restartRequired, nil)
+	}
+	if slackBot.guardedDialog != guardedDialog {
+		t.Errorf("Reload() error = %v, wantErr %v", errors.New("replaced dialog guards"), false)
+	}
+	if slackBot.dialogClient == oldClient {
+		t.Errorf("Reload() error = %v, wantErr %v", errors.New("kept dialog client"), false)
+	}
+
+	request := &responders.Request{Text: "Who is a good dog?", ChannelId: "C1"}
+	response, err := slackBot.responder.Respond(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Text != "Bark!" {
+		t.Errorf("Respond() = %v, want %v", response.Text, "Bark!")
+	}
+	if oldRequests != 0 || newRequests != 1 {
+		t.Errorf("Respond() requests = %v, %v, want %v, %v", oldRequests, newRequests, 0, 1)
+	}
+	err = slackBot.CheckDialog(context.Background())
+	if err != nil {
+		t.Errorf("CheckDialog() error = %v, wantErr %v", err, false)
+	}
+}
+
+func TestBot_IntentDetection(t *testing.T) {
+	rulesPath := filepath.Join(t.TempDir(), "rules.json")
+	err := ioutil.WriteFile(
+		rulesPath,
+		[]byte(`[{"pattern": "where is the ball", "reply": "Under the couch!"}]`),
+		0600,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"reply": "Woof!", "confidence": 1}`))
+			},
+		),
+	)
+	defer server.Close()
+
+	slackBot, err := New(
+		&Parameters{
+			Logger:          fakeZapLogger(),
+			ApiUrl:          gofakeit.URL(),
+			AppToken:        secrets.New(gofakeit.UUID()),
+			BotToken:        secrets.New(gofakeit.UUID()),
+			DialogUrl:       server.URL + "/",
+			RulesPath:       rulesPath,
+			IntentDetection: true,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"QuestionFromRules", "Where is the ball?", "Under the couch!"},
+		{"QuestionWithoutRule", "What is your favorite toy?", defaultFaqReply},
+		{"UnknownCommandVerb", "deploy: production now", "Woof!"},
+		{"CommandVerb", "cache: sideways", defaultCommandReply},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := slackBot.responder.Respond(
+				context.Background(),
+				&responders.Request{Text: tt.text, ChannelId: "C1"},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.Text != tt.want {
+				t.Errorf("Respond() = %v, want %v", response.Text, tt.want)
+			}
+		})
+	}
+}
+
+func TestBot_Checks(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"ok": true}`))
+			},
+		),
+	)
+	defer server.Close()
+
+	slackBot, err := New(
+		&Parameters{
+			Logger:    fakeZapLogger(),
+			ApiUrl:    gofakeit.URL(),
+			AppToken:  secrets.New(gofakeit.UUID()),
+			BotToken:  secrets.New(gofakeit.UUID()),
+			DialogUrl: server.URL + "/",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if slackBot.CheckAuthentication(ctx) == nil {
+		t.Errorf("CheckAuthentication() error = %v, wantErr %v", nil, true)
+	}
+	if slackBot.CheckConnection(ctx) == nil {
+		t.Errorf("CheckConnection() error = %v, wantErr %v", nil, true)
+	}
+	err = slackBot.CheckDialog(ctx)
+	if err != nil {
+		t.Errorf("CheckDialog() error = %v, wantErr %v", err, false)
+	}
+
+	slackBot.setConnected(true)
+	err = slackBot.CheckConnection(ctx)
+	if err != nil {
+		t.Errorf("CheckConnection() error = %v, wantErr %v", err, false)
+	}
+}
+
+func TestBot_Maintenance(t *testing.T) {
+	params := &Parameters{
+		Logger:           fakeZapLogger(),
+		ApiUrl:           gofakeit.URL(),
+		AppToken:         secrets.New(gofakeit.UUID()),
+		BotToken:         secrets.New(gofakeit.UUID()),
+		MaintenanceReply: gofakeit.LoremIpsumSentence(5),
+	}
+	slackBot, err := New(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slackBot.Maintenance() {
+		t.Errorf("Maintenance() = %v, want %v", true, false)
+	}
+
+	slackBot.SetMaintenance(true)
+	_, err = slackBot.Reload(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slackBot.Maintenance() {
+		t.Errorf("Maintenance() = %v, want %v", false, true)
+	}
+
+	response, err := slackBot.responder.Respond(context.Background(), &responders.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Text != params.MaintenanceReply {
+		t.Errorf("Respond() = %v, want %v", response.Text, params.MaintenanceReply)
+	}
+}
+
+func TestBot_LogLevel(t *testing.T) {
+	tests := []struct {
+		name      string
+		logLevel  *zap.AtomicLevel
+		setLevel  zapcore.Level
+		wantLevel zapcore.Level
+		wantErr   bool
+	}{
+		{
+			name:      "ChangesAtomicLevel",
+			logLevel:  fakeAtomicLevel(zapcore.InfoLevel),
+			setLevel:  zapcore.DebugLevel,
+			wantLevel: zapcore.DebugLevel,
+			wantErr:   false,
+		},
+		{
+			name:      "MissingAtomicLevel",
+			logLevel:  nil,
+			setLevel:  zapcore.DebugLevel,
+			wantLevel: zapcore.FatalLevel,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slackBot, err := New(
+				&Parameters{
+					Logger:   fakeZapLogger(),
+					LogLevel: tt.logLevel,
+					ApiUrl:   gofakeit.URL(),
+					AppToken: secrets.New(gofakeit.UUID()),
+					BotToken: secrets.New(gofakeit.UUID()),
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = slackBot.SetLogLevel(tt.setLevel)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetLogLevel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if slackBot.LogLevel() != tt.wantLevel {
+				t.Errorf("LogLevel() = %v, want %v", slackBot.LogLevel(), tt.wantLevel)
+			}
+		})
+	}
+}
+
+func TestBot_Reconnect(t *testing.T) {
+	slackBot, err := New(
+		&Parameters{
+			Logger:   fakeZapLogger(),
+			ApiUrl:   gofakeit.URL(),
+			AppToken: secrets.New(gofakeit.UUID()),
+			BotToken: secrets.New(gofakeit.UUID()),
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slackBot.Reconnect() == nil {
+		t.Errorf("Reconnect() error = %v, wantErr %v", nil, true)
+	}
+
+	slackBot.setConnected(true)
+	for i := 0; i < 2; i++ {
+		err = slackBot.Reconnect()
+		if err != nil {
+			t.Errorf("Reconnect() error = %v, wantErr %v", err, false)
+		}
+	}
+	if len(slackBot.reconnect) != 1 {
+		t.Errorf("Reconnect() = %v, want %v", len(slackBot.reconnect), 1)
+	}
+}
+
+func TestBot_RunKeepsHandlerAcrossReconnects(t *testing.T) {
+	connections := make(chan *websocket.Conn)
+	server := fakeSlackServer(connections)
+	defer server.Close()
+
+	slackBot, err := New(
+		&Parameters{
+			Logger:             fakeZapLogger(),
+			ApiUrl:             server.URL + "/",
+			AppToken:           secrets.New(gofakeit.UUID()),
+			BotToken:           secrets.New(gofakeit.UUID()),
+			MaxConnectAttempts: 1,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() {
+		stopped <- slackBot.Run(ctx)
+	}()
+
+	go serveConnection(<-connections)
+	slackBot.mutex.Lock()
+	handler := slackBot.handler
+	slackBot.mutex.Unlock()
+
+	for slackBot.Reconnect() != nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+	go serveConnection(<-connections)
+	slackBot.mutex.Lock()
+	reconnectedHandler := slackBot.handler
+	slackBot.mutex.Unlock()
+	if reconnectedHandler != handler {
+		t.Errorf("Run() handler = %p, want %p", reconnectedHandler, handler)
+	}
+
+	cancel()
+	err = <-stopped
+	if err != nil {
+		t.Errorf("Run() error = %v, wantErr %v", err, false)
+	}
+}
+
+type fakeLease struct {
+	mutex sync.Mutex
+	held  bool
+}
+
+func (lease *fakeLease) Acquire(ctx context.Context) (bool, error) {
+	lease.mutex.Lock()
+	defer lease.mutex.Unlock()
+	return lease.held, nil
+}
+
+func (lease *fakeLease) Release() error {
+	return nil
+}
+
+func (lease *fakeLease) set(held bool) {
+	lease.mutex.Lock()
+	defer lease.mutex.Unlock()
+	lease.held = held
+}
+
+// fakeEnvelope returns an events API envelope
+// carrying an event with the given ID.
+func fakeEnvelope(eventId string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "events_api",
+		"envelope_id": gofakeit.UUID(),
+		"payload": map[string]interface{}{
+			"event_id": eventId,
+			"event": map[string]interface{}{
+				"type": "reaction_removed",
+				"user": "U1",
+			},
+		},
+	}
+}
+
+// waitFor waits for the given condition to hold,
+// failing the test if it does not soon enough.
+func waitFor(t *testing.T, condition func() bool, message string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal(message)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// processed returns true if the given Bot has
+// processed the event with the given ID.
+func processed(slackBot *Bot, eventId string) bool {
+	for _, id := range slackBot.ProcessedEvents() {
+		if id == eventId {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBot_RunLeavesEventsToLeader(t *testing.T) {
+	connections := make(chan *websocket.Conn)
+	server := fakeSlackServer(connections)
+	defer server.Close()
+
+	newBot := func(lease *fakeLease) *Bot {
+		elector, err := leader.NewElector(
+			&leader.ElectorParameters{
+				Logger:        fakeZapLogger(),
+				Lease:         lease,
+				RetryInterval: time.Millisecond,
+			},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slackBot, err := New(
+			&Parameters{
+				Logger:             fakeZapLogger(),
+				Elector:            elector,
+				ApiUrl:             server.URL + "/",
+				AppToken:           secrets.New(gofakeit.UUID()),
+				BotToken:           secrets.New(gofakeit.UUID()),
+				MaxConnectAttempts: 1,
+			},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return slackBot
+	}
+	leaderLease := &fakeLease{held: true}
+	standbyLease := &fakeLease{}
+	leaderBot := newBot(leaderLease)
+	standbyBot := newBot(standbyLease)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 2)
+	for _, slackBot := range []*Bot{leaderBot, standbyBot} {
+		go func(slackBot *Bot) {
+			stopped <- slackBot.Run(ctx)
+		}(slackBot)
+	}
+
+	deliver := func(conn *websocket.Conn, eventId string) {
+		envelope := fakeEnvelope(eventId)
+		err := conn.WriteJSON(envelope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ack map[string]interface{}
+		err = conn.ReadJSON(&ack)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ack["envelope_id"] != envelope["envelope_id"] {
+			t.Errorf("Run() ack = %v, want %v", ack["envelope_id"], envelope["envelope_id"])
+		}
+		go serveConnection(conn)
+	}
+
+	conn := <-connections
+	waitFor(
+		t,
+		func() bool {
+			return standbyBot.CheckAuthentication(ctx) == nil
+		},
+		"standby did not validate authentication",
+	)
+	if standbyBot.CheckConnection(ctx) != nil {
+		t.Errorf("CheckConnection() error = %v, wantErr %v", standbyBot.CheckConnection(ctx), false)
+	}
+	deliver(conn, "Ev1")
+	waitFor(
+		t,
+		func() bool {
+			return processed(leaderBot, "Ev1")
+		},
+		"leader did not process the event",
+	)
+
+	leaderLease.set(false)
+	standbyLease.set(true)
+	deliver(<-connections, "Ev2")
+	waitFor(
+		t,
+		func() bool {
+			return processed(standbyBot, "Ev2")
+		},
+		"new leader did not process the event",
+	)
+	if processed(standbyBot, "Ev1") || processed(leaderBot, "Ev2") {
+		t.Errorf("Run() processed events on the standby")
+	}
+
+	select {
+	case conn := <-connections:
+		t.Errorf("Run() connected while standing by")
+		go serveConnection(conn)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	for i := 0; i < 2; i++ {
+		err := <-stopped
+		if err != nil {
+			t.Errorf("Run() error = %v, wantErr %v", err, false)
+		}
+	}
+}
+
+type fakeContextKey struct{}
+
+func TestDetach(t *testing.T) {
+	parent, cancel := context.WithCancel(
+		context.WithValue(context.Background(), fakeContextKey{}, "value"),
+	)
+	cancel()
+
+	ctx := detach(parent)
+	if ctx.Err() != nil {
+		t.Errorf("detach() error = %v, want %v", ctx.Err(), nil)
+	}
+	if ctx.Done() != nil {
+		t.Errorf("detach() done = %v, want %v", ctx.Done(), nil)
+	}
+	if ctx.Value(fakeContextKey{}) != "value" {
+		t.Errorf("detach() value = %v, want %v", ctx.Value(fakeContextKey{}), "value")
+	}
+}
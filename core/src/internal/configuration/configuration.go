@@ -1,27 +1,185 @@
 package configuration
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"github.com/drewnorman/jt-slackbot/core/internal/secrets"
 	"github.com/joho/godotenv"
 	"go.uber.org/zap/zapcore"
+	"net/url"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // EnvLoader loads the environment files
 // associated with the given filenames.
 type EnvLoader func(filenames ...string) (err error)
 
+// EnvReader reads the variables of the
+// environment files associated with the
+// given filenames without setting them.
+type EnvReader func(filenames ...string) (map[string]string, error)
+
 // A Configuration is a collection of settings
 // for the application.
 type Configuration struct {
-	ApiUrl             string
-	AppToken           string
-	BotToken           string
-	MaxConnectAttempts int
-	DebugWssReconnects bool
-	LogLevel           zapcore.Level
-	loadEnvironment    EnvLoader
+	ConfigFile                   string
+	SecretProvider               string
+	SecretProviderDir            string
+	SecretProviderCommand        []string
+	SecretProviderTimeout        time.Duration
+	ApiUrl                       string
+	AppToken                     secrets.Secret
+	BotToken                     secrets.Secret
+	MaxConnectAttempts           int
+	DebugWssReconnects           bool
+	LogLevel                     zapcore.Level
+	LogFilePath                  string
+	LogMaxSize                   int
+	LogMaxBackups                int
+	LogMaxAge                    int
+	SlackTimeout                 time.Duration
+	ProcessedEventsSize          int
+	IgnoreBots                   bool
+	AllowedBotIds                []string
+	BotLoopThreshold             int
+	BotLoopWindow                time.Duration
+	SyncReplies                  bool
+	SyncRepliesByChannel         map[string]bool
+	EventMaxAttempts             int
+	EventRetryDelay              time.Duration
+	DeadLetterPath               string
+	EventTimeout                 time.Duration
+	PendingEventsPath            string
+	DrainGracePeriod             time.Duration
+	UserRateLimit                RateLimit
+	ChannelRateLimit             RateLimit
+	GlobalRateLimit              RateLimit
+	DialogUrl                    string
+	DialogTimeout                time.Duration
+	DialogMaxRetries             int
+	DialogMaxIdleConns           int
+	DialogHistoryLength          int
+	ConfidenceThreshold          float64
+	ConfidenceThresholdByChannel map[string]float64
+	LowConfidenceAction          string
+	ClarifyReply                 string
+	FeedbackLogPath              string
+	ForwardFeedback              bool
+	PositiveReactions            []string
+	NegativeReactions            []string
+	TrainerUserIds               []string
+	Responder                    string
+	ResponderByChannel           map[string]string
+	RulesPath                    string
+	ChatCompletionsUrl           string
+	ChatCompletionsKey           secrets.Secret
+	ChatCompletionsModel         string
+	ChatCompletionsPrompt        string
+	DialogBreakerThreshold       int
+	DialogBreakerCooldown        time.Duration
+	DialogBreakerProbes          int
+	FallbackResponder            string
+	FallbackReply                string
+	FallbackRecallSize           int
+	MaintenanceReply             string
+	ResponseCacheTtl             time.Duration
+	ResponseCacheSize            int
+	ResponseCacheScope           string
+	AdminUserIds                 []string
+	IntentDetection              bool
+	IntentResponders             map[string]string
+	HelpKeywords                 []string
+	GreetingKeywords             []string
+	HealthAddr                   string
+	AdminApiAddr                 string
+	AdminApiToken                secrets.Secret
+	LeaderElection               string
+	LeaderLockPath               string
+	LeaderRetryInterval          time.Duration
+	TracingExporter              string
+	TracingFilePath              string
+	loadEnvironment              EnvLoader
+	readEnvironment              EnvReader
+	processEnvironment           map[string]bool
+	fileValues                   map[string]string
+	settings                     map[string]Setting
+	secretProvider               secrets.Provider
+}
+
+// A Setting describes the effective value of
+// a configuration key and where it came from.
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+	Secret bool
+}
+
+// Sources of the effective values of settings.
+const (
+	SourceDefault        = "default"
+	SourceFile           = "file"
+	SourceEnvironment    = "environment"
+	SourceSecretFile     = "secret file"
+	SourceSecretProvider = "secret provider"
+)
+
+// A ValidationError lists every problem found
+// while loading a Configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+// defaultConfigFile is the path of the
+// configuration file read if CONFIG_FILE is
+// not set. It is fine for it not to exist.
+const defaultConfigFile = "/etc/jt-slackbot-core/config.yaml"
+
+// secretKeys names the settings whose values
+// must not be shown. Each may instead be read
+// from the file named by the same key with a
+// _FILE suffix, or from the secret provider
+// under the key in lower case.
+var secretKeys = map[string]bool{
+	"SLACK_APP_TOKEN":          true,
+	"SLACK_BOT_TOKEN":          true,
+	"CHAT_COMPLETIONS_API_KEY": true,
+	"ADMIN_API_TOKEN":          true,
+}
+
+// redacted replaces the values of secret
+// settings that are shown.
+const redacted = "[redacted]"
+
+// A RateLimit allows Count events per Period.
+// A zero RateLimit is unlimited.
+type RateLimit struct {
+	Count  int
+	Period time.Duration
+}
+
+// Error returns every problem on its own line.
+func (err *ValidationError) Error() string {
+	return strings.Join(err.Problems, "\n")
+}
+
+// add records the given error as a problem
+// unless it is nil, merging the problems of
+// another ValidationError.
+func (err *ValidationError) add(problem error) {
+	if problem == nil {
+		return
+	}
+	if problems, ok := problem.(*ValidationError); ok {
+		err.Problems = append(err.Problems, problems.Problems...)
+		return
+	}
+	err.Problems = append(err.Problems, problem.Error())
 }
 
 // NewConfiguration returns a new instance of
@@ -30,67 +188,665 @@ type Configuration struct {
 func NewConfiguration() *Configuration {
 	return &Configuration{
 		loadEnvironment: godotenv.Load,
+		readEnvironment: godotenv.Read,
 	}
 }
 
-// Load prepares the Configuration from
-// the environment, returning an error if the
-// required Configuration fields could not
-// be set.
+// Load prepares the Configuration from the
+// defaults, the configuration file and the
+// environment, in increasing precedence. It
+// returns a ValidationError listing every
+// problem found if the Configuration is
+// not valid.
 func (config *Configuration) Load() error {
-	exists := false
-	_ = config.loadEnvironment()
+	problems := &ValidationError{}
+	problems.add(config.loadSources())
 
-	config.ApiUrl, exists = os.LookupEnv("SLACK_API_URL")
-	if !exists {
-		return errors.New("missing slack api url")
+	var exists bool
+	var err error
+	config.SecretProvider = config.lookupString("SECRET_PROVIDER", "none")
+	config.SecretProviderDir = config.lookupString("SECRET_PROVIDER_DIR", "/run/secrets")
+	config.SecretProviderCommand = strings.Fields(config.lookupString("SECRET_PROVIDER_COMMAND", ""))
+	config.SecretProviderTimeout, err = config.lookupDuration("SECRET_PROVIDER_TIMEOUT", 10*time.Second)
+	problems.add(err)
+	config.secretProvider = nil
+	switch config.SecretProvider {
+	case "none":
+	case "file":
+		provider, err := secrets.NewFileProvider(config.SecretProviderDir)
+		if err != nil {
+			problems.add(errors.New("missing secret provider dir"))
+			break
+		}
+		config.secretProvider = provider
+	case "exec":
+		provider, err := secrets.NewExecProvider(config.SecretProviderCommand)
+		if err != nil {
+			problems.add(errors.New("missing secret provider command"))
+			break
+		}
+		config.secretProvider = provider
+	default:
+		problems.add(errors.New("unrecognized secret provider"))
 	}
 
-	config.AppToken, exists = os.LookupEnv("SLACK_APP_TOKEN")
+	config.ApiUrl, exists = config.lookup("SLACK_API_URL", "")
 	if !exists {
-		return errors.New("missing slack app token")
+		problems.add(errors.New("missing slack api url"))
+	} else {
+		problems.add(validateUrl("SLACK_API_URL", config.ApiUrl))
 	}
 
-	config.BotToken, exists = os.LookupEnv("SLACK_BOT_TOKEN")
-	if !exists {
-		return errors.New("missing slack bot token")
+	config.AppToken, exists, err = config.lookupSecret("SLACK_APP_TOKEN")
+	problems.add(err)
+	if !exists && err == nil {
+		problems.add(errors.New("missing slack app token"))
 	}
 
-	retryMax, exists := os.LookupEnv("MAX_CONNECT_ATTEMPTS")
-	if !exists {
-		config.MaxConnectAttempts = 3
-	} else {
-		var err error
-		config.MaxConnectAttempts, err = strconv.Atoi(retryMax)
+	config.BotToken, exists, err = config.lookupSecret("SLACK_BOT_TOKEN")
+	problems.add(err)
+	if !exists && err == nil {
+		problems.add(errors.New("missing slack bot token"))
+	}
+
+	config.MaxConnectAttempts, err = config.lookupInt("MAX_CONNECT_ATTEMPTS", 3)
+	problems.add(err)
+
+	config.DebugWssReconnects, err = config.lookupBool("DEBUG_WEBSOCKET_RECONNECTS", false)
+	problems.add(err)
+
+	switch config.lookupString("LOG_LEVEL", "info") {
+	case "debug":
+		config.LogLevel = zapcore.DebugLevel
+	case "info":
+		config.LogLevel = zapcore.InfoLevel
+	case "warn":
+		config.LogLevel = zapcore.WarnLevel
+	case "error":
+		config.LogLevel = zapcore.ErrorLevel
+	default:
+		problems.add(errors.New("unrecognized log level"))
+	}
+
+	config.LogFilePath = config.lookupString("LOG_FILE_PATH", "/var/log/jt-slackbot-core/main.log")
+
+	config.LogMaxSize, err = config.lookupInt("LOG_MAX_SIZE_MB", 10)
+	problems.add(err)
+
+	config.LogMaxBackups, err = config.lookupInt("LOG_MAX_BACKUPS", 3)
+	problems.add(err)
+
+	config.LogMaxAge, err = config.lookupInt("LOG_MAX_AGE_DAYS", 28)
+	problems.add(err)
+
+	config.SlackTimeout, err = config.lookupDuration("SLACK_TIMEOUT", 10*time.Second)
+	problems.add(err)
+
+	config.ProcessedEventsSize, err = config.lookupInt("PROCESSED_EVENTS_SIZE", 5)
+	problems.add(err)
+	if config.ProcessedEventsSize < 1 {
+		problems.add(errors.New("processed events size must be at least 1"))
+	}
+
+	config.IgnoreBots, err = config.lookupBool("IGNORE_BOTS", false)
+	problems.add(err)
+	config.AllowedBotIds = config.lookupList("ALLOWED_BOT_IDS", nil)
+
+	config.BotLoopThreshold, err = config.lookupInt("BOT_LOOP_THRESHOLD", 5)
+	problems.add(err)
+
+	config.BotLoopWindow, err = config.lookupDuration("BOT_LOOP_WINDOW", time.Minute)
+	problems.add(err)
+
+	config.SyncReplies, err = config.lookupBool("SYNC_REPLIES", true)
+	problems.add(err)
+	syncRepliesByChannel, err := config.lookupChannelMap("SYNC_REPLIES_CHANNELS")
+	problems.add(err)
+	config.SyncRepliesByChannel = make(map[string]bool)
+	for channelId, value := range syncRepliesByChannel {
+		config.SyncRepliesByChannel[channelId] = value == "true"
+	}
+
+	config.EventMaxAttempts, err = config.lookupInt("EVENT_MAX_ATTEMPTS", 3)
+	problems.add(err)
+
+	config.EventRetryDelay, err = config.lookupDuration("EVENT_RETRY_DELAY", time.Second)
+	problems.add(err)
+
+	config.DeadLetterPath = config.lookupString("DEAD_LETTER_PATH", "/var/log/jt-slackbot-core/dead-letters.jsonl")
+
+	config.EventTimeout, err = config.lookupDuration("EVENT_TIMEOUT", 30*time.Second)
+	problems.add(err)
+
+	config.PendingEventsPath = config.lookupString("PENDING_EVENTS_PATH", "/var/log/jt-slackbot-core/pending-events.jsonl")
+
+	config.DrainGracePeriod, err = config.lookupDuration("DRAIN_GRACE_PERIOD", 30*time.Second)
+	problems.add(err)
+
+	config.UserRateLimit, err = config.lookupRateLimit("RATE_LIMIT_USER", "5/1m")
+	problems.add(err)
+
+	config.ChannelRateLimit, err = config.lookupRateLimit("RATE_LIMIT_CHANNEL", "20/1m")
+	problems.add(err)
+
+	config.GlobalRateLimit, err = config.lookupRateLimit("RATE_LIMIT_GLOBAL", "60/1m")
+	problems.add(err)
+
+	config.DialogUrl = config.lookupString("DIALOG_URL", "http://localhost:5000/")
+	problems.add(validateUrl("DIALOG_URL", config.DialogUrl))
+
+	config.DialogTimeout, err = config.lookupDuration("DIALOG_TIMEOUT", 10*time.Second)
+	problems.add(err)
+
+	config.DialogMaxRetries, err = config.lookupInt("DIALOG_MAX_RETRIES", 2)
+	problems.add(err)
+
+	config.DialogMaxIdleConns, err = config.lookupInt("DIALOG_MAX_IDLE_CONNS", 10)
+	problems.add(err)
+
+	config.DialogHistoryLength, err = config.lookupInt("DIALOG_HISTORY_LENGTH", 10)
+	problems.add(err)
+
+	config.ConfidenceThreshold, err = config.lookupFloat("CONFIDENCE_THRESHOLD", 0.5)
+	problems.add(err)
+
+	confidenceThresholdByChannel, err := config.lookupChannelMap("CONFIDENCE_THRESHOLD_CHANNELS")
+	problems.add(err)
+	config.ConfidenceThresholdByChannel = make(map[string]float64)
+	for channelId, value := range confidenceThresholdByChannel {
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			problems.add(fmt.Errorf("invalid value for CONFIDENCE_THRESHOLD_CHANNELS: %s", err))
+			continue
+		}
+		config.ConfidenceThresholdByChannel[channelId] = threshold
+	}
+
+	config.LowConfidenceAction = config.lookupString("LOW_CONFIDENCE_ACTION", "clarify")
+	switch config.LowConfidenceAction {
+	case "clarify", "suggest", "silent":
+	default:
+		problems.add(errors.New("unrecognized low confidence action"))
+	}
+
+	config.ClarifyReply = config.lookupString("CLARIFY_REPLY", "")
+
+	config.FeedbackLogPath = config.lookupString("FEEDBACK_LOG_PATH", "/var/log/jt-slackbot-core/feedback.jsonl")
+	config.ForwardFeedback, err = config.lookupBool("FEEDBACK_FORWARD", true)
+	problems.add(err)
+
+	config.PositiveReactions = config.lookupList(
+		"FEEDBACK_POSITIVE_REACTIONS",
+		[]string{"+1", "heart", "tada", "joy"},
+	)
+	config.NegativeReactions = config.lookupList(
+		"FEEDBACK_NEGATIVE_REACTIONS",
+		[]string{"-1", "confused", "thinking_face"},
+	)
+
+	config.TrainerUserIds = config.lookupList("TRAINER_USER_IDS", nil)
+
+	config.Responder = config.lookupString("RESPONDER", "dialog")
+
+	config.ResponderByChannel, err = config.lookupChannelMap("RESPONDER_CHANNELS")
+	problems.add(err)
+
+	config.RulesPath = config.lookupString("RULES_PATH", "")
+	config.ChatCompletionsUrl = config.lookupString("CHAT_COMPLETIONS_URL", "")
+	if config.ChatCompletionsUrl != "" {
+		problems.add(validateUrl("CHAT_COMPLETIONS_URL", config.ChatCompletionsUrl))
+	}
+	config.ChatCompletionsKey, _, err = config.lookupSecret("CHAT_COMPLETIONS_API_KEY")
+	problems.add(err)
+	config.ChatCompletionsModel = config.lookupString("CHAT_COMPLETIONS_MODEL", "")
+	config.ChatCompletionsPrompt = config.lookupString("CHAT_COMPLETIONS_SYSTEM_PROMPT", "")
+
+	config.DialogBreakerThreshold, err = config.lookupInt("DIALOG_BREAKER_THRESHOLD", 5)
+	problems.add(err)
+	if config.DialogBreakerThreshold < 1 {
+		problems.add(errors.New("dialog breaker threshold must be at least 1"))
+	}
+
+	config.DialogBreakerCooldown, err = config.lookupDuration("DIALOG_BREAKER_COOLDOWN", 30*time.Second)
+	problems.add(err)
+
+	config.DialogBreakerProbes, err = config.lookupInt("DIALOG_BREAKER_PROBES", 1)
+	problems.add(err)
+
+	config.FallbackResponder = config.lookupString("FALLBACK_RESPONDER", "")
+	config.FallbackReply = config.lookupString("FALLBACK_REPLY", "")
+
+	config.FallbackRecallSize, err = config.lookupInt("FALLBACK_RECALL_SIZE", 100)
+	problems.add(err)
+
+	config.MaintenanceReply = config.lookupString("MAINTENANCE_REPLY", "")
+
+	config.ResponseCacheTtl, err = config.lookupDuration("RESPONSE_CACHE_TTL", 10*time.Minute)
+	problems.add(err)
+
+	config.ResponseCacheSize, err = config.lookupInt("RESPONSE_CACHE_SIZE", 500)
+	problems.add(err)
+	if config.ResponseCacheSize < 1 {
+		problems.add(errors.New("response cache size must be at least 1"))
+	}
+
+	config.ResponseCacheScope = config.lookupString("RESPONSE_CACHE_SCOPE", "global")
+	switch config.ResponseCacheScope {
+	case "global", "channel":
+	default:
+		problems.add(errors.New("unrecognized response cache scope"))
+	}
+
+	config.AdminUserIds = config.lookupList("ADMIN_USER_IDS", nil)
+
+	config.IntentDetection, err = config.lookupBool("INTENT_DETECTION", true)
+	problems.add(err)
+
+	config.IntentResponders, err = config.lookupChannelMap("INTENT_RESPONDERS")
+	problems.add(err)
+
+	config.HelpKeywords = config.lookupList("INTENT_HELP_KEYWORDS", nil)
+	config.GreetingKeywords = config.lookupList("INTENT_GREETING_KEYWORDS", nil)
+
+	config.HealthAddr = config.lookupString("HEALTH_ADDR", "")
+
+	config.AdminApiAddr = config.lookupString("ADMIN_API_ADDR", "")
+	config.AdminApiToken, _, err = config.lookupSecret("ADMIN_API_TOKEN")
+	problems.add(err)
+	if config.AdminApiAddr != "" && config.AdminApiToken.Empty() && err == nil {
+		problems.add(errors.New("missing admin api token"))
+	}
+
+	config.LeaderElection = config.lookupString("LEADER_ELECTION", "none")
+	switch config.LeaderElection {
+	case "none", "file":
+	default:
+		problems.add(errors.New("unrecognized leader election"))
+	}
+
+	config.LeaderLockPath = config.lookupString("LEADER_LOCK_PATH", "/var/lib/jt-slackbot-core/leader.lock")
+
+	config.LeaderRetryInterval, err = config.lookupDuration("LEADER_RETRY_INTERVAL", 2*time.Second)
+	problems.add(err)
+
+	config.TracingExporter = config.lookupString("TRACING_EXPORTER", "none")
+	switch config.TracingExporter {
+	case "none", "otlp", "stdout", "file":
+	default:
+		problems.add(errors.New("unrecognized tracing exporter"))
+	}
+
+	config.TracingFilePath = config.lookupString("TRACING_FILE_PATH", "/var/log/jt-slackbot-core/traces.jsonl")
+
+	for _, key := range sortedKeys(config.fileValues) {
+		if _, known := config.settings[key]; !known {
+			problems.add(fmt.Errorf(
+				"unknown setting %s in %s",
+				strings.ToLower(key),
+				config.ConfigFile,
+			))
+		}
+	}
+	config.fileValues = nil
+
+	if len(problems.Problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// LoadHealthAddr prepares only the address of
+// the health server from the configuration
+// file and the environment, so a running bot
+// can be probed without reading secrets or
+// validating any other setting.
+func (config *Configuration) LoadHealthAddr() error {
+	err := config.loadSources()
+	config.HealthAddr = config.lookupString("HEALTH_ADDR", "")
+	config.fileValues = nil
+	return err
+}
+
+// loadSources loads the environment files and
+// reads the configuration file, which is fine
+// not to exist unless it was named explicitly.
+func (config *Configuration) loadSources() error {
+	if config.processEnvironment == nil {
+		config.processEnvironment = environmentKeys()
+	}
+	_ = config.loadEnvironment()
+
+	config.settings = make(map[string]Setting)
+	config.fileValues = nil
+	config.ConfigFile = defaultConfigFile
+	path, exists := os.LookupEnv("CONFIG_FILE")
+	if exists {
+		config.ConfigFile = path
+	}
+	if config.ConfigFile == "" {
+		return nil
+	}
+	var err error
+	config.fileValues, err = readConfigFile(config.ConfigFile)
+	if err != nil && (exists || !os.IsNotExist(err)) {
+		return err
+	}
+	return nil
+}
+
+// Settings returns the effective value and
+// source of every setting as of the last time
+// the Configuration was loaded, sorted by key.
+// The values of secrets are redacted.
+func (config *Configuration) Settings() []Setting {
+	settings := make([]Setting, 0, len(config.settings))
+	for _, setting := range config.settings {
+		settings = append(settings, setting)
+	}
+	sort.Slice(settings, func(i, j int) bool {
+		return settings[i].Key < settings[j].Key
+	})
+	return settings
+}
+
+// Reload prepares the Configuration again from
+// the environment, picking up changes to the
+// environment files since it was loaded.
+// Variables set by the process itself still
+// take precedence over the files.
+func (config *Configuration) Reload() error {
+	if config.processEnvironment == nil {
+		return config.Load()
+	}
+	values, err := config.readEnvironment()
+	if err != nil {
+		values = nil
+	}
+	for key := range environmentKeys() {
+		_, inFiles := values[key]
+		if !inFiles && !config.processEnvironment[key] {
+			_ = os.Unsetenv(key)
+		}
+	}
+	for key, value := range values {
+		if config.processEnvironment[key] {
+			continue
+		}
+		err = os.Setenv(key, value)
 		if err != nil {
 			return err
 		}
 	}
+	return config.Load()
+}
+
+// environmentKeys returns the names of the
+// variables currently in the environment.
+func environmentKeys() map[string]bool {
+	keys := make(map[string]bool)
+	for _, pair := range os.Environ() {
+		keys[strings.SplitN(pair, "=", 2)[0]] = true
+	}
+	return keys
+}
 
-	debugWssReconnects, exists := os.LookupEnv("DEBUG_WEBSOCKET_RECONNECTS")
+// lookup returns the value of the environment
+// variable named by the key, or else the value
+// of the setting in the configuration file, and
+// whether either is present. The effective
+// value is recorded along with its source,
+// which is the given fallback if neither
+// is present.
+func (config *Configuration) lookup(key string, fallback string) (string, bool) {
+	value, exists := os.LookupEnv(key)
+	source := SourceEnvironment
 	if !exists {
-		config.DebugWssReconnects = false
+		value, exists = config.fileValues[key]
+		source = SourceFile
+	}
+	if exists {
+		config.record(key, value, source)
 	} else {
-		config.DebugWssReconnects = debugWssReconnects == "true"
+		config.record(key, fallback, SourceDefault)
+	}
+	return value, exists
+}
+
+// lookupSecret returns the secret setting named
+// by the key and whether it is present. It is
+// looked up as a plain setting, then in the
+// file named by the setting of the key with a
+// _FILE suffix, then from the secret provider.
+// Empty settings count as missing, and setting
+// both the key and its file is an error.
+func (config *Configuration) lookupSecret(key string) (secrets.Secret, bool, error) {
+	value, _ := config.lookup(key, "")
+	exists := value != ""
+	path, _ := config.lookup(key+"_FILE", "")
+	fileExists := path != ""
+	if exists && fileExists {
+		return secrets.Secret{}, false, fmt.Errorf("both %s and %s_FILE are set", key, key)
+	}
+	if exists {
+		return secrets.New(value), true, nil
+	}
+	if fileExists {
+		secret, err := secrets.ReadFile(path)
+		if err != nil {
+			return secrets.Secret{}, false, fmt.Errorf("failed to read %s_FILE: %s", key, err)
+		}
+		config.record(key, secret.Reveal(), SourceSecretFile)
+		return secret, true, nil
+	}
+	if config.secretProvider == nil {
+		return secrets.Secret{}, false, nil
+	}
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		config.SecretProviderTimeout,
+	)
+	defer cancel()
+	secret, err := config.secretProvider.Secret(ctx, strings.ToLower(key))
+	if errors.Is(err, secrets.ErrNotFound) {
+		return secrets.Secret{}, false, nil
+	}
+	if err != nil {
+		return secrets.Secret{}, false, err
+	}
+	config.record(key, secret.Reveal(), SourceSecretProvider)
+	return secret, true, nil
+}
+
+// record records the effective value of the
+// setting named by the key and its source. The
+// values of secrets are redacted before they
+// are recorded, so that they are held nowhere
+// but in the Configuration fields.
+func (config *Configuration) record(key string, value string, source string) {
+	setting := Setting{
+		Key:    key,
+		Value:  value,
+		Source: source,
+		Secret: secretKeys[key],
 	}
+	if setting.Secret && setting.Value != "" {
+		setting.Value = redacted
+	}
+	config.settings[key] = setting
+}
 
-	logLevel, exists := os.LookupEnv("LOG_LEVEL")
+// lookupString returns the value of the setting
+// named by the key or the fallback if the
+// setting is not present.
+func (config *Configuration) lookupString(key string, fallback string) string {
+	value, exists := config.lookup(key, fallback)
 	if !exists {
-		config.LogLevel = zapcore.InfoLevel
-	} else {
-		switch logLevel {
-		case "debug":
-			config.LogLevel = zapcore.DebugLevel
-		case "info":
-			config.LogLevel = zapcore.InfoLevel
-		case "warn":
-			config.LogLevel = zapcore.WarnLevel
-		case "error":
-			config.LogLevel = zapcore.ErrorLevel
-		default:
-			return errors.New("unrecognized log level")
+		return fallback
+	}
+	return value
+}
+
+// lookupBool returns the boolean value of the
+// setting named by the key or the fallback if
+// the setting is not present.
+func (config *Configuration) lookupBool(key string, fallback bool) (bool, error) {
+	value, exists := config.lookup(key, strconv.FormatBool(fallback))
+	if !exists {
+		return fallback, nil
+	}
+	switch value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	default:
+		return fallback, fmt.Errorf("invalid value for %s: %s", key, value)
+	}
+}
+
+// lookupInt returns the integer value of the
+// setting named by the key or the fallback if
+// the setting is not present.
+func (config *Configuration) lookupInt(key string, fallback int) (int, error) {
+	value, exists := config.lookup(key, strconv.Itoa(fallback))
+	if !exists {
+		return fallback, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid value for %s: %s", key, value)
+	}
+	return parsed, nil
+}
+
+// lookupFloat returns the float value of the
+// setting named by the key or the fallback if
+// the setting is not present.
+func (config *Configuration) lookupFloat(key string, fallback float64) (float64, error) {
+	value, exists := config.lookup(key, strconv.FormatFloat(fallback, 'g', -1, 64))
+	if !exists {
+		return fallback, nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid value for %s: %s", key, value)
+	}
+	return parsed, nil
+}
+
+// lookupDuration returns the duration value of
+// the setting named by the key or the fallback
+// if the setting is not present.
+func (config *Configuration) lookupDuration(
+	key string,
+	fallback time.Duration,
+) (time.Duration, error) {
+	value, exists := config.lookup(key, fallback.String())
+	if !exists {
+		return fallback, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid value for %s: %s", key, value)
+	}
+	return parsed, nil
+}
+
+// lookupList returns the comma-separated values
+// of the setting named by the key or the
+// fallback if the setting is not present
+// or empty.
+func (config *Configuration) lookupList(key string, fallback []string) []string {
+	value, exists := config.lookup(key, strings.Join(fallback, ","))
+	if !exists {
+		return fallback
+	}
+	list := splitList(value)
+	if list == nil {
+		return fallback
+	}
+	return list
+}
+
+// lookupChannelMap returns the comma-separated
+// channel=value pairs of the setting named by
+// the key or an empty map if the setting is
+// not present.
+func (config *Configuration) lookupChannelMap(key string) (map[string]string, error) {
+	value, _ := config.lookup(key, "")
+	channelMap := make(map[string]string)
+	for _, pair := range splitList(value) {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid value for %s: %s", key, pair)
+		}
+		channelMap[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return channelMap, nil
+}
+
+// lookupRateLimit returns the rate limit described
+// by the setting named by the key in the form
+// count/period, such as 5/1m, or by the fallback
+// if the setting is not present. An empty value
+// disables the rate limit.
+func (config *Configuration) lookupRateLimit(key string, fallback string) (RateLimit, error) {
+	value, exists := config.lookup(key, fallback)
+	if !exists {
+		value = fallback
+	}
+	if value == "" {
+		return RateLimit{}, nil
+	}
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return RateLimit{}, fmt.Errorf("invalid value for %s: %s", key, value)
+	}
+	count, err := strconv.Atoi(parts[0])
+	if err != nil || count < 0 {
+		return RateLimit{}, fmt.Errorf("invalid value for %s: %s", key, value)
+	}
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid value for %s: %s", key, value)
+	}
+	return RateLimit{
+		Count:  count,
+		Period: period,
+	}, nil
+}
+
+// sortedKeys returns the keys of the given
+// map in order.
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splitList returns the trimmed, non-empty
+// comma-separated values of the given string.
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
 		}
 	}
+	return list
+}
 
+// validateUrl returns an error unless the value
+// of the setting named by the key is an
+// absolute URL.
+func validateUrl(key string, value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("invalid value for %s: %s", key, value)
+	}
 	return nil
 }
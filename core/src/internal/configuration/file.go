@@ -0,0 +1,101 @@
+package configuration
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// readConfigFile reads the YAML configuration
+// file at the given path. Its top-level keys are
+// the names of the environment variables in lower
+// case, such as slack_api_url, and are returned
+// in upper case with their values in the form the
+// environment variables take: lists are joined
+// with commas and maps become comma-separated
+// key=value pairs. Values of any other form are
+// reported in a ValidationError.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]interface{}
+	err = yaml.UnmarshalStrict(data, &document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %s", path, err)
+	}
+
+	values := make(map[string]string)
+	problems := &ValidationError{}
+	for key, value := range document {
+		formatted, ok := formatValue(value)
+		if !ok {
+			problems.add(fmt.Errorf("invalid value for %s in %s", key, path))
+			continue
+		}
+		values[strings.ToUpper(key)] = formatted
+	}
+	if len(problems.Problems) > 0 {
+		sort.Strings(problems.Problems)
+		return values, problems
+	}
+	return values, nil
+}
+
+// formatValue returns the given YAML value in the
+// form of an environment variable, or false if it
+// is nested too deeply.
+func formatValue(value interface{}) (string, bool) {
+	switch value := value.(type) {
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			formatted, ok := formatScalar(item)
+			if !ok {
+				return "", false
+			}
+			items = append(items, formatted)
+		}
+		return strings.Join(items, ","), true
+	case map[interface{}]interface{}:
+		pairs := make([]string, 0, len(value))
+		for key, item := range value {
+			formattedKey, ok := formatScalar(key)
+			if !ok {
+				return "", false
+			}
+			formatted, ok := formatScalar(item)
+			if !ok {
+				return "", false
+			}
+			pairs = append(pairs, formattedKey+"="+formatted)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ","), true
+	default:
+		return formatScalar(value)
+	}
+}
+
+// formatScalar returns the given YAML scalar as
+// a string, or false if it is not a scalar.
+func formatScalar(value interface{}) (string, bool) {
+	switch value := value.(type) {
+	case nil:
+		return "", true
+	case string:
+		return value, true
+	case bool:
+		return strconv.FormatBool(value), true
+	case int:
+		return strconv.Itoa(value), true
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,425 @@
+package dialog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/drewnorman/jt-slackbot/core/internal/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// A dialog.Client makes requests to the
+// dialog service.
+type Client struct {
+	logger     *zap.Logger
+	baseUrl    string
+	maxRetries int
+	retryDelay time.Duration
+	httpClient *http.Client
+	metrics    *metrics.Metrics
+}
+
+// dialog.ClientParameters describe how a
+// new dialog.Client should be created.
+type ClientParameters struct {
+	Logger       *zap.Logger
+	BaseUrl      string
+	Timeout      time.Duration
+	MaxRetries   int
+	RetryDelay   time.Duration
+	MaxIdleConns int
+	HttpClient   *http.Client
+	Metrics      *metrics.Metrics
+}
+
+// A dialog.StatusError is returned when the
+// dialog service responds with an unexpected
+// HTTP status code.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+// defaultTimeout, defaultRetryDelay, and
+// defaultMaxIdleConns determine how requests
+// to the dialog service are made unless
+// specified otherwise.
+const (
+	defaultTimeout      = 10 * time.Second
+	defaultRetryDelay   = 200 * time.Millisecond
+	defaultMaxIdleConns = 10
+)
+
+// tracer records spans for requests to the
+// dialog service.
+var tracer = otel.Tracer("github.com/drewnorman/jt-slackbot/core/internal/dialog")
+
+// maxErrorBodyLength limits how much of an
+// unexpected response body is kept for
+// error reporting.
+const maxErrorBodyLength = 512
+
+// Error returns a description of the
+// unexpected status code.
+func (err *StatusError) Error() string {
+	return fmt.Sprintf(
+		"dialog service responded with status %d: %s",
+		err.StatusCode,
+		err.Body,
+	)
+}
+
+// Temporary returns true if the status code
+// indicates the request may succeed later.
+func (err *StatusError) Temporary() bool {
+	return err.StatusCode == http.StatusTooManyRequests ||
+		err.StatusCode >= http.StatusInternalServerError
+}
+
+// NewClient returns a new dialog.Client
+// according to the given parameters.
+func NewClient(params *ClientParameters) (*Client, error) {
+	if params.Logger == nil {
+		return nil, errors.New("missing logger")
+	}
+	if params.BaseUrl == "" {
+		return nil, errors.New("missing base url")
+	}
+
+	maxRetries := 0
+	if params.MaxRetries > 0 {
+		maxRetries = params.MaxRetries
+	}
+	retryDelay := defaultRetryDelay
+	if params.RetryDelay > 0 {
+		retryDelay = params.RetryDelay
+	}
+
+	httpClient := params.HttpClient
+	if httpClient == nil {
+		timeout := defaultTimeout
+		if params.Timeout > 0 {
+			timeout = params.Timeout
+		}
+		maxIdleConns := defaultMaxIdleConns
+		if params.MaxIdleConns > 0 {
+			maxIdleConns = params.MaxIdleConns
+		}
+		httpClient = &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					Timeout:   timeout,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				MaxIdleConns:        maxIdleConns,
+				MaxIdleConnsPerHost: maxIdleConns,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		}
+	}
+
+	return &Client{
+		logger:     params.Logger,
+		baseUrl:    strings.TrimSuffix(params.BaseUrl, "/") + "/",
+		maxRetries: maxRetries,
+		retryDelay: retryDelay,
+		httpClient: httpClient,
+		metrics:    params.Metrics,
+	}, nil
+}
+
+// Converse requests a reply to the given
+// conversation from the dialog service.
+func (client *Client) Converse(
+	ctx context.Context,
+	conversation *Conversation,
+) (*Reply, error) {
+	if conversation == nil || conversation.Message == "" {
+		return nil, errors.New("missing message")
+	}
+	request := *conversation
+	request.Version = ProtocolVersion
+
+	var body replyBody
+	err := client.post(ctx, "converse", true, &request, &body)
+	if err != nil {
+		return nil, err
+	}
+
+	if body.Reply == nil {
+		return nil, errors.New("failed to find reply in response")
+	}
+	confidence := 1.0
+	if body.Confidence != nil {
+		confidence = *body.Confidence
+	}
+	return &Reply{
+		Text:        *body.Reply,
+		Confidence:  confidence,
+		Reactions:   body.Reactions,
+		Blocks:      body.Blocks,
+		Suggestions: body.Suggestions,
+	}, nil
+}
+
+// SendFeedback sends the given feedback on a
+// reply to the dialog service.
+func (client *Client) SendFeedback(
+	ctx context.Context,
+	feedback *Feedback,
+) error {
+	if feedback == nil || feedback.Message == "" || feedback.Reply == "" {
+		return errors.New("missing message or reply")
+	}
+	if feedback.Label == "" {
+		return errors.New("missing label")
+	}
+	request := *feedback
+	request.Version = ProtocolVersion
+
+	var ignored map[string]interface{}
+	return client.post(ctx, "feedback", false, &request, &ignored)
+}
+
+// Train sends the given training to the
+// dialog service.
+func (client *Client) Train(
+	ctx context.Context,
+	training *Training,
+) error {
+	if training == nil || training.Statement == "" {
+		return errors.New("missing statement")
+	}
+	switch training.Action {
+	case TrainingTeach:
+		if training.Response == "" {
+			return errors.New("missing response")
+		}
+	case TrainingForget:
+	default:
+		return fmt.Errorf("unknown training action %q", training.Action)
+	}
+	request := *training
+	request.Version = ProtocolVersion
+
+	var ignored map[string]interface{}
+	return client.post(ctx, "train", false, &request, &ignored)
+}
+
+// Ping checks that the dialog service is
+// reachable and reports itself healthy,
+// without retrying.
+func (client *Client) Ping(ctx context.Context) (err error) {
+	ctx, end := client.trace(ctx, "healthz")
+	start := time.Now()
+	defer func() {
+		client.metrics.DialogCalled("healthz", time.Since(start), err)
+		end(err)
+	}()
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"GET",
+		client.baseUrl+"healthz",
+		nil,
+	)
+	if err != nil {
+		return errors.New("failed to init request")
+	}
+	req.Header.Add("Accept", "application/json")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLength))
+		return &StatusError{
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(body)),
+		}
+	}
+	return nil
+}
+
+// CloseIdleConnections closes the connections
+// to the dialog service that are not in use.
+func (client *Client) CloseIdleConnections() {
+	client.httpClient.CloseIdleConnections()
+}
+
+// post makes a POST request with the given JSON
+// body to the dialog service, retrying failures
+// that are likely to be temporary, and decodes
+// the response into out. Requests that are not
+// idempotent are only retried if they failed
+// before reaching the dialog service, so that
+// they are never applied twice.
+func (client *Client) post(
+	ctx context.Context,
+	endpoint string,
+	idempotent bool,
+	body interface{},
+	out interface{},
+) error {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	attempt := 0
+	for {
+		err := client.postOnce(ctx, endpoint, jsonData, out)
+		if err == nil {
+			return nil
+		}
+		if attempt >= client.maxRetries || !retryable(err) ||
+			(!idempotent && !unsent(err)) {
+			return err
+		}
+		attempt++
+		client.logger.Debug(
+			"retrying dialog request",
+			zap.String("endpoint", endpoint),
+			zap.String("err", err.Error()),
+			zap.Int("attempt", attempt),
+		)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(client.retryDelay * time.Duration(attempt)):
+		}
+	}
+}
+
+// postOnce makes a single POST request with the
+// given JSON body to the dialog service and
+// decodes the response into out.
+func (client *Client) postOnce(
+	ctx context.Context,
+	endpoint string,
+	jsonData []byte,
+	out interface{},
+) (err error) {
+	ctx, end := client.trace(ctx, endpoint)
+	start := time.Now()
+	defer func() {
+		client.metrics.DialogCalled(endpoint, time.Since(start), err)
+		end(err)
+	}()
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		client.baseUrl+endpoint,
+		bytes.NewReader(jsonData),
+	)
+	if err != nil {
+		return errors.New("failed to init request")
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Accept", "application/json")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLength))
+		return &StatusError{
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(body)),
+		}
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// trace starts a span for a single request to
+// the given endpoint of the dialog service and
+// returns a context carrying it, along with a
+// function ending the span with the outcome
+// of the request.
+func (client *Client) trace(
+	ctx context.Context,
+	endpoint string,
+) (context.Context, func(err error)) {
+	ctx, span := tracer.Start(
+		ctx,
+		"dialog "+endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("dialog.endpoint", endpoint),
+		),
+	)
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			span.SetAttributes(attribute.Int("http.status_code", statusErr.StatusCode))
+		}
+		span.End()
+	}
+}
+
+// retryable returns true if the given error
+// from a single request is worth retrying.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Temporary()
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// unsent returns true if the given error from
+// a single request shows that the request never
+// reached the dialog service, because no
+// connection could be made.
+func unsent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
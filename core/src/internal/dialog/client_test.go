@@ -0,0 +1,683 @@
+package dialog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/brianvoe/gofakeit/v6"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func fakeZapLogger() *zap.Logger {
+	return zap.New(
+		zapcore.NewCore(
+			zapcore.NewJSONEncoder(
+				zap.NewProductionEncoderConfig(),
+			),
+			zapcore.AddSync(
+				os.NewFile(0, os.DevNull),
+			),
+			zap.FatalLevel,
+		),
+	)
+}
+
+func fakeDialogServer(
+	t *testing.T,
+	handler http.HandlerFunc,
+) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewClient(t *testing.T) {
+	type args struct {
+		params *ClientParameters
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "ReturnsClient",
+			args: args{
+				params: &ClientParameters{
+					Logger:  fakeZapLogger(),
+					BaseUrl: gofakeit.URL(),
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingLogger",
+			args: args{
+				params: &ClientParameters{
+					BaseUrl: gofakeit.URL(),
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingBaseUrl",
+			args: args{
+				params: &ClientParameters{
+					Logger: fakeZapLogger(),
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClient(tt.args.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_Converse(t *testing.T) {
+	reply := gofakeit.LoremIpsumSentence(5)
+
+	tests := []struct {
+		name      string
+		statuses  []int
+		body      map[string]interface{}
+		want      string
+		wantCalls int32
+		wantErr   bool
+	}{
+		{
+			name:      "ReturnsReply",
+			statuses:  []int{http.StatusOK},
+			body:      map[string]interface{}{"reply": reply},
+			want:      reply,
+			wantCalls: 1,
+			wantErr:   false,
+		},
+		{
+			name: "RetriesServerErrors",
+			statuses: []int{
+				http.StatusServiceUnavailable,
+				http.StatusOK,
+			},
+			body:      map[string]interface{}{"reply": reply},
+			want:      reply,
+			wantCalls: 2,
+			wantErr:   false,
+		},
+		{
+			name: "StopsAfterMaxRetries",
+			statuses: []int{
+				http.StatusInternalServerError,
+				http.StatusInternalServerError,
+				http.StatusInternalServerError,
+				http.StatusOK,
+			},
+			body:      map[string]interface{}{"reply": reply},
+			wantCalls: 3,
+			wantErr:   true,
+		},
+		{
+			name:      "DoesNotRetryClientErrors",
+			statuses:  []int{http.StatusBadRequest, http.StatusOK},
+			body:      map[string]interface{}{"reply": reply},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:     "ReturnsRichReply",
+			statuses: []int{http.StatusOK},
+			body: map[string]interface{}{
+				"reply":       reply,
+				"confidence":  0.25,
+				"reactions":   []string{"dog"},
+				"suggestions": []string{"Who is a good dog?"},
+				"blocks": []interface{}{
+					map[string]interface{}{"type": "divider"},
+				},
+			},
+			want:      reply,
+			wantCalls: 1,
+			wantErr:   false,
+		},
+		{
+			name:      "MissingReply",
+			statuses:  []int{http.StatusOK},
+			body:      map[string]interface{}{},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := fakeDialogServer(
+				t,
+				func(w http.ResponseWriter, r *http.Request) {
+					call := atomic.AddInt32(&calls, 1)
+					if r.URL.Path != "/converse" {
+						t.Errorf("Converse() path = %v, want %v", r.URL.Path, "/converse")
+					}
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(tt.statuses[call-1])
+					_ = json.NewEncoder(w).Encode(tt.body)
+				},
+			)
+
+			client, err := NewClient(
+				&ClientParameters{
+					Logger:     fakeZapLogger(),
+					BaseUrl:    server.URL,
+					MaxRetries: 2,
+					RetryDelay: time.Millisecond,
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := client.Converse(context.Background(), &Conversation{Message: "hello"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Converse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.Text != tt.want {
+				t.Errorf("Converse() = %v, want %v", got.Text, tt.want)
+			}
+			if err == nil {
+				wantConfidence := 1.0
+				if confidence, ok := tt.body["confidence"].(float64); ok {
+					wantConfidence = confidence
+				}
+				if got.Confidence != wantConfidence {
+					t.Errorf("Converse() confidence = %v, want %v", got.Confidence, wantConfidence)
+				}
+				if _, ok := tt.body["reactions"]; ok && len(got.Reactions) != 1 {
+					t.Errorf("Converse() reactions = %v, want %v", got.Reactions, tt.body["reactions"])
+				}
+				if _, ok := tt.body["suggestions"]; ok && len(got.Suggestions) != 1 {
+					t.Errorf("Converse() suggestions = %v, want %v", got.Suggestions, tt.body["suggestions"])
+				}
+				if _, ok := tt.body["blocks"]; ok && len(got.Blocks) != 1 {
+					t.Errorf("Converse() blocks = %v, want %v", got.Blocks, tt.body["blocks"])
+				}
+			}
+			if atomic.LoadInt32(&calls) != tt.wantCalls {
+				t.Errorf("Converse() calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestClient_ConverseTimesOut(t *testing.T) {
+	server := fakeDialogServer(
+		t,
+		func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(50 * time.Millisecond)
+		},
+	)
+
+	client, err := NewClient(
+		&ClientParameters{
+			Logger:     fakeZapLogger(),
+			BaseUrl:    server.URL,
+			Timeout:    10 * time.Millisecond,
+			MaxRetries: 1,
+			RetryDelay: time.Millisecond,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Converse(context.Background(), &Conversation{Message: "hello"})
+	if err == nil {
+		t.Errorf("Converse() error = %v, wantErr %v", err, true)
+	}
+}
+
+func TestStatusError_Temporary(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		want       bool
+	}{
+		{http.StatusBadRequest, false},
+		{http.StatusNotFound, false},
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusBadGateway, true},
+	}
+	for _, tt := range tests {
+		err := &StatusError{StatusCode: tt.statusCode}
+		if got := err.Temporary(); got != tt.want {
+			t.Errorf("Temporary() for %d = %v, want %v", tt.statusCode, got, tt.want)
+		}
+	}
+}
+
+func TestClient_ConverseSendsConversation(t *testing.T) {
+	var received map[string]interface{}
+	server := fakeDialogServer(
+		t,
+		func(w http.ResponseWriter, r *http.Request) {
+			err := json.NewDecoder(r.Body).Decode(&received)
+			if err != nil {
+				t.Error(err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"reply": "Woof!"})
+		},
+	)
+
+	client, err := NewClient(
+		&ClientParameters{
+			Logger:  fakeZapLogger(),
+			BaseUrl: server.URL,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Converse(
+		context.Background(),
+		&Conversation{
+			SessionId: SessionId("C0123", ""),
+			Message:   "hello",
+			User:      &User{Id: "U0123", Name: "Jimmy"},
+			Channel:   &Channel{Id: "C0123", Type: "channel"},
+			History:   []*Message{{UserId: "U0123", Text: "hi"}},
+			Locale:    "en-US",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if received["version"] != float64(ProtocolVersion) {
+		t.Errorf("Converse() version = %v, want %v", received["version"], ProtocolVersion)
+	}
+	if received["message"] != "hello" {
+		t.Errorf("Converse() message = %v, want %v", received["message"], "hello")
+	}
+	user, _ := received["user"].(map[string]interface{})
+	if user["name"] != "Jimmy" {
+		t.Errorf("Converse() user = %v, want %v", user, "Jimmy")
+	}
+	history, _ := received["history"].([]interface{})
+	if len(history) != 1 {
+		t.Errorf("Converse() history = %v, want %v", history, 1)
+	}
+}
+
+func TestClient_SendFeedback(t *testing.T) {
+	tests := []struct {
+		name     string
+		feedback *Feedback
+		status   int
+		wantErr  bool
+	}{
+		{
+			name: "SendsFeedback",
+			feedback: &Feedback{
+				Message: "hello",
+				Reply:   "Woof!",
+				Label:   "positive",
+			},
+			status:  http.StatusOK,
+			wantErr: false,
+		},
+		{
+			name: "MissingReply",
+			feedback: &Feedback{
+				Message: "hello",
+				Label:   "positive",
+			},
+			status:  http.StatusOK,
+			wantErr: true,
+		},
+		{
+			name: "MissingLabel",
+			feedback: &Feedback{
+				Message: "hello",
+				Reply:   "Woof!",
+			},
+			status:  http.StatusOK,
+			wantErr: true,
+		},
+		{
+			name: "EndpointMissing",
+			feedback: &Feedback{
+				Message: "hello",
+				Reply:   "Woof!",
+				Label:   "negative",
+			},
+			status:  http.StatusNotFound,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeDialogServer(
+				t,
+				func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/feedback" {
+						t.Errorf("SendFeedback() path = %v, want %v", r.URL.Path, "/feedback")
+					}
+					var received map[string]interface{}
+					_ = json.NewDecoder(r.Body).Decode(&received)
+					if received["label"] != tt.feedback.Label {
+						t.Errorf("SendFeedback() label = %v, want %v", received["label"], tt.feedback.Label)
+					}
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(tt.status)
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{"ok": true})
+				},
+			)
+
+			client, err := NewClient(
+				&ClientParameters{
+					Logger:  fakeZapLogger(),
+					BaseUrl: server.URL,
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = client.SendFeedback(context.Background(), tt.feedback)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SendFeedback() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_Train(t *testing.T) {
+	tests := []struct {
+		name     string
+		training *Training
+		wantErr  bool
+	}{
+		{
+			name: "Teaches",
+			training: &Training{
+				Action:    TrainingTeach,
+				Statement: "Who is a good dog?",
+				Response:  "Me!",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Forgets",
+			training: &Training{
+				Action:    TrainingForget,
+				Statement: "Who is a good dog?",
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingResponse",
+			training: &Training{
+				Action:    TrainingTeach,
+				Statement: "Who is a good dog?",
+			},
+			wantErr: true,
+		},
+		{
+			name: "UnknownAction",
+			training: &Training{
+				Action:    "sit",
+				Statement: "Who is a good dog?",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeDialogServer(
+				t,
+				func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/train" {
+						t.Errorf("Train() path = %v, want %v", r.URL.Path, "/train")
+					}
+					var received map[string]interface{}
+					_ = json.NewDecoder(r.Body).Decode(&received)
+					if received["action"] != tt.training.Action {
+						t.Errorf("Train() action = %v, want %v", received["action"], tt.training.Action)
+					}
+					w.Header().Set("Content-Type", "application/json")
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{"ok": true})
+				},
+			)
+
+			client, err := NewClient(
+				&ClientParameters{
+					Logger:  fakeZapLogger(),
+					BaseUrl: server.URL,
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = client.Train(context.Background(), tt.training)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Train() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type fakeTransport struct {
+	requests int32
+	err      error
+}
+
+func (transport *fakeTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	atomic.AddInt32(&transport.requests, 1)
+	return nil, transport.err
+}
+
+func TestClient_TrainRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statusCode   int
+		transportErr error
+		wantRequests int32
+	}{
+		{
+			name:         "DoesNotRetryServerErrors",
+			statusCode:   http.StatusInternalServerError,
+			wantRequests: 1,
+		},
+		{
+			name:         "RetriesFailedConnections",
+			transportErr: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+			wantRequests: 3,
+		},
+		{
+			name:         "DoesNotRetryFailedReads",
+			transportErr: &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")},
+			wantRequests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			server := fakeDialogServer(
+				t,
+				func(w http.ResponseWriter, r *http.Request) {
+					atomic.AddInt32(&requests, 1)
+					w.WriteHeader(tt.statusCode)
+				},
+			)
+			var httpClient *http.Client
+			transport := &fakeTransport{err: tt.transportErr}
+			if tt.transportErr != nil {
+				httpClient = &http.Client{Transport: transport}
+			}
+
+			client, err := NewClient(
+				&ClientParameters{
+					Logger:     fakeZapLogger(),
+					BaseUrl:    server.URL,
+					MaxRetries: 2,
+					RetryDelay: time.Millisecond,
+					HttpClient: httpClient,
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = client.Train(
+				context.Background(),
+				&Training{
+					Action:    TrainingTeach,
+					Statement: "Who is a good dog?",
+					Response:  "Me!",
+				},
+			)
+			if err == nil {
+				t.Errorf("Train() error = %v, wantErr %v", err, true)
+			}
+			got := atomic.LoadInt32(&requests) + atomic.LoadInt32(&transport.requests)
+			if got != tt.wantRequests {
+				t.Errorf("Train() requests = %v, want %v", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestClient_ConverseWrapsDecodeErrors(t *testing.T) {
+	server := fakeDialogServer(
+		t,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte("{"))
+		},
+	)
+	client, err := NewClient(
+		&ClientParameters{
+			Logger:  fakeZapLogger(),
+			BaseUrl: server.URL,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Converse(context.Background(), &Conversation{Message: "hello"})
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Converse() error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestClient_Ping(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		wantErr    bool
+	}{
+		{
+			name:       "Healthy",
+			statusCode: http.StatusOK,
+			wantErr:    false,
+		},
+		{
+			name:       "Unhealthy",
+			statusCode: http.StatusServiceUnavailable,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			server := fakeDialogServer(
+				t,
+				func(w http.ResponseWriter, r *http.Request) {
+					atomic.AddInt32(&requests, 1)
+					if r.Method != "GET" || r.URL.Path != "/healthz" {
+						t.Errorf("Ping() request = %v %v, want %v %v", r.Method, r.URL.Path, "GET", "/healthz")
+					}
+					w.WriteHeader(tt.statusCode)
+				},
+			)
+
+			client, err := NewClient(
+				&ClientParameters{
+					Logger:     fakeZapLogger(),
+					BaseUrl:    server.URL,
+					MaxRetries: 2,
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = client.Ping(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Ping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if atomic.LoadInt32(&requests) != 1 {
+				t.Errorf("Ping() requests = %v, want %v", requests, 1)
+			}
+		})
+	}
+}
+
+func TestClient_ConversePropagatesTraceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(
+		context.Background(),
+		"event",
+	)
+	defer span.End()
+
+	server := fakeDialogServer(
+		t,
+		func(w http.ResponseWriter, r *http.Request) {
+			traceparent := r.Header.Get("traceparent")
+			if !strings.Contains(traceparent, span.SpanContext().TraceID().String()) {
+				t.Errorf("Converse() traceparent = %v, want %v", traceparent, span.SpanContext().TraceID())
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"reply": "Woof!"})
+		},
+	)
+
+	client, err := NewClient(
+		&ClientParameters{
+			Logger:  fakeZapLogger(),
+			BaseUrl: server.URL,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Converse(ctx, &Conversation{Message: "Who is a good dog?"})
+	if err != nil {
+		t.Errorf("Converse() error = %v, wantErr %v", err, false)
+	}
+}
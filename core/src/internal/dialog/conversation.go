@@ -0,0 +1,111 @@
+package dialog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// ProtocolVersion is the version of the
+// conversation protocol spoken by Client.
+const ProtocolVersion = 2
+
+// A dialog.Conversation describes a message
+// sent to the dialog service along with the
+// context it was sent in.
+type Conversation struct {
+	Version   int        `json:"version"`
+	SessionId string     `json:"session_id,omitempty"`
+	Message   string     `json:"message"`
+	User      *User      `json:"user,omitempty"`
+	Channel   *Channel   `json:"channel,omitempty"`
+	History   []*Message `json:"history,omitempty"`
+	Locale    string     `json:"locale,omitempty"`
+}
+
+// A dialog.User describes the sender of
+// a message.
+type User struct {
+	Id   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// A dialog.Channel describes where a message
+// was sent. Type is one of channel,
+// private_channel, mpim, or im.
+type Channel struct {
+	Id   string `json:"id"`
+	Type string `json:"type,omitempty"`
+}
+
+// A dialog.Message is a previous message in
+// the same conversation.
+type Message struct {
+	UserId  string `json:"user_id,omitempty"`
+	Text    string `json:"text"`
+	Ts      string `json:"ts,omitempty"`
+	FromBot bool   `json:"from_bot,omitempty"`
+}
+
+// A dialog.Reply is the response of the
+// dialog service to a message. Services that
+// only reply with text are fully confident in
+// their replies. Suggestions are messages the
+// sender may have meant instead.
+type Reply struct {
+	Text        string
+	Confidence  float64
+	Reactions   []string
+	Blocks      []json.RawMessage
+	Suggestions []string
+}
+
+// A replyBody is the JSON form of a Reply.
+// Only the reply field is required.
+type replyBody struct {
+	Reply       *string           `json:"reply"`
+	Confidence  *float64          `json:"confidence"`
+	Reactions   []string          `json:"reactions"`
+	Blocks      []json.RawMessage `json:"blocks"`
+	Suggestions []string          `json:"suggestions"`
+}
+
+// A dialog.Feedback labels a reply of the
+// dialog service as positive or negative
+// according to how it was received.
+type Feedback struct {
+	Version   int    `json:"version"`
+	SessionId string `json:"session_id,omitempty"`
+	Message   string `json:"message"`
+	Reply     string `json:"reply"`
+	Label     string `json:"label"`
+	Reaction  string `json:"reaction,omitempty"`
+	UserId    string `json:"user_id,omitempty"`
+}
+
+// Training actions understood by the
+// dialog service.
+const (
+	TrainingTeach  = "teach"
+	TrainingForget = "forget"
+)
+
+// A dialog.Training asks the dialog service to
+// learn Response as an answer to Statement, or
+// to forget what it learned about Statement.
+type Training struct {
+	Version   int    `json:"version"`
+	Action    string `json:"action"`
+	Statement string `json:"statement"`
+	Response  string `json:"response,omitempty"`
+	UserId    string `json:"user_id,omitempty"`
+}
+
+// SessionId returns an opaque identifier for
+// the conversation taking place in the given
+// channel and thread. Messages outside of a
+// thread share a session with their channel.
+func SessionId(channelId string, threadTs string) string {
+	sum := sha256.Sum256([]byte(channelId + "/" + threadTs))
+	return hex.EncodeToString(sum[:16])
+}
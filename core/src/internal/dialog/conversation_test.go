@@ -0,0 +1,43 @@
+package dialog
+
+import "testing"
+
+func TestSessionId(t *testing.T) {
+	tests := []struct {
+		name      string
+		channelId string
+		threadTs  string
+		other     [2]string
+		wantSame  bool
+	}{
+		{
+			name:      "SameThread",
+			channelId: "C0123",
+			threadTs:  "1612345678.000100",
+			other:     [2]string{"C0123", "1612345678.000100"},
+			wantSame:  true,
+		},
+		{
+			name:      "DifferentThread",
+			channelId: "C0123",
+			threadTs:  "1612345678.000100",
+			other:     [2]string{"C0123", "1612345678.000200"},
+			wantSame:  false,
+		},
+		{
+			name:      "DifferentChannel",
+			channelId: "C0123",
+			other:     [2]string{"C0456", ""},
+			wantSame:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SessionId(tt.channelId, tt.threadTs)
+			other := SessionId(tt.other[0], tt.other[1])
+			if (got == other) != tt.wantSame {
+				t.Errorf("SessionId() = %v, other %v, wantSame %v", got, other, tt.wantSame)
+			}
+		})
+	}
+}
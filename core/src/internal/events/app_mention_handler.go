@@ -1,13 +1,14 @@
 package events
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
+	"github.com/drewnorman/jt-slackbot/core/internal/metrics"
+	"github.com/drewnorman/jt-slackbot/core/internal/responders"
 	"github.com/drewnorman/jt-slackbot/core/internal/slack"
+	"github.com/drewnorman/jt-slackbot/core/internal/slack/mrkdwn"
 	"go.uber.org/zap"
-	"net/http"
 	"strings"
 )
 
@@ -16,13 +17,36 @@ import (
 type AppMentionHandler struct {
 	logger          *zap.Logger
 	slackHttpClient *slack.HttpClient
+	responder       responders.Responder
+	replies         *replyTracker
+	rateLimiter     *rateLimiter
+	conversation    *conversationContext
+	confidenceGate  *confidenceGate
+	trainer         Trainer
+	trainers        map[string]bool
+	responseCache   ResponseCache
+	recalledReplies RecalledReplies
+	admins          map[string]bool
+	metrics         *metrics.Metrics
 }
 
 // AppMentionHandlerParameters describe
 // how to create a new AppMentionHandler.
 type AppMentionHandlerParameters struct {
-	Logger          *zap.Logger
-	SlackHttpClient *slack.HttpClient
+	Logger           *zap.Logger
+	SlackHttpClient  *slack.HttpClient
+	Responder        responders.Responder
+	HistoryLength    int
+	ConfidencePolicy ConfidencePolicy
+	Trainer          Trainer
+	TrainerUserIds   []string
+	ResponseCache    ResponseCache
+	RecalledReplies  RecalledReplies
+	AdminUserIds     []string
+	UserRateLimit    RateLimit
+	ChannelRateLimit RateLimit
+	GlobalRateLimit  RateLimit
+	Metrics          *metrics.Metrics
 }
 
 // An appMentionEvent defines the
@@ -32,8 +56,15 @@ type appMentionEvent struct {
 	channelId    string
 	senderUserId string
 	text         string
+	ts           string
+	threadTs     string
 }
 
+// throttledNotice is sent to a user the first
+// time they are rate limited.
+const throttledNotice = "I'm getting a lot of questions right now. " +
+	"Give me a moment to catch my breath and try again shortly!"
+
 // NewAppMentionHandler returns a new
 // instance of AppMentionHandler
 // according to the given parameters.
@@ -46,15 +77,59 @@ func NewAppMentionHandler(
 	if params.SlackHttpClient == nil {
 		return nil, errors.New("missing slack http client")
 	}
+	if params.Responder == nil {
+		return nil, errors.New("missing responder")
+	}
+	confidenceGate, err := newConfidenceGate(
+		params.ConfidencePolicy.Threshold,
+		params.ConfidencePolicy.ThresholdByChannel,
+		params.ConfidencePolicy.Action,
+		params.ConfidencePolicy.Clarification,
+	)
+	if err != nil {
+		return nil, err
+	}
+	trainers := make(map[string]bool)
+	for _, userId := range params.TrainerUserIds {
+		trainers[userId] = true
+	}
+	admins := make(map[string]bool)
+	for _, userId := range params.AdminUserIds {
+		admins[userId] = true
+	}
 	return &AppMentionHandler{
 		logger:          params.Logger,
 		slackHttpClient: params.SlackHttpClient,
+		responder:       params.Responder,
+		replies:         newReplyTracker(replyTrackerMaxLength),
+		rateLimiter: newRateLimiter(
+			params.UserRateLimit,
+			params.ChannelRateLimit,
+			params.GlobalRateLimit,
+		),
+		conversation: newConversationContext(
+			params.Logger,
+			params.SlackHttpClient,
+			params.HistoryLength,
+		),
+		confidenceGate:  confidenceGate,
+		trainer:         params.Trainer,
+		trainers:        trainers,
+		responseCache:   params.ResponseCache,
+		recalledReplies: params.RecalledReplies,
+		admins:          admins,
+		metrics:         params.Metrics,
 	}, nil
 }
 
 // Process processes the given event data
-// and tries to respond appropriately.
+// and tries to respond appropriately. Errors
+// after the reply was sent are final, and
+// mentions that were already replied to are
+// skipped, so that retries never send the
+// reply twice.
 func (handler *AppMentionHandler) Process(
+	ctx context.Context,
 	eventData map[string]interface{},
 ) error {
 	event, err := eventFromData(eventData)
@@ -62,46 +137,387 @@ func (handler *AppMentionHandler) Process(
 		return err
 	}
 
-	jsonData, err := json.Marshal(
-		map[string]string{
-			"message": event.text,
+	if _, ok := handler.replies.findBySource(event.channelId, event.ts); ok && event.ts != "" {
+		handler.logger.Debug(
+			"already replied to app mention",
+			zap.String("channelId", event.channelId),
+			zap.String("ts", event.ts),
+		)
+		return nil
+	}
+
+	if !retried(ctx) && !handler.allow(event.senderUserId, event.channelId) {
+		if handler.rateLimiter.shouldNotify(event.senderUserId) {
+			err = handler.slackHttpClient.SendEphemeralMessage(
+				ctx,
+				throttledNotice,
+				event.channelId,
+				event.senderUserId,
+			)
+			if err != nil {
+				handler.logger.Warn(
+					"failed to send throttled notice",
+					zap.String("err", err.Error()),
+					zap.String("userId", event.senderUserId),
+				)
+			}
+		}
+		return nil
+	}
+
+	event.text = handler.conversation.plainText(ctx, event.text)
+
+	if handler.responseCache != nil {
+		action, ok := parseCacheCommand(event.text)
+		if ok {
+			return handler.runCacheCommand(ctx, event, action)
+		}
+	}
+
+	if handler.trainer != nil {
+		command, ok := parseCommand(event.text)
+		if ok {
+			return handler.runCommand(ctx, event, command)
+		}
+	}
+
+	response, err := handler.respond(
+		ctx,
+		&responders.Request{
+			Text:      event.text,
+			UserId:    event.senderUserId,
+			ChannelId: event.channelId,
+			Ts:        event.ts,
+			ThreadTs:  event.threadTs,
 		},
 	)
+	if errors.Is(err, responders.ErrNoResponse) {
+		handler.logger.Debug(
+			"no response to app mention",
+			zap.String("channelId", event.channelId),
+			zap.String("ts", event.ts),
+		)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	resp, err := http.Post(
-		"http://localhost:5000/converse",
-		"application/json",
-		bytes.NewBuffer(jsonData),
+	reply := response.Text
+	blocks := response.Blocks
+	decision := handler.confidenceGate.decide(event.channelId, true, response)
+	switch decision {
+	case decisionSilent:
+		handler.logger.Debug(
+			"dropping low confidence response",
+			zap.String("channelId", event.channelId),
+			zap.Float64("confidence", response.Confidence),
+		)
+		return nil
+	case decisionClarify:
+		reply = handler.confidenceGate.clarification
+		blocks = nil
+	case decisionSuggest:
+		reply = suggestionPrompt
+		blocks, err = suggestionBlocks(
+			event.senderUserId,
+			event.threadTs,
+			response.Suggestions,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	var replyTs string
+	if len(blocks) > 0 {
+		replyTs, err = handler.slackHttpClient.SendBlocksToChannel(
+			ctx,
+			formatReply(event.senderUserId, reply),
+			blocks,
+			event.channelId,
+		)
+	} else {
+		replyTs, err = handler.slackHttpClient.SendMessageToChannel(
+			ctx,
+			formatReply(event.senderUserId, reply),
+			event.channelId,
+		)
+	}
+	if err != nil {
+		return final(err)
+	}
+
+	if event.ts != "" && replyTs != "" {
+		tracked := trackedReply{
+			channelId:    event.channelId,
+			sourceTs:     event.ts,
+			threadTs:     event.threadTs,
+			replyTs:      replyTs,
+			senderUserId: event.senderUserId,
+			input:        event.text,
+			reply:        reply,
+		}
+		if decision == decisionSuggest {
+			tracked.suggestions = offeredSuggestions(response.Suggestions)
+		}
+		handler.replies.track(tracked)
+	}
+
+	if decision == decisionReply {
+		handler.react(ctx, event.channelId, event.ts, response.Reactions)
+	}
+	return nil
+}
+
+// Reanswer replaces the reply to the message
+// matching the given channelId and timestamp
+// with a response to the given text. Messages
+// the app did not reply to are ignored, and
+// the reply is left alone if the response
+// falls below the confidence threshold.
+func (handler *AppMentionHandler) Reanswer(
+	ctx context.Context,
+	channelId string,
+	sourceTs string,
+	text string,
+) error {
+	tracked, ok := handler.replies.findBySource(channelId, sourceTs)
+	if !ok {
+		handler.logger.Debug(
+			"no tracked reply for edited message",
+			zap.String("channelId", channelId),
+			zap.String("ts", sourceTs),
+		)
+		return nil
+	}
+	if !retried(ctx) && !handler.allow(tracked.senderUserId, channelId) {
+		return nil
+	}
+	text = handler.conversation.plainText(ctx, text)
+
+	response, err := handler.respond(
+		ctx,
+		&responders.Request{
+			Text:      text,
+			UserId:    tracked.senderUserId,
+			ChannelId: channelId,
+			Ts:        sourceTs,
+			ThreadTs:  tracked.threadTs,
+		},
 	)
+	if errors.Is(err, responders.ErrNoResponse) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
+	if handler.confidenceGate.decide(channelId, false, response) != decisionReply {
+		handler.logger.Debug(
+			"keeping reply to edited message after low confidence response",
+			zap.String("channelId", channelId),
+			zap.Float64("confidence", response.Confidence),
+		)
+		return nil
+	}
+	reply := response.Text
+
+	err = handler.slackHttpClient.UpdateMessage(
+		ctx,
+		formatReply(tracked.senderUserId, reply),
+		tracked.channelId,
+		tracked.replyTs,
+	)
+	if err != nil {
+		return final(err)
+	}
+
+	tracked.input = text
+	tracked.reply = reply
+	handler.replies.track(tracked)
+	return nil
+}
 
-	decoded := make(map[string]interface{})
-	err = json.NewDecoder(resp.Body).Decode(&decoded)
+// AnswerSuggestion replaces the message matching
+// the given channelId and timestamp, which offered
+// suggestions to the user matching the given
+// userId, with a response to the suggestion at
+// the given index, continuing the conversation
+// of the thread matching the given threadTs.
+// The label of the button is taken as the
+// suggestion if the message is no longer
+// tracked.
+func (handler *AppMentionHandler) AnswerSuggestion(
+	ctx context.Context,
+	channelId string,
+	messageTs string,
+	threadTs string,
+	userId string,
+	index int,
+	label string,
+) error {
+	if !retried(ctx) && !handler.allow(userId, channelId) {
+		return nil
+	}
+	suggestion := label
+	tracked, ok := handler.replies.findByReply(channelId, messageTs)
+	if ok && index >= 0 && index < len(tracked.suggestions) {
+		suggestion = tracked.suggestions[index]
+	}
+	if suggestion == "" {
+		return fmt.Errorf("failed to determine suggestion %d of message %s", index, messageTs)
+	}
+
+	response, err := handler.respond(
+		ctx,
+		&responders.Request{
+			Text:      suggestion,
+			UserId:    userId,
+			ChannelId: channelId,
+			Ts:        messageTs,
+			ThreadTs:  threadTs,
+		},
+	)
+	if errors.Is(err, responders.ErrNoResponse) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	reply, ok := decoded["reply"].(string)
+	return final(
+		handler.slackHttpClient.UpdateMessage(
+			ctx,
+			formatReply(userId, response.Text),
+			channelId,
+			messageTs,
+		),
+	)
+}
+
+// Retract deletes the reply to the message
+// matching the given channelId and timestamp.
+// Messages the app did not reply to are
+// ignored.
+func (handler *AppMentionHandler) Retract(
+	ctx context.Context,
+	channelId string,
+	sourceTs string,
+) error {
+	tracked, ok := handler.replies.findBySource(channelId, sourceTs)
 	if !ok {
-		return errors.New("failed to find reply in response")
+		handler.logger.Debug(
+			"no tracked reply for deleted message",
+			zap.String("channelId", channelId),
+			zap.String("ts", sourceTs),
+		)
+		return nil
 	}
 
-	err = handler.slackHttpClient.SendMessageToChannel(
-		"<@"+event.senderUserId+"> "+reply,
-		event.channelId,
+	err := handler.slackHttpClient.DeleteMessage(
+		ctx,
+		tracked.channelId,
+		tracked.replyTs,
 	)
 	if err != nil {
-		return err
+		return final(err)
 	}
 
+	handler.replies.forgetBySource(channelId, sourceTs)
 	return nil
 }
 
+// LookupReply returns the exchange in which the
+// app sent the reply matching the given channelId
+// and timestamp, if it is still tracked.
+func (handler *AppMentionHandler) LookupReply(
+	channelId string,
+	replyTs string,
+) (Exchange, bool) {
+	tracked, ok := handler.replies.findByReply(channelId, replyTs)
+	if !ok {
+		return Exchange{}, false
+	}
+	return Exchange{
+		ChannelId:    tracked.channelId,
+		SourceTs:     tracked.sourceTs,
+		ThreadTs:     tracked.threadTs,
+		ReplyTs:      tracked.replyTs,
+		SenderUserId: tracked.senderUserId,
+		Input:        tracked.input,
+		Reply:        tracked.reply,
+	}, true
+}
+
+// allow returns true if the rate limits permit
+// replying to the user matching the given userId
+// in the channel matching the given channelId.
+func (handler *AppMentionHandler) allow(
+	userId string,
+	channelId string,
+) bool {
+	allowed, scope := handler.rateLimiter.allow(userId, channelId)
+	if allowed {
+		return true
+	}
+	handler.metrics.RateLimited(scope)
+	stats := handler.rateLimiter.Stats()
+	handler.logger.Info(
+		"rate limited app mention",
+		zap.String("scope", scope),
+		zap.String("userId", userId),
+		zap.String("channelId", channelId),
+		zap.Int("userLimitTriggers", stats.User),
+		zap.Int("channelLimitTriggers", stats.Channel),
+		zap.Int("globalLimitTriggers", stats.Global),
+	)
+	return false
+}
+
+// respond adds the conversation context to the
+// given request and requests a response to it
+// from the responder.
+func (handler *AppMentionHandler) respond(
+	ctx context.Context,
+	request *responders.Request,
+) (*responders.Response, error) {
+	handler.conversation.enrich(ctx, request)
+	return handler.responder.Respond(ctx, request)
+}
+
+// react adds the given reactions to the message
+// matching the given channelId and timestamp.
+// Reactions are a nicety, so failures are only
+// logged.
+func (handler *AppMentionHandler) react(
+	ctx context.Context,
+	channelId string,
+	ts string,
+	reactions []string,
+) {
+	if ts == "" {
+		return
+	}
+	for _, reaction := range reactions {
+		err := handler.slackHttpClient.AddReaction(ctx, reaction, channelId, ts)
+		if err != nil {
+			handler.logger.Warn(
+				"failed to add reaction",
+				zap.String("err", err.Error()),
+				zap.String("reaction", reaction),
+				zap.String("channelId", channelId),
+			)
+		}
+	}
+}
+
+// formatReply addresses the given reply to
+// the user matching the given userId, escaping
+// it for Slack.
+func formatReply(userId string, reply string) string {
+	return "<@" + userId + "> " + mrkdwn.Escape(reply)
+}
+
 // eventFromData returns a new appMentionEvent
 // from the given event data or an error if
 // any of the necessary data is missing.
@@ -109,7 +525,7 @@ func eventFromData(
 	data map[string]interface{},
 ) (*appMentionEvent, error) {
 	authorizations, ok := data["authorizations"].([]interface{})
-	if !ok {
+	if !ok || len(authorizations) == 0 {
 		return nil, fmt.Errorf("failed to determine authorizations from data %v", data)
 	}
 	authorization, ok := authorizations[0].(map[string]interface{})
@@ -136,11 +552,21 @@ func eventFromData(
 	if !ok {
 		return nil, fmt.Errorf("failed to determine text from event data %v", eventData)
 	}
-	text = strings.ReplaceAll(text, "<@"+appUserId+">", "")
+	ts, _ := eventData["ts"].(string)
+	threadTs, _ := eventData["thread_ts"].(string)
 	return &appMentionEvent{
 		appUserId,
 		channelId,
 		senderUserId,
-		text,
+		stripAppMention(text, appUserId),
+		ts,
+		threadTs,
 	}, nil
 }
+
+// stripAppMention removes mentions of the app
+// user matching the given appUserId from the
+// given text.
+func stripAppMention(text string, appUserId string) string {
+	return strings.ReplaceAll(text, "<@"+appUserId+">", "")
+}
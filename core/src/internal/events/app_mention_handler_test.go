@@ -1,7 +1,17 @@
 package events
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/drewnorman/jt-slackbot/core/internal/metrics"
+	"github.com/drewnorman/jt-slackbot/core/internal/responders"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewAppMentionHandler(t *testing.T) {
@@ -22,6 +32,7 @@ func TestNewAppMentionHandler(t *testing.T) {
 						t,
 						map[string]interface{}{},
 					),
+					Responder: fakeStaticResponder(gofakeit.LoremIpsumSentence(5)),
 				},
 			},
 			wantErr: false,
@@ -35,6 +46,7 @@ func TestNewAppMentionHandler(t *testing.T) {
 						t,
 						map[string]interface{}{},
 					),
+					Responder: fakeStaticResponder(gofakeit.LoremIpsumSentence(5)),
 				},
 			},
 			wantErr: true,
@@ -45,6 +57,20 @@ func TestNewAppMentionHandler(t *testing.T) {
 				params: &AppMentionHandlerParameters{
 					Logger:          fakeZapLogger(),
 					SlackHttpClient: nil,
+					Responder:       fakeStaticResponder(gofakeit.LoremIpsumSentence(5)),
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingResponder",
+			args: args{
+				params: &AppMentionHandlerParameters{
+					Logger: fakeZapLogger(),
+					SlackHttpClient: fakeSlackHttpClient(
+						t,
+						map[string]interface{}{},
+					),
 				},
 			},
 			wantErr: true,
@@ -63,3 +89,392 @@ func TestNewAppMentionHandler(t *testing.T) {
 		})
 	}
 }
+
+func fakeAppMentionEvent(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"event_id": gofakeit.UUID(),
+		"authorizations": []interface{}{
+			map[string]interface{}{
+				"user_id": "UAPP",
+			},
+		},
+		"event": map[string]interface{}{
+			"type":    "app_mention",
+			"channel": "C1",
+			"user":    "U1",
+			"text":    "<@UAPP> " + text,
+			"ts":      "1610000000.000100",
+		},
+	}
+}
+
+func TestAppMentionHandler_Process(t *testing.T) {
+	tests := []struct {
+		name      string
+		responder *fakeResponder
+		eventData map[string]interface{}
+		wantErr   bool
+	}{
+		{
+			name:      "Replies",
+			responder: fakeStaticResponder("Woof!"),
+			eventData: fakeAppMentionEvent("hello"),
+			wantErr:   false,
+		},
+		{
+			name: "StaysQuietWithoutResponse",
+			responder: &fakeResponder{
+				respond: func(request *responders.Request) (*responders.Response, error) {
+					return nil, responders.ErrNoResponse
+				},
+			},
+			eventData: fakeAppMentionEvent("hello"),
+			wantErr:   false,
+		},
+		{
+			name: "ReturnsResponderError",
+			responder: &fakeResponder{
+				respond: func(request *responders.Request) (*responders.Response, error) {
+					return nil, errors.New("fake responder error")
+				},
+			},
+			eventData: fakeAppMentionEvent("hello"),
+			wantErr:   true,
+		},
+		{
+			name:      "MissingAuthorizations",
+			responder: fakeStaticResponder("Woof!"),
+			eventData: map[string]interface{}{
+				"authorizations": []interface{}{},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := NewAppMentionHandler(
+				&AppMentionHandlerParameters{
+					Logger: fakeZapLogger(),
+					SlackHttpClient: fakeSlackHttpClient(
+						t,
+						map[string]interface{}{
+							"ok": true,
+							"ts": "1610000000.000200",
+						},
+					),
+					Responder: tt.responder,
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = handler.Process(context.Background(), tt.eventData)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAppMentionHandler_ProcessRecordsRateLimits(t *testing.T) {
+	recorder := metrics.New()
+	handler, err := NewAppMentionHandler(
+		&AppMentionHandlerParameters{
+			Logger: fakeZapLogger(),
+			SlackHttpClient: fakeSlackHttpClient(
+				t,
+				map[string]interface{}{
+					"ok": true,
+					"ts": "1610000000.000200",
+				},
+			),
+			Responder:     fakeStaticResponder("Woof!"),
+			UserRateLimit: RateLimit{Count: 1, Period: time.Minute},
+			Metrics:       recorder,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		eventData := fakeAppMentionEvent("hello")
+		eventData["event"].(map[string]interface{})["ts"] = fmt.Sprintf("1610000000.00010%d", i)
+		err = handler.Process(context.Background(), eventData)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	response := httptest.NewRecorder()
+	recorder.Handler().ServeHTTP(response, httptest.NewRequest("GET", "/metrics", nil))
+	want := `jt_slackbot_rate_limited_total{scope="user"} 2`
+	if !strings.Contains(response.Body.String(), want) {
+		t.Errorf("Process() metrics = %v, want %v", response.Body.String(), want)
+	}
+}
+
+func TestAppMentionHandler_ProcessRichResponse(t *testing.T) {
+	calls := make(map[string]int)
+	handler, err := NewAppMentionHandler(
+		&AppMentionHandlerParameters{
+			Logger: fakeZapLogger(),
+			SlackHttpClient: fakeRoutedSlackHttpClient(
+				t,
+				map[string]map[string]interface{}{
+					"chat.postMessage": {"ok": true, "ts": "1610000000.000200"},
+					"reactions.add":    {"ok": true},
+				},
+				calls,
+			),
+			Responder: &fakeResponder{
+				respond: func(request *responders.Request) (*responders.Response, error) {
+					if request.Ts != "1610000000.000100" {
+						t.Errorf("Respond() ts = %v, want %v", request.Ts, "1610000000.000100")
+					}
+					return &responders.Response{
+						Text:       "Woof!",
+						Confidence: 1,
+						Reactions:  []string{"dog", "wave"},
+						Blocks:     []json.RawMessage{json.RawMessage(`{"type":"divider"}`)},
+					}, nil
+				},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = handler.Process(context.Background(), fakeAppMentionEvent("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls["chat.postMessage"] != 1 {
+		t.Errorf("Process() messages sent = %v, want %v", calls["chat.postMessage"], 1)
+	}
+	if calls["reactions.add"] != 2 {
+		t.Errorf("Process() reactions added = %v, want %v", calls["reactions.add"], 2)
+	}
+}
+
+func TestAppMentionHandler_ProcessLowConfidence(t *testing.T) {
+	tests := []struct {
+		name         string
+		action       string
+		suggestions  []string
+		wantMessages int
+		wantText     string
+	}{
+		{
+			name:         "Clarifies",
+			action:       LowConfidenceClarify,
+			wantMessages: 1,
+			wantText:     "<@U1> Say again?",
+		},
+		{
+			name:         "Suggests",
+			action:       LowConfidenceSuggest,
+			suggestions:  []string{"Who is a good dog?"},
+			wantMessages: 1,
+			wantText:     "<@U1> " + suggestionPrompt,
+		},
+		{
+			name:         "StaysSilent",
+			action:       LowConfidenceSilent,
+			wantMessages: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := make(map[string]int)
+			slackHttpClient := fakeRoutedSlackHttpClient(
+				t,
+				map[string]map[string]interface{}{
+					"chat.postMessage": {"ok": true, "ts": "1610000000.000200"},
+				},
+				calls,
+			)
+			handler, err := NewAppMentionHandler(
+				&AppMentionHandlerParameters{
+					Logger:          fakeZapLogger(),
+					SlackHttpClient: slackHttpClient,
+					Responder: &fakeResponder{
+						respond: func(request *responders.Request) (*responders.Response, error) {
+							return &responders.Response{
+								Text:        "Bark?",
+								Confidence:  0.1,
+								Suggestions: tt.suggestions,
+							}, nil
+						},
+					},
+					ConfidencePolicy: ConfidencePolicy{
+						Threshold:     0.5,
+						Action:        tt.action,
+						Clarification: "Say again?",
+					},
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = handler.Process(context.Background(), fakeAppMentionEvent("hello"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if calls["chat.postMessage"] != tt.wantMessages {
+				t.Errorf("Process() messages sent = %v, want %v", calls["chat.postMessage"], tt.wantMessages)
+			}
+			if tt.wantMessages > 0 {
+				tracked, _ := handler.replies.findBySource("C1", "1610000000.000100")
+				if formatReply("U1", tracked.reply) != tt.wantText {
+					t.Errorf("Process() reply = %v, want %v", tracked.reply, tt.wantText)
+				}
+			}
+		})
+	}
+}
+
+func TestAppMentionHandler_AnswerSuggestion(t *testing.T) {
+	suggestion := strings.Repeat("Who is a good dog? ", 10)
+	tests := []struct {
+		name      string
+		messageTs string
+		index     int
+		label     string
+		wantText  string
+		wantErr   bool
+	}{
+		{
+			name:      "AnswersTrackedSuggestion",
+			messageTs: "1610000000.000200",
+			index:     0,
+			label:     suggestionLabel(suggestion),
+			wantText:  suggestion,
+			wantErr:   false,
+		},
+		{
+			name:      "AnswersLabelOfUntrackedMessage",
+			messageTs: "1610000000.000300",
+			index:     0,
+			label:     "Who is a good dog?",
+			wantText:  "Who is a good dog?",
+			wantErr:   false,
+		},
+		{
+			name:      "MissingSuggestion",
+			messageTs: "1610000000.000300",
+			index:     0,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := make(map[string]int)
+			slackHttpClient := fakeRoutedSlackHttpClient(
+				t,
+				map[string]map[string]interface{}{
+					"chat.postMessage": {"ok": true, "ts": "1610000000.000200"},
+					"chat.update":      {"ok": true},
+				},
+				calls,
+			)
+			var requested, requestedThreadTs string
+			handler, err := NewAppMentionHandler(
+				&AppMentionHandlerParameters{
+					Logger:          fakeZapLogger(),
+					SlackHttpClient: slackHttpClient,
+					Responder: &fakeResponder{
+						respond: func(request *responders.Request) (*responders.Response, error) {
+							requested = request.Text
+							requestedThreadTs = request.ThreadTs
+							return &responders.Response{
+								Text:        "Bark?",
+								Confidence:  0.1,
+								Suggestions: []string{suggestion},
+							}, nil
+						},
+					},
+					ConfidencePolicy: ConfidencePolicy{
+						Threshold: 0.5,
+						Action:    LowConfidenceSuggest,
+					},
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = handler.Process(context.Background(), fakeAppMentionEvent("hello"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = handler.AnswerSuggestion(
+				context.Background(),
+				"C1",
+				tt.messageTs,
+				"1610000000.000050",
+				"U1",
+				tt.index,
+				tt.label,
+			)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AnswerSuggestion() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && requested != tt.wantText {
+				t.Errorf("AnswerSuggestion() requested = %v, want %v", requested, tt.wantText)
+			}
+			if err == nil && requestedThreadTs != "1610000000.000050" {
+				t.Errorf("AnswerSuggestion() threadTs = %v, want %v", requestedThreadTs, "1610000000.000050")
+			}
+		})
+	}
+}
+
+func TestAppMentionHandler_ReanswerInThread(t *testing.T) {
+	calls := make(map[string]int)
+	slackHttpClient := fakeRoutedSlackHttpClient(
+		t,
+		map[string]map[string]interface{}{
+			"chat.postMessage": {"ok": true, "ts": "1610000000.000200"},
+			"chat.update":      {"ok": true},
+		},
+		calls,
+	)
+	var requests []responders.Request
+	handler, err := NewAppMentionHandler(
+		&AppMentionHandlerParameters{
+			Logger:          fakeZapLogger(),
+			SlackHttpClient: slackHttpClient,
+			Responder: &fakeResponder{
+				respond: func(request *responders.Request) (*responders.Response, error) {
+					requests = append(requests, *request)
+					return &responders.Response{Text: "Woof!", Confidence: 1}, nil
+				},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := fakeAppMentionEvent("hello")
+	event["event"].(map[string]interface{})["thread_ts"] = "1610000000.000050"
+	err = handler.Process(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = handler.Reanswer(context.Background(), "C1", "1610000000.000100", "hello again")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || requests[1].ThreadTs != "1610000000.000050" {
+		t.Errorf("Reanswer() requests = %v, want thread ts %v", requests, "1610000000.000050")
+	}
+	if calls["chat.update"] != 1 {
+		t.Errorf("Reanswer() updates = %v, want %v", calls["chat.update"], 1)
+	}
+}
@@ -0,0 +1,170 @@
+package events
+
+import (
+	"time"
+)
+
+// A botFilter decides whether an event was
+// sent by the app itself or by another bot
+// and should therefore be dropped to avoid
+// endless reply loops.
+type botFilter struct {
+	botUserId     string
+	botId         string
+	ignoreBots    bool
+	allowedBotIds map[string]bool
+	loopThreshold int
+	loopWindow    time.Duration
+	threads       map[string][]time.Time
+	now           func() time.Time
+}
+
+// botFilterParameters describe how to
+// create a new botFilter.
+type botFilterParameters struct {
+	botUserId     string
+	botId         string
+	ignoreBots    bool
+	allowedBotIds []string
+	loopThreshold int
+	loopWindow    time.Duration
+}
+
+// defaultBotLoopThreshold and defaultBotLoopWindow
+// determine how many bot messages a single thread
+// may receive in a window of time before the
+// exchange is considered a loop.
+const (
+	defaultBotLoopThreshold = 5
+	defaultBotLoopWindow    = time.Minute
+)
+
+// Reasons reported by botFilter.shouldIgnore.
+const (
+	ignoreReasonSelf       = "self"
+	ignoreReasonBot        = "bot"
+	ignoreReasonBotLoop    = "bot loop"
+	ignoreReasonNotIgnored = ""
+)
+
+// newBotFilter returns a new botFilter
+// according to the given parameters.
+func newBotFilter(params *botFilterParameters) *botFilter {
+	allowedBotIds := make(map[string]bool)
+	for _, botId := range params.allowedBotIds {
+		allowedBotIds[botId] = true
+	}
+	loopThreshold := defaultBotLoopThreshold
+	if params.loopThreshold > 0 {
+		loopThreshold = params.loopThreshold
+	}
+	loopWindow := defaultBotLoopWindow
+	if params.loopWindow > 0 {
+		loopWindow = params.loopWindow
+	}
+	return &botFilter{
+		botUserId:     params.botUserId,
+		botId:         params.botId,
+		ignoreBots:    params.ignoreBots,
+		allowedBotIds: allowedBotIds,
+		loopThreshold: loopThreshold,
+		loopWindow:    loopWindow,
+		threads:       make(map[string][]time.Time),
+		now:           time.Now,
+	}
+}
+
+// shouldIgnore returns true and the reason if
+// the given event was sent by the app itself,
+// by a bot that is not allowed, or by a bot
+// that has sent too many messages to the same
+// thread in a short period of time.
+func (filter *botFilter) shouldIgnore(
+	event map[string]interface{},
+) (bool, string) {
+	eventData, ok := event["event"].(map[string]interface{})
+	if !ok {
+		return false, ignoreReasonNotIgnored
+	}
+
+	senderUserId, _ := eventData["user"].(string)
+	senderBotId, _ := eventData["bot_id"].(string)
+	subtype, _ := eventData["subtype"].(string)
+
+	botUserId := filter.botUserId
+	if botUserId == "" {
+		botUserId = appUserIdFromData(event)
+	}
+	if senderUserId != "" && senderUserId == botUserId {
+		return true, ignoreReasonSelf
+	}
+	if senderBotId != "" && senderBotId == filter.botId {
+		return true, ignoreReasonSelf
+	}
+
+	if senderBotId == "" && subtype != "bot_message" {
+		return false, ignoreReasonNotIgnored
+	}
+
+	if filter.ignoreBots && !filter.allowedBotIds[senderBotId] {
+		return true, ignoreReasonBot
+	}
+
+	if filter.isLooping(threadKey(eventData)) {
+		return true, ignoreReasonBotLoop
+	}
+
+	return false, ignoreReasonNotIgnored
+}
+
+// isLooping records a bot message for the thread
+// matching the given key and returns true if the
+// thread has received more bot messages than the
+// loop threshold within the loop window.
+func (filter *botFilter) isLooping(key string) bool {
+	now := filter.now()
+	cutoff := now.Add(-filter.loopWindow)
+
+	var recent []time.Time
+	for _, receivedAt := range filter.threads[key] {
+		if receivedAt.After(cutoff) {
+			recent = append(recent, receivedAt)
+		}
+	}
+	recent = append(recent, now)
+	filter.threads[key] = recent
+
+	for threadKey, timestamps := range filter.threads {
+		if len(timestamps) == 0 || !timestamps[len(timestamps)-1].After(cutoff) {
+			delete(filter.threads, threadKey)
+		}
+	}
+
+	return len(recent) > filter.loopThreshold
+}
+
+// threadKey returns a key identifying the
+// thread the given event data belongs to,
+// falling back to the channel itself for
+// messages outside of a thread.
+func threadKey(eventData map[string]interface{}) string {
+	channelId, _ := eventData["channel"].(string)
+	threadTs, _ := eventData["thread_ts"].(string)
+	return channelId + ":" + threadTs
+}
+
+// appUserIdFromData returns the user ID of
+// the app from the authorizations in the
+// given event data if it can be determined.
+func appUserIdFromData(data map[string]interface{}) string {
+	authorizations, ok := data["authorizations"].([]interface{})
+	if !ok || len(authorizations) == 0 {
+		return ""
+	}
+	authorization, ok := authorizations[0].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	appUserId, _ := authorization["user_id"].(string)
+	return appUserId
+}
@@ -0,0 +1,195 @@
+package events
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"testing"
+	"time"
+)
+
+func fakeBotEvent(
+	userId string,
+	botId string,
+	channelId string,
+) map[string]interface{} {
+	eventData := map[string]interface{}{
+		"type":    "app_mention",
+		"channel": channelId,
+	}
+	if userId != "" {
+		eventData["user"] = userId
+	}
+	if botId != "" {
+		eventData["bot_id"] = botId
+	}
+	return map[string]interface{}{
+		"event_id": gofakeit.UUID(),
+		"authorizations": []interface{}{
+			map[string]interface{}{
+				"user_id": "UAPP",
+			},
+		},
+		"event": eventData,
+	}
+}
+
+func TestBotFilter_ShouldIgnore(t *testing.T) {
+	type args struct {
+		params *botFilterParameters
+		events []map[string]interface{}
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantIgnore bool
+		wantReason string
+	}{
+		{
+			name: "AllowsUsers",
+			args: args{
+				params: &botFilterParameters{},
+				events: []map[string]interface{}{
+					fakeBotEvent("UHUMAN", "", "C1"),
+				},
+			},
+			wantIgnore: false,
+			wantReason: ignoreReasonNotIgnored,
+		},
+		{
+			name: "IgnoresSelfByAuthorization",
+			args: args{
+				params: &botFilterParameters{},
+				events: []map[string]interface{}{
+					fakeBotEvent("UAPP", "", "C1"),
+				},
+			},
+			wantIgnore: true,
+			wantReason: ignoreReasonSelf,
+		},
+		{
+			name: "IgnoresSelfByBotId",
+			args: args{
+				params: &botFilterParameters{
+					botUserId: "USELF",
+					botId:     "BSELF",
+				},
+				events: []map[string]interface{}{
+					fakeBotEvent("", "BSELF", "C1"),
+				},
+			},
+			wantIgnore: true,
+			wantReason: ignoreReasonSelf,
+		},
+		{
+			name: "AllowsBotsByDefault",
+			args: args{
+				params: &botFilterParameters{},
+				events: []map[string]interface{}{
+					fakeBotEvent("UOTHER", "BOTHER", "C1"),
+				},
+			},
+			wantIgnore: false,
+			wantReason: ignoreReasonNotIgnored,
+		},
+		{
+			name: "IgnoresBots",
+			args: args{
+				params: &botFilterParameters{
+					ignoreBots: true,
+				},
+				events: []map[string]interface{}{
+					fakeBotEvent("UOTHER", "BOTHER", "C1"),
+				},
+			},
+			wantIgnore: true,
+			wantReason: ignoreReasonBot,
+		},
+		{
+			name: "AllowsAllowedBots",
+			args: args{
+				params: &botFilterParameters{
+					ignoreBots:    true,
+					allowedBotIds: []string{"BOTHER"},
+				},
+				events: []map[string]interface{}{
+					fakeBotEvent("UOTHER", "BOTHER", "C1"),
+				},
+			},
+			wantIgnore: false,
+			wantReason: ignoreReasonNotIgnored,
+		},
+		{
+			name: "IgnoresBotLoops",
+			args: args{
+				params: &botFilterParameters{
+					loopThreshold: 2,
+				},
+				events: []map[string]interface{}{
+					fakeBotEvent("UOTHER", "BOTHER", "C1"),
+					fakeBotEvent("UOTHER", "BOTHER", "C1"),
+					fakeBotEvent("UOTHER", "BOTHER", "C1"),
+				},
+			},
+			wantIgnore: true,
+			wantReason: ignoreReasonBotLoop,
+		},
+		{
+			name: "TracksLoopsPerThread",
+			args: args{
+				params: &botFilterParameters{
+					loopThreshold: 2,
+				},
+				events: []map[string]interface{}{
+					fakeBotEvent("UOTHER", "BOTHER", "C1"),
+					fakeBotEvent("UOTHER", "BOTHER", "C1"),
+					fakeBotEvent("UOTHER", "BOTHER", "C2"),
+				},
+			},
+			wantIgnore: false,
+			wantReason: ignoreReasonNotIgnored,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := newBotFilter(tt.args.params)
+			var ignore bool
+			var reason string
+			for _, event := range tt.args.events {
+				ignore, reason = filter.shouldIgnore(event)
+			}
+			if ignore != tt.wantIgnore || reason != tt.wantReason {
+				t.Errorf(
+					"shouldIgnore() = %v, %q, want %v, %q",
+					ignore,
+					reason,
+					tt.wantIgnore,
+					tt.wantReason,
+				)
+			}
+		})
+	}
+}
+
+func TestBotFilter_LoopWindowExpires(t *testing.T) {
+	filter := newBotFilter(
+		&botFilterParameters{
+			loopThreshold: 1,
+			loopWindow:    time.Minute,
+		},
+	)
+	now := time.Now()
+	filter.now = func() time.Time {
+		return now
+	}
+
+	event := fakeBotEvent("UOTHER", "BOTHER", "C1")
+	ignore, _ := filter.shouldIgnore(event)
+	if ignore {
+		t.Errorf("shouldIgnore() = %v, want %v", ignore, false)
+	}
+
+	now = now.Add(2 * time.Minute)
+	ignore, _ = filter.shouldIgnore(event)
+	if ignore {
+		t.Errorf("shouldIgnore() = %v, want %v", ignore, false)
+	}
+}
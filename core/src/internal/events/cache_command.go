@@ -0,0 +1,115 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"github.com/drewnorman/jt-slackbot/core/internal/responders"
+	"go.uber.org/zap"
+	"regexp"
+	"strings"
+)
+
+// A ResponseCache remembers responses to
+// repeated questions and can be purged or
+// bypassed by admins.
+type ResponseCache interface {
+	Purge()
+	Invalidate(text string)
+	SetBypass(bypass bool)
+	Stats() responders.CacheStats
+}
+
+// The actions of cache commands.
+const (
+	cacheActionPurge  = "purge"
+	cacheActionStats  = "stats"
+	cacheActionBypass = "bypass"
+	cacheActionResume = "resume"
+)
+
+// cacheCommandPattern recognizes mentions that
+// start with the cache command keyword.
+var cacheCommandPattern = regexp.MustCompile(`(?is)^\s*cache\s*:\s*(.*?)\s*$`)
+
+// cacheCommandUsage explains how to give cache
+// commands when one could not be understood.
+const cacheCommandUsage = "I didn't catch that. Try `cache: purge`, `cache: stats`, " +
+	"`cache: bypass` or `cache: resume`."
+
+// cacheCommandForbidden is sent to users who
+// give cache commands but are not admins.
+const cacheCommandForbidden = "Sorry, only my admins can manage my memory."
+
+// parseCacheCommand returns the action of the
+// cache command given in the given text. The
+// second return value is false if the text is
+// not a cache command at all, and the action
+// is empty if it could not be understood.
+func parseCacheCommand(text string) (string, bool) {
+	matches := cacheCommandPattern.FindStringSubmatch(text)
+	if matches == nil {
+		return "", false
+	}
+	action := strings.ToLower(strings.TrimRight(matches[1], "."))
+	switch action {
+	case cacheActionPurge, cacheActionStats, cacheActionBypass, cacheActionResume:
+		return action, true
+	}
+	return "", true
+}
+
+// runCacheCommand carries out the given cache
+// action from the sender of the given event if
+// they are an admin and lets them know the
+// outcome in an ephemeral message.
+func (handler *AppMentionHandler) runCacheCommand(
+	ctx context.Context,
+	event *appMentionEvent,
+	action string,
+) error {
+	notice := cacheCommandUsage
+	switch {
+	case !handler.admins[event.senderUserId]:
+		handler.logger.Info(
+			"refused cache command from non-admin",
+			zap.String("userId", event.senderUserId),
+		)
+		notice = cacheCommandForbidden
+	case action == cacheActionPurge:
+		handler.responseCache.Purge()
+		notice = "Okay, I forgot every answer I was remembering."
+	case action == cacheActionBypass:
+		handler.responseCache.SetBypass(true)
+		notice = "Okay, I'll ask for fresh answers until you say `cache: resume`."
+	case action == cacheActionResume:
+		handler.responseCache.SetBypass(false)
+		notice = "Okay, I'll remember answers to repeated questions again."
+	case action == cacheActionStats:
+		stats := handler.responseCache.Stats()
+		notice = fmt.Sprintf(
+			"I'm remembering %d answers: %d hits, %d misses and %d evictions so far.",
+			stats.Size,
+			stats.Hits,
+			stats.Misses,
+			stats.Evictions,
+		)
+		if stats.Bypassed {
+			notice += " The cache is bypassed."
+		}
+	}
+	if action != "" && handler.admins[event.senderUserId] {
+		handler.logger.Info(
+			"ran cache command",
+			zap.String("action", action),
+			zap.String("userId", event.senderUserId),
+		)
+	}
+	return final(
+		handler.slackHttpClient.SendEphemeralMessage(
+			ctx,
+			notice,
+			event.channelId,
+			event.senderUserId,
+		),
+	)
+}
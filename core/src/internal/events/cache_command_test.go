@@ -0,0 +1,153 @@
+package events
+
+import (
+	"context"
+	"github.com/drewnorman/jt-slackbot/core/internal/responders"
+	"testing"
+)
+
+type fakeResponseCache struct {
+	purges        int
+	invalidations []string
+	bypass        bool
+}
+
+func (cache *fakeResponseCache) Purge() {
+	cache.purges++
+}
+
+func (cache *fakeResponseCache) Invalidate(text string) {
+	cache.invalidations = append(cache.invalidations, text)
+}
+
+func (cache *fakeResponseCache) SetBypass(bypass bool) {
+	cache.bypass = bypass
+}
+
+func (cache *fakeResponseCache) Stats() responders.CacheStats {
+	return responders.CacheStats{Bypassed: cache.bypass}
+}
+
+func TestParseCacheCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		wantAction string
+		wantOk     bool
+	}{
+		{
+			name:       "Purge",
+			text:       "cache: purge",
+			wantAction: cacheActionPurge,
+			wantOk:     true,
+		},
+		{
+			name:       "CaseAndSpacing",
+			text:       "  Cache :  BYPASS. ",
+			wantAction: cacheActionBypass,
+			wantOk:     true,
+		},
+		{
+			name:       "UnknownAction",
+			text:       "cache: explode",
+			wantAction: "",
+			wantOk:     true,
+		},
+		{
+			name:       "NotACommand",
+			text:       "what is a cache?",
+			wantAction: "",
+			wantOk:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, ok := parseCacheCommand(tt.text)
+			if action != tt.wantAction || ok != tt.wantOk {
+				t.Errorf("parseCacheCommand() = %v, %v, want %v, %v", action, ok, tt.wantAction, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestAppMentionHandler_ProcessCacheCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		senderUserId string
+		text         string
+		wantPurges   int
+		wantBypass   bool
+		wantNotices  int
+	}{
+		{
+			name:         "AdminPurges",
+			senderUserId: "U1",
+			text:         "cache: purge",
+			wantPurges:   1,
+			wantNotices:  1,
+		},
+		{
+			name:         "AdminBypasses",
+			senderUserId: "U1",
+			text:         "cache: bypass",
+			wantBypass:   true,
+			wantNotices:  1,
+		},
+		{
+			name:         "AdminStats",
+			senderUserId: "U1",
+			text:         "cache: stats",
+			wantNotices:  1,
+		},
+		{
+			name:         "NonAdminRefused",
+			senderUserId: "U2",
+			text:         "cache: purge",
+			wantPurges:   0,
+			wantNotices:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := make(map[string]int)
+			cache := &fakeResponseCache{}
+			handler, err := NewAppMentionHandler(
+				&AppMentionHandlerParameters{
+					Logger: fakeZapLogger(),
+					SlackHttpClient: fakeRoutedSlackHttpClient(
+						t,
+						map[string]map[string]interface{}{
+							"chat.postEphemeral": {"ok": true},
+						},
+						calls,
+					),
+					Responder:     fakeStaticResponder("Woof!"),
+					ResponseCache: cache,
+					AdminUserIds:  []string{"U1"},
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			eventData := fakeAppMentionEvent(tt.text)
+			eventData["event"].(map[string]interface{})["user"] = tt.senderUserId
+			err = handler.Process(context.Background(), eventData)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+			}
+			if cache.purges != tt.wantPurges {
+				t.Errorf("Process() purges = %v, want %v", cache.purges, tt.wantPurges)
+			}
+			if cache.bypass != tt.wantBypass {
+				t.Errorf("Process() bypass = %v, want %v", cache.bypass, tt.wantBypass)
+			}
+			if calls["chat.postEphemeral"] != tt.wantNotices {
+				t.Errorf("Process() notices = %v, want %v", calls["chat.postEphemeral"], tt.wantNotices)
+			}
+			if calls["chat.postMessage"] != 0 {
+				t.Errorf("Process() messages sent = %v, want %v", calls["chat.postMessage"], 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/drewnorman/jt-slackbot/core/internal/responders"
+	"strconv"
+	"strings"
+)
+
+// A ConfidencePolicy describes how to treat
+// responses the responder is not confident in.
+// Responses below the threshold for their
+// channel, or Threshold if there is none, are
+// replaced according to Action: clarify asks
+// the sender to rephrase with Clarification,
+// suggest offers the suggestions of the
+// response as buttons, and silent drops them.
+type ConfidencePolicy struct {
+	Threshold          float64
+	ThresholdByChannel map[string]float64
+	Action             string
+	Clarification      string
+}
+
+// Actions taken on responses below the
+// confidence threshold of their channel.
+const (
+	LowConfidenceClarify = "clarify"
+	LowConfidenceSuggest = "suggest"
+	LowConfidenceSilent  = "silent"
+)
+
+// Decisions made by confidenceGate.decide.
+const (
+	decisionReply = iota
+	decisionClarify
+	decisionSuggest
+	decisionSilent
+)
+
+// defaultClarification is sent in place of
+// low confidence replies unless specified
+// otherwise.
+const defaultClarification = "I'm not sure I follow. Could you put that another way?"
+
+// suggestionPrompt asks the sender which of
+// the offered suggestions they meant.
+const suggestionPrompt = "Did you mean one of these?"
+
+// suggestionActionId identifies the buttons
+// offering suggestions to the sender.
+const suggestionActionId = "did_you_mean"
+
+// maxSuggestions limits how many suggestions
+// are offered at once.
+const maxSuggestions = 3
+
+// maxSuggestionLabelLength is the most
+// characters Slack allows in a button label.
+const maxSuggestionLabelLength = 75
+
+// A confidenceGate decides what to do with
+// responses according to how confident the
+// responder is in them.
+type confidenceGate struct {
+	threshold     float64
+	byChannel     map[string]float64
+	action        string
+	clarification string
+}
+
+// newConfidenceGate returns a new confidenceGate
+// applying the given threshold, or the threshold
+// for the channel if there is one, and taking the
+// given action below it.
+func newConfidenceGate(
+	threshold float64,
+	byChannel map[string]float64,
+	action string,
+	clarification string,
+) (*confidenceGate, error) {
+	switch action {
+	case "":
+		action = LowConfidenceClarify
+	case LowConfidenceClarify, LowConfidenceSuggest, LowConfidenceSilent:
+	default:
+		return nil, fmt.Errorf("unknown low confidence action %q", action)
+	}
+	if clarification == "" {
+		clarification = defaultClarification
+	}
+	return &confidenceGate{
+		threshold:     threshold,
+		byChannel:     byChannel,
+		action:        action,
+		clarification: clarification,
+	}, nil
+}
+
+// decide returns what to do with the given
+// response in the channel matching the given
+// channelId. Outside of mentions, low confidence
+// responses are always dropped. Suggestions fall
+// back to a clarification when the response
+// offers none.
+func (gate *confidenceGate) decide(
+	channelId string,
+	mentioned bool,
+	response *responders.Response,
+) int {
+	threshold, ok := gate.byChannel[channelId]
+	if !ok {
+		threshold = gate.threshold
+	}
+	if response.Confidence >= threshold {
+		return decisionReply
+	}
+	if !mentioned {
+		return decisionSilent
+	}
+	switch gate.action {
+	case LowConfidenceSilent:
+		return decisionSilent
+	case LowConfidenceSuggest:
+		if len(response.Suggestions) > 0 {
+			return decisionSuggest
+		}
+	}
+	return decisionClarify
+}
+
+// offeredSuggestions returns the suggestions
+// of the given response that are offered to
+// the sender.
+func offeredSuggestions(suggestions []string) []string {
+	if len(suggestions) > maxSuggestions {
+		return suggestions[:maxSuggestions]
+	}
+	return suggestions
+}
+
+// suggestionBlocks returns Block Kit blocks
+// asking the user matching the given userId
+// which of the given suggestions they meant.
+// Each button carries the index of its
+// suggestion, since its label may be cut
+// short to fit, and the given threadTs of the
+// conversation it belongs to.
+func suggestionBlocks(
+	userId string,
+	threadTs string,
+	suggestions []string,
+) ([]json.RawMessage, error) {
+	suggestions = offeredSuggestions(suggestions)
+	buttons := make([]interface{}, 0, len(suggestions))
+	for index, suggestion := range suggestions {
+		buttons = append(buttons, map[string]interface{}{
+			"type":      "button",
+			"action_id": fmt.Sprintf("%s_%d", suggestionActionId, index),
+			"value":     suggestionValue(index, threadTs),
+			"text": map[string]interface{}{
+				"type": "plain_text",
+				"text": suggestionLabel(suggestion),
+			},
+		})
+	}
+	blocks := []interface{}{
+		map[string]interface{}{
+			"type": "section",
+			"text": map[string]interface{}{
+				"type": "mrkdwn",
+				"text": formatReply(userId, suggestionPrompt),
+			},
+		},
+		map[string]interface{}{
+			"type":     "actions",
+			"elements": buttons,
+		},
+	}
+	rawBlocks := make([]json.RawMessage, 0, len(blocks))
+	for _, block := range blocks {
+		rawBlock, err := json.Marshal(block)
+		if err != nil {
+			return nil, err
+		}
+		rawBlocks = append(rawBlocks, rawBlock)
+	}
+	return rawBlocks, nil
+}
+
+// suggestionValue returns the value of the
+// button offering the suggestion at the given
+// index in the thread matching the given
+// threadTs, if any.
+func suggestionValue(index int, threadTs string) string {
+	value := strconv.Itoa(index)
+	if threadTs != "" {
+		value += ":" + threadTs
+	}
+	return value
+}
+
+// parseSuggestionValue returns the index of the
+// suggestion and the timestamp of the thread
+// carried by the given button value.
+func parseSuggestionValue(value string) (int, string, error) {
+	parts := strings.SplitN(value, ":", 2)
+	index, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", err
+	}
+	if len(parts) == 1 {
+		return index, "", nil
+	}
+	return index, parts[1], nil
+}
+
+// suggestionLabel returns the given suggestion
+// cut short with an ellipsis if it is too long
+// for a button label.
+func suggestionLabel(suggestion string) string {
+	runes := []rune(suggestion)
+	if len(runes) <= maxSuggestionLabelLength {
+		return suggestion
+	}
+	return string(runes[:maxSuggestionLabelLength-1]) + "…"
+}
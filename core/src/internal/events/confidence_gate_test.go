@@ -0,0 +1,228 @@
+package events
+
+import (
+	"encoding/json"
+	"github.com/drewnorman/jt-slackbot/core/internal/responders"
+	"strings"
+	"testing"
+)
+
+func TestNewConfidenceGate(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		wantErr bool
+	}{
+		{
+			name:    "DefaultAction",
+			action:  "",
+			wantErr: false,
+		},
+		{
+			name:    "Suggest",
+			action:  LowConfidenceSuggest,
+			wantErr: false,
+		},
+		{
+			name:    "UnknownAction",
+			action:  "shrug",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newConfidenceGate(0.5, nil, tt.action, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newConfidenceGate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfidenceGate_Decide(t *testing.T) {
+	type args struct {
+		action    string
+		channelId string
+		mentioned bool
+		response  *responders.Response
+	}
+	tests := []struct {
+		name string
+		args args
+		want int
+	}{
+		{
+			name: "ConfidentReply",
+			args: args{
+				action:    LowConfidenceClarify,
+				channelId: "C1",
+				mentioned: true,
+				response:  &responders.Response{Confidence: 0.6},
+			},
+			want: decisionReply,
+		},
+		{
+			name: "Clarifies",
+			args: args{
+				action:    LowConfidenceClarify,
+				channelId: "C1",
+				mentioned: true,
+				response:  &responders.Response{Confidence: 0.4},
+			},
+			want: decisionClarify,
+		},
+		{
+			name: "ChannelThreshold",
+			args: args{
+				action:    LowConfidenceClarify,
+				channelId: "CSTRICT",
+				mentioned: true,
+				response:  &responders.Response{Confidence: 0.6},
+			},
+			want: decisionClarify,
+		},
+		{
+			name: "Suggests",
+			args: args{
+				action:    LowConfidenceSuggest,
+				channelId: "C1",
+				mentioned: true,
+				response: &responders.Response{
+					Confidence:  0.4,
+					Suggestions: []string{"Who is a good dog?"},
+				},
+			},
+			want: decisionSuggest,
+		},
+		{
+			name: "ClarifiesWithoutSuggestions",
+			args: args{
+				action:    LowConfidenceSuggest,
+				channelId: "C1",
+				mentioned: true,
+				response:  &responders.Response{Confidence: 0.4},
+			},
+			want: decisionClarify,
+		},
+		{
+			name: "Silent",
+			args: args{
+				action:    LowConfidenceSilent,
+				channelId: "C1",
+				mentioned: true,
+				response:  &responders.Response{Confidence: 0.4},
+			},
+			want: decisionSilent,
+		},
+		{
+			name: "SilentWithoutMention",
+			args: args{
+				action:    LowConfidenceClarify,
+				channelId: "C1",
+				mentioned: false,
+				response:  &responders.Response{Confidence: 0.4},
+			},
+			want: decisionSilent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gate, err := newConfidenceGate(
+				0.5,
+				map[string]float64{"CSTRICT": 0.8},
+				tt.args.action,
+				"",
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := gate.decide(tt.args.channelId, tt.args.mentioned, tt.args.response)
+			if got != tt.want {
+				t.Errorf("decide() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestionBlocks(t *testing.T) {
+	blocks, err := suggestionBlocks(
+		"U1",
+		"1610000000.000100",
+		[]string{"one", "two", "three", "four"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("suggestionBlocks() = %v, want %v blocks", len(blocks), 2)
+	}
+	var actions struct {
+		Elements []struct {
+			ActionId string `json:"action_id"`
+			Value    string `json:"value"`
+		} `json:"elements"`
+	}
+	err = json.Unmarshal(blocks[1], &actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions.Elements) != maxSuggestions {
+		t.Errorf("suggestionBlocks() buttons = %v, want %v", len(actions.Elements), maxSuggestions)
+	}
+	if actions.Elements[1].Value != "1:1610000000.000100" {
+		t.Errorf("suggestionBlocks() value = %v, want %v", actions.Elements[1].Value, "1:1610000000.000100")
+	}
+}
+
+func TestParseSuggestionValue(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        string
+		wantIndex    int
+		wantThreadTs string
+		wantErr      bool
+	}{
+		{"IndexOnly", "2", 2, "", false},
+		{"IndexAndThread", "1:1610000000.000100", 1, "1610000000.000100", false},
+		{"InvalidIndex", "Who is a good dog?", 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, threadTs, err := parseSuggestionValue(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseSuggestionValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if index != tt.wantIndex || threadTs != tt.wantThreadTs {
+				t.Errorf("parseSuggestionValue() = %v, %v, want %v, %v", index, threadTs, tt.wantIndex, tt.wantThreadTs)
+			}
+		})
+	}
+}
+
+func TestSuggestionBlocks_LongSuggestion(t *testing.T) {
+	suggestion := strings.Repeat("a", 200)
+	blocks, err := suggestionBlocks("U1", "", []string{suggestion})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions struct {
+		Elements []struct {
+			Value string `json:"value"`
+			Text  struct {
+				Text string `json:"text"`
+			} `json:"text"`
+		} `json:"elements"`
+	}
+	err = json.Unmarshal(blocks[1], &actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	label := []rune(actions.Elements[0].Text.Text)
+	if len(label) != maxSuggestionLabelLength || label[len(label)-1] != '…' {
+		t.Errorf("suggestionBlocks() label = %v, want %v characters ending in an ellipsis", string(label), maxSuggestionLabelLength)
+	}
+	if actions.Elements[0].Value != "0" {
+		t.Errorf("suggestionBlocks() value = %v, want %v", actions.Elements[0].Value, "0")
+	}
+}
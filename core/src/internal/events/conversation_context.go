@@ -0,0 +1,237 @@
+package events
+
+import (
+	"context"
+	"github.com/drewnorman/jt-slackbot/core/internal/responders"
+	"github.com/drewnorman/jt-slackbot/core/internal/slack"
+	"github.com/drewnorman/jt-slackbot/core/internal/slack/mrkdwn"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// conversationContextTtl determines how long
+// user and channel details are cached before
+// they are requested from Slack again.
+const conversationContextTtl = time.Hour
+
+// conversationContextMaxEntries limits how many
+// users and channels are cached at once.
+const conversationContextMaxEntries = 1000
+
+// A conversationContext adds details about the
+// sender, the channel, and the recent messages
+// of a conversation to requests for responders.
+type conversationContext struct {
+	logger          *zap.Logger
+	slackHttpClient *slack.HttpClient
+	historyLength   int
+	now             func() time.Time
+	mutex           sync.Mutex
+	users           map[string]cachedUser
+	channels        map[string]cachedChannel
+}
+
+// A cachedUser is a user looked up at fetchedAt.
+type cachedUser struct {
+	user      *slack.User
+	fetchedAt time.Time
+}
+
+// A cachedChannel is a channel looked up
+// at fetchedAt.
+type cachedChannel struct {
+	channel   *slack.Channel
+	fetchedAt time.Time
+}
+
+// newConversationContext returns a new
+// conversationContext including up to
+// historyLength recent messages in requests.
+func newConversationContext(
+	logger *zap.Logger,
+	slackHttpClient *slack.HttpClient,
+	historyLength int,
+) *conversationContext {
+	return &conversationContext{
+		logger:          logger,
+		slackHttpClient: slackHttpClient,
+		historyLength:   historyLength,
+		now:             time.Now,
+		users:           make(map[string]cachedUser),
+		channels:        make(map[string]cachedChannel),
+	}
+}
+
+// enrich adds whatever context can be found to
+// the given request. Context is a nicety, so
+// failed lookups are logged and skipped.
+func (conversation *conversationContext) enrich(
+	ctx context.Context,
+	request *responders.Request,
+) {
+	if request.UserId != "" {
+		user, err := conversation.user(ctx, request.UserId)
+		if err != nil {
+			conversation.logger.Debug(
+				"failed to look up user",
+				zap.String("err", err.Error()),
+				zap.String("userId", request.UserId),
+			)
+		} else {
+			request.UserName = user.DisplayName
+			request.Locale = user.Locale
+		}
+	}
+
+	if request.ChannelId == "" {
+		return
+	}
+	channel, err := conversation.channel(ctx, request.ChannelId)
+	if err != nil {
+		conversation.logger.Debug(
+			"failed to look up channel",
+			zap.String("err", err.Error()),
+			zap.String("channelId", request.ChannelId),
+		)
+	} else {
+		request.ChannelType = channel.Type
+	}
+
+	if conversation.historyLength < 1 {
+		return
+	}
+	messages, err := conversation.slackHttpClient.RecentMessages(
+		ctx,
+		request.ChannelId,
+		request.ThreadTs,
+		conversation.historyLength+1,
+	)
+	if err != nil {
+		conversation.logger.Debug(
+			"failed to look up recent messages",
+			zap.String("err", err.Error()),
+			zap.String("channelId", request.ChannelId),
+		)
+		return
+	}
+	history := make([]*responders.Message, 0, len(messages))
+	for _, message := range messages {
+		if message.Ts == request.Ts {
+			continue
+		}
+		history = append(
+			history,
+			&responders.Message{
+				UserId:  message.UserId,
+				Text:    conversation.plainText(ctx, message.Text),
+				Ts:      message.Ts,
+				FromBot: message.BotId != "",
+			},
+		)
+	}
+	if len(history) > conversation.historyLength {
+		history = history[len(history)-conversation.historyLength:]
+	}
+	request.History = history
+}
+
+// plainText returns the given Slack message as
+// plain text, naming the users and channels it
+// mentions.
+func (conversation *conversationContext) plainText(
+	ctx context.Context,
+	message string,
+) string {
+	return mrkdwn.PlainText(ctx, message, conversation)
+}
+
+// UserName returns the display name of the
+// user matching the given userId.
+func (conversation *conversationContext) UserName(
+	ctx context.Context,
+	userId string,
+) (string, error) {
+	user, err := conversation.user(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+	return user.DisplayName, nil
+}
+
+// ChannelName returns the name of the channel
+// matching the given channelId.
+func (conversation *conversationContext) ChannelName(
+	ctx context.Context,
+	channelId string,
+) (string, error) {
+	channel, err := conversation.channel(ctx, channelId)
+	if err != nil {
+		return "", err
+	}
+	return channel.Name, nil
+}
+
+// user returns the cached details of the user
+// matching the given userId, looking them up
+// if necessary.
+func (conversation *conversationContext) user(
+	ctx context.Context,
+	userId string,
+) (*slack.User, error) {
+	now := conversation.now()
+	conversation.mutex.Lock()
+	cached, ok := conversation.users[userId]
+	conversation.mutex.Unlock()
+	if ok && now.Sub(cached.fetchedAt) < conversationContextTtl {
+		return cached.user, nil
+	}
+
+	user, err := conversation.slackHttpClient.UserInfo(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	conversation.mutex.Lock()
+	defer conversation.mutex.Unlock()
+	if len(conversation.users) >= conversationContextMaxEntries {
+		conversation.users = make(map[string]cachedUser)
+	}
+	conversation.users[userId] = cachedUser{
+		user:      user,
+		fetchedAt: now,
+	}
+	return user, nil
+}
+
+// channel returns the cached details of the
+// channel matching the given channelId, looking
+// them up if necessary.
+func (conversation *conversationContext) channel(
+	ctx context.Context,
+	channelId string,
+) (*slack.Channel, error) {
+	now := conversation.now()
+	conversation.mutex.Lock()
+	cached, ok := conversation.channels[channelId]
+	conversation.mutex.Unlock()
+	if ok && now.Sub(cached.fetchedAt) < conversationContextTtl {
+		return cached.channel, nil
+	}
+
+	channel, err := conversation.slackHttpClient.ChannelInfo(ctx, channelId)
+	if err != nil {
+		return nil, err
+	}
+
+	conversation.mutex.Lock()
+	defer conversation.mutex.Unlock()
+	if len(conversation.channels) >= conversationContextMaxEntries {
+		conversation.channels = make(map[string]cachedChannel)
+	}
+	conversation.channels[channelId] = cachedChannel{
+		channel:   channel,
+		fetchedAt: now,
+	}
+	return channel, nil
+}
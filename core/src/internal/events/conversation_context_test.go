@@ -0,0 +1,184 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/drewnorman/jt-slackbot/core/internal/responders"
+	"github.com/drewnorman/jt-slackbot/core/internal/slack"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"testing"
+	"time"
+)
+
+func fakeRoutedSlackHttpClient(
+	t *testing.T,
+	routes map[string]map[string]interface{},
+	calls map[string]int,
+) *slack.HttpClient {
+	t.Helper()
+
+	httpClient := fakeHttpClient(
+		func(req *http.Request) *http.Response {
+			endpoint := path.Base(req.URL.Path)
+			calls[endpoint]++
+			data, ok := routes[endpoint]
+			if !ok {
+				data = map[string]interface{}{"ok": false, "error": "unknown_method"}
+			}
+			bodyJson, err := json.Marshal(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			header := http.Header{}
+			header.Add("Content-Type", "application/json")
+			return &http.Response{
+				StatusCode: 200,
+				Header:     header,
+				Body:       ioutil.NopCloser(bytes.NewBuffer(bodyJson)),
+			}
+		},
+	)
+
+	slackHttpClient, err := slack.NewHttpClient(
+		&slack.HttpClientParameters{
+			Logger:     fakeZapLogger(),
+			ApiUrl:     "https://slack.test/api/",
+			AppToken:   "xapp-fake",
+			BotToken:   "xoxb-fake",
+			HttpClient: httpClient,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return slackHttpClient
+}
+
+func TestConversationContext_Enrich(t *testing.T) {
+	routes := map[string]map[string]interface{}{
+		"users.info": {
+			"ok": true,
+			"user": map[string]interface{}{
+				"name":    "jimmy",
+				"locale":  "en-US",
+				"profile": map[string]interface{}{"display_name": "Jimmy"},
+			},
+		},
+		"conversations.info": {
+			"ok":      true,
+			"channel": map[string]interface{}{"is_private": true},
+		},
+		"conversations.replies": {
+			"ok": true,
+			"messages": []interface{}{
+				map[string]interface{}{"user": "U0123", "text": "first", "ts": "1"},
+				map[string]interface{}{"bot_id": "B0123", "text": "second", "ts": "2"},
+				map[string]interface{}{"user": "U0123", "text": "third", "ts": "3"},
+				map[string]interface{}{"user": "U0123", "text": "current", "ts": "4"},
+			},
+		},
+	}
+	calls := make(map[string]int)
+	conversation := newConversationContext(
+		fakeZapLogger(),
+		fakeRoutedSlackHttpClient(t, routes, calls),
+		2,
+	)
+
+	request := &responders.Request{
+		Text:      "current",
+		UserId:    "U0123",
+		ChannelId: "C0123",
+		Ts:        "4",
+		ThreadTs:  "1",
+	}
+	conversation.enrich(context.Background(), request)
+
+	if request.UserName != "Jimmy" {
+		t.Errorf("enrich() user name = %v, want %v", request.UserName, "Jimmy")
+	}
+	if request.Locale != "en-US" {
+		t.Errorf("enrich() locale = %v, want %v", request.Locale, "en-US")
+	}
+	if request.ChannelType != "private_channel" {
+		t.Errorf("enrich() channel type = %v, want %v", request.ChannelType, "private_channel")
+	}
+	if len(request.History) != 2 {
+		t.Fatalf("enrich() history = %v, want %v", len(request.History), 2)
+	}
+	if request.History[0].Text != "second" || !request.History[0].FromBot {
+		t.Errorf("enrich() history[0] = %v, want bot message %v", request.History[0], "second")
+	}
+	if request.History[1].Text != "third" {
+		t.Errorf("enrich() history[1] = %v, want %v", request.History[1].Text, "third")
+	}
+
+	conversation.enrich(context.Background(), &responders.Request{UserId: "U0123", ChannelId: "C0123"})
+	if calls["users.info"] != 1 || calls["conversations.info"] != 1 {
+		t.Errorf("enrich() calls = %v, want cached lookups", calls)
+	}
+
+	later := time.Now().Add(2 * conversationContextTtl)
+	conversation.now = func() time.Time { return later }
+	conversation.enrich(context.Background(), &responders.Request{UserId: "U0123"})
+	if calls["users.info"] != 2 {
+		t.Errorf("enrich() calls = %v, want expired lookup", calls)
+	}
+}
+
+func TestConversationContext_EnrichIgnoresFailures(t *testing.T) {
+	calls := make(map[string]int)
+	conversation := newConversationContext(
+		fakeZapLogger(),
+		fakeRoutedSlackHttpClient(t, map[string]map[string]interface{}{}, calls),
+		5,
+	)
+
+	request := &responders.Request{
+		Text:      "hello",
+		UserId:    "U0123",
+		ChannelId: "C0123",
+	}
+	conversation.enrich(context.Background(), request)
+
+	if request.UserName != "" || request.ChannelType != "" || request.History != nil {
+		t.Errorf("enrich() = %v, want no context", request)
+	}
+	if request.Text != "hello" {
+		t.Errorf("enrich() text = %v, want %v", request.Text, "hello")
+	}
+}
+
+func TestConversationContext_PlainText(t *testing.T) {
+	routes := map[string]map[string]interface{}{
+		"users.info": {
+			"ok": true,
+			"user": map[string]interface{}{
+				"name":    "jimmy",
+				"profile": map[string]interface{}{"display_name": "Jimmy"},
+			},
+		},
+		"conversations.info": {
+			"ok":      true,
+			"channel": map[string]interface{}{"name": "general"},
+		},
+	}
+	calls := make(map[string]int)
+	conversation := newConversationContext(
+		fakeZapLogger(),
+		fakeRoutedSlackHttpClient(t, routes, calls),
+		0,
+	)
+
+	got := conversation.plainText(context.Background(), " is <@U0123> in <#C0123>? :thinking_face: ")
+	if want := "is @Jimmy in #general? thinking_face"; got != want {
+		t.Errorf("plainText() = %v, want %v", got, want)
+	}
+	conversation.plainText(context.Background(), "<@U0123>")
+	if calls["users.info"] != 1 {
+		t.Errorf("plainText() calls = %v, want cached lookups", calls)
+	}
+}
@@ -0,0 +1,143 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// A DeadLetter is an event that could not
+// be processed along with the reason why.
+type DeadLetter struct {
+	EventId   string                 `json:"event_id"`
+	EventType string                 `json:"event_type"`
+	Error     string                 `json:"error"`
+	Attempts  int                    `json:"attempts"`
+	FailedAt  time.Time              `json:"failed_at"`
+	Event     map[string]interface{} `json:"event"`
+}
+
+// A DeadLetterStore persists dead letters
+// as JSON lines in a file.
+type DeadLetterStore struct {
+	mutex sync.Mutex
+	path  string
+}
+
+// NewDeadLetterStore returns a new instance
+// of DeadLetterStore writing to the file at
+// the given path.
+func NewDeadLetterStore(path string) (*DeadLetterStore, error) {
+	if path == "" {
+		return nil, errors.New("missing dead letter path")
+	}
+	return &DeadLetterStore{
+		path: path,
+	}, nil
+}
+
+// Add appends the given dead letter to
+// the store.
+func (store *DeadLetterStore) Add(letter DeadLetter) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	encoded, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(store.path), 0755)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(
+		store.path,
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+		0644,
+	)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(encoded, '\n'))
+	return err
+}
+
+// List returns every dead letter in the
+// store, oldest first.
+func (store *DeadLetterStore) List() ([]DeadLetter, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	file, err := os.Open(store.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var letters []DeadLetter
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var letter DeadLetter
+		err = json.Unmarshal(scanner.Bytes(), &letter)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	return letters, scanner.Err()
+}
+
+// Replace overwrites the contents of the
+// store with the given dead letters.
+func (store *DeadLetterStore) Replace(letters []DeadLetter) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	err := os.MkdirAll(filepath.Dir(store.path), 0755)
+	if err != nil {
+		return err
+	}
+	tempPath := store.path + ".tmp"
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	for _, letter := range letters {
+		encoded, err := json.Marshal(letter)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		_, err = writer.Write(append(encoded, '\n'))
+		if err != nil {
+			file.Close()
+			return err
+		}
+	}
+	err = writer.Flush()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tempPath, store.path)
+}
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func fakeDeadLetterStore(t *testing.T) *DeadLetterStore {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "dead-letters")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	store, err := NewDeadLetterStore(
+		filepath.Join(dir, "nested", "dead-letters.jsonl"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func fakeDeadLetter() DeadLetter {
+	return DeadLetter{
+		EventId:   gofakeit.UUID(),
+		EventType: "app_mention",
+		Error:     gofakeit.LoremIpsumSentence(3),
+		Attempts:  3,
+		FailedAt:  time.Now().UTC(),
+		Event: map[string]interface{}{
+			"event_id": gofakeit.UUID(),
+		},
+	}
+}
+
+func TestNewDeadLetterStore(t *testing.T) {
+	_, err := NewDeadLetterStore("")
+	if err == nil {
+		t.Errorf("NewDeadLetterStore() error = %v, wantErr %v", err, true)
+	}
+}
+
+func TestDeadLetterStore_AddAndList(t *testing.T) {
+	store := fakeDeadLetterStore(t)
+
+	letters, err := store.List()
+	if err != nil || len(letters) != 0 {
+		t.Errorf("List() = %v, %v, want %v, %v", letters, err, nil, nil)
+	}
+
+	first := fakeDeadLetter()
+	second := fakeDeadLetter()
+	for _, letter := range []DeadLetter{first, second} {
+		err = store.Add(letter)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	letters, err = store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 2 {
+		t.Fatalf("List() = %v, want %v", len(letters), 2)
+	}
+	if letters[0].EventId != first.EventId || letters[1].EventId != second.EventId {
+		t.Errorf("List() = %v, want %v", letters, []DeadLetter{first, second})
+	}
+}
+
+func TestDeadLetterStore_Replace(t *testing.T) {
+	store := fakeDeadLetterStore(t)
+	for i := 0; i < 3; i++ {
+		err := store.Add(fakeDeadLetter())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	remaining := fakeDeadLetter()
+	err := store.Replace([]DeadLetter{remaining})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	letters, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 || letters[0].EventId != remaining.EventId {
+		t.Errorf("List() = %v, want %v", letters, []DeadLetter{remaining})
+	}
+}
@@ -0,0 +1,294 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/drewnorman/jt-slackbot/core/internal/dialog"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// Labels given to feedback on replies.
+const (
+	FeedbackPositive = "positive"
+	FeedbackNegative = "negative"
+)
+
+// A Feedback is a reaction to a reply of the
+// app, labelled as positive or negative.
+type Feedback struct {
+	ChannelId    string    `json:"channel_id"`
+	SourceTs     string    `json:"source_ts"`
+	ThreadTs     string    `json:"thread_ts,omitempty"`
+	ReplyTs      string    `json:"reply_ts"`
+	SenderUserId string    `json:"sender_user_id"`
+	UserId       string    `json:"user_id"`
+	Input        string    `json:"input"`
+	Reply        string    `json:"reply"`
+	Reaction     string    `json:"reaction"`
+	Label        string    `json:"label"`
+	ReactedAt    time.Time `json:"reacted_at"`
+}
+
+// A FeedbackSink records feedback on replies.
+type FeedbackSink interface {
+	RecordFeedback(ctx context.Context, feedback *Feedback) error
+}
+
+// An Exchange is a message that mentioned
+// the app and the reply the app sent.
+type Exchange struct {
+	ChannelId    string
+	SourceTs     string
+	ThreadTs     string
+	ReplyTs      string
+	SenderUserId string
+	Input        string
+	Reply        string
+}
+
+// A ReplyLookup finds exchanges by the
+// timestamp of the reply.
+type ReplyLookup interface {
+	LookupReply(channelId string, replyTs string) (Exchange, bool)
+}
+
+// A FeedbackHandler processes reactions added
+// to replies of the app and records them as
+// feedback.
+type FeedbackHandler struct {
+	logger            *zap.Logger
+	replyLookup       ReplyLookup
+	sinks             []FeedbackSink
+	positiveReactions map[string]bool
+	negativeReactions map[string]bool
+}
+
+// FeedbackHandlerParameters describe how to
+// create a new FeedbackHandler.
+type FeedbackHandlerParameters struct {
+	Logger            *zap.Logger
+	ReplyLookup       ReplyLookup
+	Sinks             []FeedbackSink
+	PositiveReactions []string
+	NegativeReactions []string
+}
+
+// NewFeedbackHandler returns a new instance of
+// FeedbackHandler according to the given
+// parameters.
+func NewFeedbackHandler(
+	params *FeedbackHandlerParameters,
+) (*FeedbackHandler, error) {
+	if params.Logger == nil {
+		return nil, errors.New("missing logger")
+	}
+	if params.ReplyLookup == nil {
+		return nil, errors.New("missing reply lookup")
+	}
+	positiveReactions := reactionSet(params.PositiveReactions)
+	negativeReactions := reactionSet(params.NegativeReactions)
+	for reaction := range positiveReactions {
+		if negativeReactions[reaction] {
+			return nil, fmt.Errorf("reaction %q is both positive and negative", reaction)
+		}
+	}
+	return &FeedbackHandler{
+		logger:            params.Logger,
+		replyLookup:       params.ReplyLookup,
+		sinks:             params.Sinks,
+		positiveReactions: positiveReactions,
+		negativeReactions: negativeReactions,
+	}, nil
+}
+
+// Process processes the given reaction added
+// event, recording feedback if the reaction is
+// positive or negative and was added to a reply
+// of the app. Feedback is a nicety, so failures
+// to record it are only logged.
+func (handler *FeedbackHandler) Process(
+	ctx context.Context,
+	eventData map[string]interface{},
+) error {
+	event, ok := eventData["event"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("failed to determine event data from data %v", eventData)
+	}
+	item, ok := event["item"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("failed to determine item from event data %v", event)
+	}
+	if itemType, _ := item["type"].(string); itemType != "message" {
+		return nil
+	}
+	channelId, _ := item["channel"].(string)
+	ts, _ := item["ts"].(string)
+	userId, _ := event["user"].(string)
+	reaction, _ := event["reaction"].(string)
+
+	label := handler.label(reaction)
+	if label == "" {
+		return nil
+	}
+	exchange, ok := handler.replyLookup.LookupReply(channelId, ts)
+	if !ok {
+		handler.logger.Debug(
+			"no tracked reply for reaction",
+			zap.String("channelId", channelId),
+			zap.String("ts", ts),
+		)
+		return nil
+	}
+
+	feedback := &Feedback{
+		ChannelId:    exchange.ChannelId,
+		SourceTs:     exchange.SourceTs,
+		ThreadTs:     exchange.ThreadTs,
+		ReplyTs:      exchange.ReplyTs,
+		SenderUserId: exchange.SenderUserId,
+		UserId:       userId,
+		Input:        exchange.Input,
+		Reply:        exchange.Reply,
+		Reaction:     reaction,
+		Label:        label,
+		ReactedAt:    time.Now().UTC(),
+	}
+	if len(handler.sinks) == 0 {
+		handler.logger.Debug(
+			"no sinks to record feedback on reply",
+			zap.String("channelId", channelId),
+			zap.String("ts", ts),
+		)
+		return nil
+	}
+	recorded := 0
+	for _, sink := range handler.sinks {
+		err := sink.RecordFeedback(ctx, feedback)
+		if err != nil {
+			handler.logger.Warn(
+				"failed to record feedback",
+				zap.String("err", err.Error()),
+				zap.String("channelId", channelId),
+				zap.String("ts", ts),
+			)
+			continue
+		}
+		recorded++
+	}
+	if recorded == 0 {
+		handler.logger.Error(
+			"lost feedback on reply, every sink failed",
+			zap.String("label", label),
+			zap.String("reaction", reaction),
+			zap.String("channelId", channelId),
+			zap.String("ts", ts),
+		)
+		return nil
+	}
+	handler.logger.Info(
+		"recorded feedback on reply",
+		zap.String("label", label),
+		zap.String("reaction", reaction),
+		zap.String("channelId", channelId),
+	)
+	return nil
+}
+
+// label returns the label of the given reaction
+// or an empty string if it is neither positive
+// nor negative. Skin tones are ignored.
+func (handler *FeedbackHandler) label(reaction string) string {
+	name := normalizeReaction(reaction)
+	if handler.positiveReactions[name] {
+		return FeedbackPositive
+	}
+	if handler.negativeReactions[name] {
+		return FeedbackNegative
+	}
+	return ""
+}
+
+// A DialogFeedbackSink forwards feedback to
+// the dialog service. Since the dialog service
+// learns the replies given positive feedback,
+// only the positive feedback of trainers is
+// forwarded.
+type DialogFeedbackSink struct {
+	dialogClient *dialog.Client
+	trainers     map[string]bool
+}
+
+// DialogFeedbackSinkParameters describe how to
+// create a new DialogFeedbackSink.
+type DialogFeedbackSinkParameters struct {
+	DialogClient   *dialog.Client
+	TrainerUserIds []string
+}
+
+// NewDialogFeedbackSink returns a new
+// DialogFeedbackSink according to the given
+// parameters.
+func NewDialogFeedbackSink(
+	params *DialogFeedbackSinkParameters,
+) (*DialogFeedbackSink, error) {
+	if params.DialogClient == nil {
+		return nil, errors.New("missing dialog client")
+	}
+	trainers := make(map[string]bool)
+	for _, userId := range params.TrainerUserIds {
+		trainers[userId] = true
+	}
+	return &DialogFeedbackSink{
+		dialogClient: params.DialogClient,
+		trainers:     trainers,
+	}, nil
+}
+
+// RecordFeedback sends the given feedback to
+// the dialog service, skipping positive
+// feedback from anyone but trainers.
+func (sink *DialogFeedbackSink) RecordFeedback(
+	ctx context.Context,
+	feedback *Feedback,
+) error {
+	if feedback.Label == FeedbackPositive && !sink.trainers[feedback.UserId] {
+		return nil
+	}
+	return sink.dialogClient.SendFeedback(
+		ctx,
+		&dialog.Feedback{
+			SessionId: dialog.SessionId(feedback.ChannelId, feedback.ThreadTs),
+			Message:   feedback.Input,
+			Reply:     feedback.Reply,
+			Label:     feedback.Label,
+			Reaction:  feedback.Reaction,
+			UserId:    feedback.UserId,
+		},
+	)
+}
+
+// reactionSet returns the set of the given
+// reaction names.
+func reactionSet(reactions []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, reaction := range reactions {
+		name := normalizeReaction(reaction)
+		if name != "" {
+			set[name] = true
+		}
+	}
+	return set
+}
+
+// normalizeReaction strips colons and skin
+// tones from the given reaction name.
+func normalizeReaction(reaction string) string {
+	name := strings.Trim(strings.TrimSpace(reaction), ":")
+	if index := strings.Index(name, "::"); index >= 0 {
+		name = name[:index]
+	}
+	return name
+}
@@ -0,0 +1,337 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"github.com/drewnorman/jt-slackbot/core/internal/dialog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeReplyLookup struct {
+	exchanges map[string]Exchange
+}
+
+func (lookup *fakeReplyLookup) LookupReply(
+	channelId string,
+	replyTs string,
+) (Exchange, bool) {
+	exchange, ok := lookup.exchanges[messageKey(channelId, replyTs)]
+	return exchange, ok
+}
+
+type fakeFeedbackSink struct {
+	recorded []*Feedback
+	err      error
+}
+
+func (sink *fakeFeedbackSink) RecordFeedback(
+	ctx context.Context,
+	feedback *Feedback,
+) error {
+	sink.recorded = append(sink.recorded, feedback)
+	return sink.err
+}
+
+func fakeReactionAddedEvent(reaction string, ts string) map[string]interface{} {
+	return map[string]interface{}{
+		"event_id": "Ev1",
+		"event": map[string]interface{}{
+			"type":     "reaction_added",
+			"user":     "U2",
+			"reaction": reaction,
+			"item": map[string]interface{}{
+				"type":    "message",
+				"channel": "C1",
+				"ts":      ts,
+			},
+		},
+	}
+}
+
+func TestNewFeedbackHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  *FeedbackHandlerParameters
+		wantErr bool
+	}{
+		{
+			name: "ReturnsHandler",
+			params: &FeedbackHandlerParameters{
+				Logger:            fakeZapLogger(),
+				ReplyLookup:       &fakeReplyLookup{},
+				PositiveReactions: []string{"+1"},
+				NegativeReactions: []string{"-1"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingLogger",
+			params: &FeedbackHandlerParameters{
+				ReplyLookup: &fakeReplyLookup{},
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingReplyLookup",
+			params: &FeedbackHandlerParameters{
+				Logger: fakeZapLogger(),
+			},
+			wantErr: true,
+		},
+		{
+			name: "ConflictingReactions",
+			params: &FeedbackHandlerParameters{
+				Logger:            fakeZapLogger(),
+				ReplyLookup:       &fakeReplyLookup{},
+				PositiveReactions: []string{"eyes"},
+				NegativeReactions: []string{":eyes:"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFeedbackHandler(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewFeedbackHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFeedbackHandler_Process(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventData map[string]interface{}
+		sinkErr   error
+		wantLabel string
+		wantErr   bool
+	}{
+		{
+			name:      "RecordsPositiveFeedback",
+			eventData: fakeReactionAddedEvent("+1", "20"),
+			wantLabel: FeedbackPositive,
+			wantErr:   false,
+		},
+		{
+			name:      "RecordsNegativeFeedbackIgnoringSkinTone",
+			eventData: fakeReactionAddedEvent("-1::skin-tone-3", "20"),
+			wantLabel: FeedbackNegative,
+			wantErr:   false,
+		},
+		{
+			name:      "IgnoresOtherReactions",
+			eventData: fakeReactionAddedEvent("eyes", "20"),
+			wantErr:   false,
+		},
+		{
+			name:      "IgnoresUntrackedMessages",
+			eventData: fakeReactionAddedEvent("+1", "30"),
+			wantErr:   false,
+		},
+		{
+			name:      "ToleratesSinkFailures",
+			eventData: fakeReactionAddedEvent("+1", "20"),
+			sinkErr:   errors.New("unavailable"),
+			wantLabel: FeedbackPositive,
+			wantErr:   false,
+		},
+		{
+			name: "MissingItem",
+			eventData: map[string]interface{}{
+				"event": map[string]interface{}{
+					"type":     "reaction_added",
+					"reaction": "+1",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &fakeFeedbackSink{err: tt.sinkErr}
+			handler, err := NewFeedbackHandler(
+				&FeedbackHandlerParameters{
+					Logger: fakeZapLogger(),
+					ReplyLookup: &fakeReplyLookup{
+						exchanges: map[string]Exchange{
+							messageKey("C1", "20"): {
+								ChannelId: "C1",
+								SourceTs:  "10",
+								ReplyTs:   "20",
+								Input:     "hello",
+								Reply:     "Woof!",
+							},
+						},
+					},
+					Sinks:             []FeedbackSink{sink},
+					PositiveReactions: []string{"+1", "heart"},
+					NegativeReactions: []string{"-1"},
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = handler.Process(context.Background(), tt.eventData)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantLabel == "" {
+				if len(sink.recorded) != 0 {
+					t.Errorf("Process() recorded = %v, want none", sink.recorded)
+				}
+				return
+			}
+			if len(sink.recorded) != 1 {
+				t.Fatalf("Process() recorded = %v, want %v", len(sink.recorded), 1)
+			}
+			feedback := sink.recorded[0]
+			if feedback.Label != tt.wantLabel || feedback.Input != "hello" || feedback.UserId != "U2" {
+				t.Errorf("Process() feedback = %v, want label %v", feedback, tt.wantLabel)
+			}
+		})
+	}
+}
+
+func TestFeedbackHandler_ProcessLogsOutcome(t *testing.T) {
+	tests := []struct {
+		name        string
+		sinkErrs    []error
+		wantMessage string
+		wantLevel   zapcore.Level
+	}{
+		{
+			name:        "SomeSinksSucceed",
+			sinkErrs:    []error{errors.New("unavailable"), nil},
+			wantMessage: "recorded feedback on reply",
+			wantLevel:   zapcore.InfoLevel,
+		},
+		{
+			name:        "EverySinkFails",
+			sinkErrs:    []error{errors.New("unavailable"), errors.New("disk full")},
+			wantMessage: "lost feedback on reply, every sink failed",
+			wantLevel:   zapcore.ErrorLevel,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			var sinks []FeedbackSink
+			for _, sinkErr := range tt.sinkErrs {
+				sinks = append(sinks, &fakeFeedbackSink{err: sinkErr})
+			}
+			handler, err := NewFeedbackHandler(
+				&FeedbackHandlerParameters{
+					Logger: zap.New(core),
+					ReplyLookup: &fakeReplyLookup{
+						exchanges: map[string]Exchange{
+							messageKey("C1", "20"): {ChannelId: "C1", ReplyTs: "20"},
+						},
+					},
+					Sinks:             sinks,
+					PositiveReactions: []string{"+1"},
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = handler.Process(context.Background(), fakeReactionAddedEvent("+1", "20"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries := logs.FilterMessage(tt.wantMessage).All()
+			if len(entries) != 1 || entries[0].Level != tt.wantLevel {
+				t.Errorf("Process() logged = %v, want %q at %v", logs.All(), tt.wantMessage, tt.wantLevel)
+			}
+			if tt.wantLevel == zapcore.ErrorLevel &&
+				logs.FilterMessage("recorded feedback on reply").Len() != 0 {
+				t.Errorf("Process() logged recorded feedback, want none")
+			}
+		})
+	}
+}
+
+func TestDialogFeedbackSink_RecordFeedback(t *testing.T) {
+	tests := []struct {
+		name         string
+		label        string
+		userId       string
+		wantRequests int
+	}{
+		{
+			name:         "ForwardsPositiveFeedbackOfTrainers",
+			label:        FeedbackPositive,
+			userId:       "U1",
+			wantRequests: 1,
+		},
+		{
+			name:         "SkipsPositiveFeedbackOfOthers",
+			label:        FeedbackPositive,
+			userId:       "U2",
+			wantRequests: 0,
+		},
+		{
+			name:         "ForwardsNegativeFeedback",
+			label:        FeedbackNegative,
+			userId:       "U2",
+			wantRequests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(
+				http.HandlerFunc(
+					func(w http.ResponseWriter, r *http.Request) {
+						requests++
+						w.Header().Set("Content-Type", "application/json")
+						_, _ = w.Write([]byte(`{"ok": true}`))
+					},
+				),
+			)
+			defer server.Close()
+			dialogClient, err := dialog.NewClient(
+				&dialog.ClientParameters{
+					Logger:  fakeZapLogger(),
+					BaseUrl: server.URL + "/",
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sink, err := NewDialogFeedbackSink(
+				&DialogFeedbackSinkParameters{
+					DialogClient:   dialogClient,
+					TrainerUserIds: []string{"U1"},
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = sink.RecordFeedback(
+				context.Background(),
+				&Feedback{
+					ChannelId: "C1",
+					UserId:    tt.userId,
+					Input:     "hello",
+					Reply:     "Woof!",
+					Label:     tt.label,
+				},
+			)
+			if err != nil {
+				t.Errorf("RecordFeedback() error = %v, wantErr %v", err, false)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("RecordFeedback() requests = %v, want %v", requests, tt.wantRequests)
+			}
+		})
+	}
+}
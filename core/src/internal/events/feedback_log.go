@@ -0,0 +1,62 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// A FeedbackLog persists feedback as JSON
+// lines in a file for later curation of the
+// dialog corpus.
+type FeedbackLog struct {
+	mutex sync.Mutex
+	path  string
+}
+
+// NewFeedbackLog returns a new instance of
+// FeedbackLog writing to the file at the
+// given path.
+func NewFeedbackLog(path string) (*FeedbackLog, error) {
+	if path == "" {
+		return nil, errors.New("missing feedback log path")
+	}
+	return &FeedbackLog{
+		path: path,
+	}, nil
+}
+
+// RecordFeedback appends the given feedback
+// to the log.
+func (log *FeedbackLog) RecordFeedback(
+	ctx context.Context,
+	feedback *Feedback,
+) error {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	encoded, err := json.Marshal(feedback)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(log.path), 0755)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(
+		log.path,
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+		0644,
+	)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(encoded, '\n'))
+	return err
+}
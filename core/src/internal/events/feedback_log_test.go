@@ -0,0 +1,67 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewFeedbackLog(t *testing.T) {
+	_, err := NewFeedbackLog("")
+	if err == nil {
+		t.Errorf("NewFeedbackLog() error = %v, wantErr %v", err, true)
+	}
+}
+
+func TestFeedbackLog_RecordFeedback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "feedback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	path := filepath.Join(dir, "nested", "feedback.jsonl")
+
+	log, err := NewFeedbackLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, label := range []string{FeedbackPositive, FeedbackNegative} {
+		err = log.RecordFeedback(
+			context.Background(),
+			&Feedback{
+				ChannelId: "C1",
+				Input:     "hello",
+				Reply:     "Woof!",
+				Label:     label,
+			},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var labels []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var feedback Feedback
+		err = json.Unmarshal(scanner.Bytes(), &feedback)
+		if err != nil {
+			t.Fatal(err)
+		}
+		labels = append(labels, feedback.Label)
+	}
+	if len(labels) != 2 || labels[0] != FeedbackPositive || labels[1] != FeedbackNegative {
+		t.Errorf("RecordFeedback() labels = %v, want %v", labels, []string{FeedbackPositive, FeedbackNegative})
+	}
+}
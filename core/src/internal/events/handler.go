@@ -2,33 +2,95 @@ package events
 
 import (
 	"container/list"
+	"context"
 	"errors"
+	"fmt"
+	"github.com/drewnorman/jt-slackbot/core/internal/metrics"
+	"github.com/drewnorman/jt-slackbot/core/internal/responders"
 	"github.com/drewnorman/jt-slackbot/core/internal/slack"
+	"github.com/drewnorman/jt-slackbot/core/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"sync"
+	"time"
 )
 
 // A Handler manages Slack event processing.
 type Handler struct {
-	logger            *zap.Logger
-	processedQueue    *list.List
-	appMentionHandler eventHandler
+	logger             *zap.Logger
+	processedQueue     *list.List
+	processedQueueSize int
+	appMentionHandler  eventHandler
+	messageHandler     eventHandler
+	interactionHandler eventHandler
+	feedbackHandler    eventHandler
+	botFilter          *botFilter
+	retryPolicy        *retryPolicy
+	deadLetters        *DeadLetterStore
+	pendingEvents      *DeadLetterStore
+	eventTimeout       time.Duration
+	metrics            *metrics.Metrics
+	mutex              sync.RWMutex
+	abandonedMutex     sync.Mutex
+	abandoned          []string
+	processedMutex     sync.Mutex
 }
 
 // Parameters describe how to create a new
 // Handler instance.
 type Parameters struct {
-	Logger          *zap.Logger
-	SlackHttpClient *slack.HttpClient
+	Logger               *zap.Logger
+	SlackHttpClient      *slack.HttpClient
+	Responder            responders.Responder
+	HistoryLength        int
+	ConfidencePolicy     ConfidencePolicy
+	FeedbackSinks        []FeedbackSink
+	Trainer              Trainer
+	TrainerUserIds       []string
+	ResponseCache        ResponseCache
+	RecalledReplies      RecalledReplies
+	AdminUserIds         []string
+	PositiveReactions    []string
+	NegativeReactions    []string
+	BotUserId            string
+	BotId                string
+	IgnoreBots           bool
+	AllowedBotIds        []string
+	BotLoopThreshold     int
+	BotLoopWindow        time.Duration
+	SyncReplies          bool
+	SyncRepliesByChannel map[string]bool
+	MaxAttempts          int
+	RetryDelay           time.Duration
+	DeadLetters          *DeadLetterStore
+	PendingEvents        *DeadLetterStore
+	EventTimeout         time.Duration
+	ProcessedQueueSize   int
+	UserRateLimit        RateLimit
+	ChannelRateLimit     RateLimit
+	GlobalRateLimit      RateLimit
+	Metrics              *metrics.Metrics
 }
 
 // An eventHandler processes a single event.
 type eventHandler interface {
-	Process(eventData map[string]interface{}) error
+	Process(ctx context.Context, eventData map[string]interface{}) error
 }
 
+// defaultEventTimeout defines the duration
+// of time an event handler may take to
+// process a single event.
+const defaultEventTimeout = 30 * time.Second
+
+// tracer records spans for handled events.
+var tracer = otel.Tracer("github.com/drewnorman/jt-slackbot/core/internal/events")
+
 // processedQueueMaxLength defines the max
 // number of processed events to track at
-// any given time.
+// any given time if none is specified.
 const processedQueueMaxLength = 5
 
 // NewHandler returns a new Handler instance
@@ -40,19 +102,96 @@ func NewHandler(params *Parameters) (*Handler, error) {
 	if params.SlackHttpClient == nil {
 		return nil, errors.New("missing http client")
 	}
+	if params.Responder == nil {
+		return nil, errors.New("missing responder")
+	}
 	appMentionHandler, err := NewAppMentionHandler(
 		&AppMentionHandlerParameters{
-			Logger:          params.Logger,
-			SlackHttpClient: params.SlackHttpClient,
+			Logger:           params.Logger,
+			SlackHttpClient:  params.SlackHttpClient,
+			Responder:        params.Responder,
+			HistoryLength:    params.HistoryLength,
+			ConfidencePolicy: params.ConfidencePolicy,
+			Trainer:          params.Trainer,
+			TrainerUserIds:   params.TrainerUserIds,
+			ResponseCache:    params.ResponseCache,
+			RecalledReplies:  params.RecalledReplies,
+			AdminUserIds:     params.AdminUserIds,
+			UserRateLimit:    params.UserRateLimit,
+			ChannelRateLimit: params.ChannelRateLimit,
+			GlobalRateLimit:  params.GlobalRateLimit,
+			Metrics:          params.Metrics,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	messageHandler, err := NewMessageHandler(
+		&MessageHandlerParameters{
+			Logger:               params.Logger,
+			ReplySyncer:          appMentionHandler,
+			SyncReplies:          params.SyncReplies,
+			SyncRepliesByChannel: params.SyncRepliesByChannel,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	interactionHandler, err := NewInteractionHandler(
+		&InteractionHandlerParameters{
+			Logger:             params.Logger,
+			SuggestionAnswerer: appMentionHandler,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	feedbackHandler, err := NewFeedbackHandler(
+		&FeedbackHandlerParameters{
+			Logger:            params.Logger,
+			ReplyLookup:       appMentionHandler,
+			Sinks:             params.FeedbackSinks,
+			PositiveReactions: params.PositiveReactions,
+			NegativeReactions: params.NegativeReactions,
 		},
 	)
 	if err != nil {
 		return nil, err
 	}
+	eventTimeout := defaultEventTimeout
+	if params.EventTimeout > 0 {
+		eventTimeout = params.EventTimeout
+	}
+	processedQueueSize := processedQueueMaxLength
+	if params.ProcessedQueueSize > 0 {
+		processedQueueSize = params.ProcessedQueueSize
+	}
 	return &Handler{
-		logger:            params.Logger,
-		processedQueue:    list.New(),
-		appMentionHandler: appMentionHandler,
+		logger:             params.Logger,
+		processedQueue:     list.New(),
+		processedQueueSize: processedQueueSize,
+		appMentionHandler:  appMentionHandler,
+		messageHandler:     messageHandler,
+		interactionHandler: interactionHandler,
+		feedbackHandler:    feedbackHandler,
+		botFilter: newBotFilter(
+			&botFilterParameters{
+				botUserId:     params.BotUserId,
+				botId:         params.BotId,
+				ignoreBots:    params.IgnoreBots,
+				allowedBotIds: params.AllowedBotIds,
+				loopThreshold: params.BotLoopThreshold,
+				loopWindow:    params.BotLoopWindow,
+			},
+		),
+		retryPolicy: newRetryPolicy(
+			params.MaxAttempts,
+			params.RetryDelay,
+		),
+		deadLetters:   params.DeadLetters,
+		pendingEvents: params.PendingEvents,
+		eventTimeout:  eventTimeout,
+		metrics:       params.Metrics,
 	}, nil
 }
 
@@ -60,55 +199,460 @@ func NewHandler(params *Parameters) (*Handler, error) {
 // from the events channel to the appropriate
 // event handler based on the event type and
 // ensures events that are known to have already
-// been processed are not reprocessed.
+// been processed are not reprocessed. Events
+// sent by the app itself or by filtered bots
+// are dropped. Events are processed with the
+// given context, and once it is done, the
+// events still arriving or being processed
+// are abandoned rather than dead-lettered.
 func (handler *Handler) Process(
+	ctx context.Context,
 	events chan map[string]interface{},
 	complete chan struct{},
 ) {
 	defer close(complete)
 	for event := range events {
-		eventId, ok := event["event_id"].(string)
-		if !ok {
-			handler.logger.Warn("failed to retrieve event id")
-			continue
+		handler.handle(ctx, event)
+	}
+}
+
+// handle processes a single event from the
+// events channel with the current settings
+// of the Handler.
+func (handler *Handler) handle(
+	ctx context.Context,
+	event map[string]interface{},
+) {
+	handler.mutex.RLock()
+	defer handler.mutex.RUnlock()
+
+	ctx, span := tracer.Start(
+		tracing.Extract(ctx, event),
+		"events handle",
+		trace.WithAttributes(
+			attribute.String("event.type", eventTypeOf(event)),
+		),
+	)
+	defer span.End()
+
+	eventId, ok := eventIdOf(event)
+	if !ok {
+		handler.logger.Warn("failed to retrieve event id")
+		span.SetStatus(codes.Error, "missing event id")
+		return
+	}
+	span.SetAttributes(attribute.String("event.id", eventId))
+	if ctx.Err() != nil {
+		handler.abandon(eventId, event)
+		handler.record(span, eventTypeOf(event), metrics.OutcomeAbandoned, 0)
+		return
+	}
+	if handler.hasAlreadyProcessed(eventId) {
+		handler.logger.Debug(
+			"already processed event",
+			zap.String("eventId", eventId),
+		)
+		handler.record(span, eventTypeOf(event), metrics.OutcomeDuplicate, 0)
+		return
+	}
+
+	eventData, ok := eventDataOf(event)
+	if !ok {
+		handler.logger.Warn("failed to retrieve event data")
+		span.SetStatus(codes.Error, "missing event data")
+		return
+	}
+
+	handler.processed(eventId)
+	eventType, _ := eventData["type"].(string)
+
+	ignore, reason := handler.botFilter.shouldIgnore(event)
+	if ignore {
+		handler.logger.Debug(
+			"ignoring event from bot",
+			zap.String("eventId", eventId),
+			zap.String("reason", reason),
+		)
+		handler.record(span, eventType, metrics.OutcomeIgnored, 0)
+		return
+	}
+
+	start := time.Now()
+	attempt := 0
+	attempts, err := handler.retryPolicy.run(
+		ctx,
+		func() error {
+			attempt++
+			return handler.invoke(
+				withAttempt(ctx, attempt),
+				eventId,
+				event,
+				eventData,
+			)
+		},
+	)
+	span.SetAttributes(attribute.Int("event.attempts", attempts))
+	if err != nil && ctx.Err() != nil {
+		handler.abandon(eventId, event)
+		handler.record(span, eventType, metrics.OutcomeAbandoned, 0)
+		return
+	}
+	if err == nil {
+		handler.record(span, eventType, metrics.OutcomeSucceeded, time.Since(start))
+		return
+	}
+	handler.record(span, eventType, metrics.OutcomeFailed, time.Since(start))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	handler.logger.Error(
+		"failed to process event",
+		zap.String("err", err.Error()),
+		zap.String("eventId", eventId),
+		zap.Int("attempts", attempts),
+	)
+	handler.deadLetter(eventId, event, err, attempts)
+}
+
+// record records the outcome of handling an
+// event of the given type in the metrics and
+// on the given span.
+func (handler *Handler) record(
+	span trace.Span,
+	eventType string,
+	outcome string,
+	duration time.Duration,
+) {
+	handler.metrics.EventHandled(eventType, outcome, duration)
+	span.SetAttributes(attribute.String("event.outcome", outcome))
+}
+
+// Reconfigure replaces the settings, event
+// handlers and responder of the Handler with
+// ones created from the given parameters,
+// starting with the next event. Replies that
+// were already sent are still tracked so that
+// feedback on them is recorded, threads
+// suspected of bot loops stay suspected, and
+// rate limits only start over if they changed.
+func (handler *Handler) Reconfigure(params *Parameters) error {
+	next, err := NewHandler(params)
+	if err != nil {
+		return err
+	}
+
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	previous, ok := handler.appMentionHandler.(*AppMentionHandler)
+	if ok {
+		nextAppMentionHandler := next.appMentionHandler.(*AppMentionHandler)
+		nextAppMentionHandler.replies = previous.replies
+		if previous.rateLimiter.sameLimits(nextAppMentionHandler.rateLimiter) {
+			nextAppMentionHandler.rateLimiter = previous.rateLimiter
 		}
-		if handler.hasAlreadyProcessed(eventId) {
-			handler.logger.Debug(
-				"already processed event",
-				zap.String("eventId", eventId),
+	}
+	if handler.botFilter != nil {
+		next.botFilter.threads = handler.botFilter.threads
+	}
+	handler.logger = next.logger
+	handler.appMentionHandler = next.appMentionHandler
+	handler.messageHandler = next.messageHandler
+	handler.interactionHandler = next.interactionHandler
+	handler.feedbackHandler = next.feedbackHandler
+	handler.botFilter = next.botFilter
+	handler.retryPolicy = next.retryPolicy
+	handler.deadLetters = next.deadLetters
+	handler.pendingEvents = next.pendingEvents
+	handler.eventTimeout = next.eventTimeout
+	handler.metrics = next.metrics
+
+	handler.processedMutex.Lock()
+	handler.processedQueueSize = next.processedQueueSize
+	handler.processedMutex.Unlock()
+	return nil
+}
+
+// Abandoned returns the IDs of the events that
+// were abandoned because processing stopped
+// before they could be processed.
+func (handler *Handler) Abandoned() []string {
+	handler.abandonedMutex.Lock()
+	defer handler.abandonedMutex.Unlock()
+	abandoned := make([]string, len(handler.abandoned))
+	copy(abandoned, handler.abandoned)
+	return abandoned
+}
+
+// Processed returns the IDs of the events
+// most recently processed, newest first, that
+// are used to ignore duplicate deliveries.
+func (handler *Handler) Processed() []string {
+	handler.processedMutex.Lock()
+	defer handler.processedMutex.Unlock()
+	processed := make([]string, 0, handler.processedQueue.Len())
+	for id := handler.processedQueue.Front(); id != nil; id = id.Next() {
+		processed = append(processed, id.Value.(string))
+	}
+	return processed
+}
+
+// Replay processes the given dead letters
+// again and returns the dead letters that
+// still could not be processed.
+func (handler *Handler) Replay(
+	ctx context.Context,
+	letters []DeadLetter,
+) []DeadLetter {
+	handler.mutex.RLock()
+	defer handler.mutex.RUnlock()
+
+	var remaining []DeadLetter
+	for _, letter := range letters {
+		eventData, ok := eventDataOf(letter.Event)
+		if !ok {
+			handler.logger.Warn(
+				"failed to retrieve dead letter event data",
+				zap.String("eventId", letter.EventId),
 			)
+			remaining = append(remaining, letter)
 			continue
 		}
 
-		eventData, ok := (event["event"]).(map[string]interface{})
-		if !ok {
-			handler.logger.Warn("failed to retrieve event data")
+		attempts, err := handler.retryPolicy.run(
+			ctx,
+			func() error {
+				return handler.invoke(ctx, letter.EventId, letter.Event, eventData)
+			},
+		)
+		if err != nil {
+			handler.logger.Warn(
+				"failed to replay dead letter",
+				zap.String("err", err.Error()),
+				zap.String("eventId", letter.EventId),
+			)
+			letter.Error = err.Error()
+			letter.Attempts += attempts
+			letter.FailedAt = time.Now().UTC()
+			remaining = append(remaining, letter)
 			continue
 		}
+		handler.logger.Info(
+			"replayed dead letter",
+			zap.String("eventId", letter.EventId),
+		)
+	}
+	return remaining
+}
 
-		handler.processed(eventId)
-
-		switch eventData["type"] {
-		case "app_mention":
-			err := handler.appMentionHandler.Process(event)
-			if err != nil {
-				handler.logger.Error(
-					"failed to process app mention event",
-					zap.String("err", err.Error()),
-					zap.String("eventId", eventId),
-				)
-				break
+// invoke dispatches the given event with a
+// deadline, recovering from any panic raised
+// while processing it so that a single bad
+// payload cannot stop event handling.
+func (handler *Handler) invoke(
+	ctx context.Context,
+	eventId string,
+	event map[string]interface{},
+	eventData map[string]interface{},
+) error {
+	ctx, cancel := context.WithTimeout(ctx, handler.eventTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
 			}
-			continue
-		default:
-			handler.logger.Debug(
-				"skipping processing of unrecognized event",
+			eventType, _ := eventData["type"].(string)
+			handler.logger.Error(
+				"recovered from panic while processing event",
 				zap.String("eventId", eventId),
-				zap.String("eventType", eventData["type"].(string)),
+				zap.String("eventType", eventType),
+				zap.String("panic", fmt.Sprint(recovered)),
+				zap.Any("event", event),
+				zap.Stack("stack"),
 			)
-			continue
+			done <- fmt.Errorf("panic while processing event: %v", recovered)
+		}()
+		done <- handler.dispatch(ctx, eventId, event, eventData)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// The attempt may still be running, so it
+		// must not be retried alongside itself.
+		eventType, _ := eventData["type"].(string)
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			handler.logger.Warn(
+				"cancelled processing event",
+				zap.String("eventId", eventId),
+				zap.String("eventType", eventType),
+			)
+			return final(ctx.Err())
+		}
+		handler.logger.Error(
+			"timed out processing event",
+			zap.String("eventId", eventId),
+			zap.String("eventType", eventType),
+			zap.Duration("timeout", handler.eventTimeout),
+			zap.Any("event", event),
+		)
+		return final(
+			fmt.Errorf(
+				"timed out processing event after %s",
+				handler.eventTimeout,
+			),
+		)
+	}
+}
+
+// dispatch delegates the given event to the
+// appropriate event handler based on its type.
+func (handler *Handler) dispatch(
+	ctx context.Context,
+	eventId string,
+	event map[string]interface{},
+	eventData map[string]interface{},
+) error {
+	eventType, _ := eventData["type"].(string)
+	switch eventType {
+	case "app_mention":
+		err := handler.appMentionHandler.Process(ctx, event)
+		if err != nil {
+			return fmt.Errorf("failed to process app mention event: %w", err)
+		}
+	case "message":
+		err := handler.messageHandler.Process(ctx, event)
+		if err != nil {
+			return fmt.Errorf("failed to process message event: %w", err)
+		}
+	case "reaction_added":
+		err := handler.feedbackHandler.Process(ctx, event)
+		if err != nil {
+			return fmt.Errorf("failed to process reaction added event: %w", err)
 		}
-		break
+	case interactionTypeBlockActions:
+		err := handler.interactionHandler.Process(ctx, event)
+		if err != nil {
+			return fmt.Errorf("failed to process block actions: %w", err)
+		}
+	default:
+		handler.logger.Debug(
+			"skipping processing of unrecognized event",
+			zap.String("eventId", eventId),
+			zap.String("eventType", eventType),
+		)
+	}
+	return nil
+}
+
+// eventIdOf returns the ID of the given event.
+// Interactions have no event ID, so their
+// trigger ID is used instead.
+func eventIdOf(event map[string]interface{}) (string, bool) {
+	if eventType, _ := event["type"].(string); eventType == interactionTypeBlockActions {
+		triggerId, ok := event["trigger_id"].(string)
+		return triggerId, ok
+	}
+	eventId, ok := event["event_id"].(string)
+	return eventId, ok
+}
+
+// eventDataOf returns the data describing the
+// given event. Interactions are described by
+// the payload itself rather than an inner event.
+func eventDataOf(
+	event map[string]interface{},
+) (map[string]interface{}, bool) {
+	if eventType, _ := event["type"].(string); eventType == interactionTypeBlockActions {
+		return event, true
+	}
+	eventData, ok := event["event"].(map[string]interface{})
+	return eventData, ok
+}
+
+// eventTypeOf returns the type of the given
+// event, or an empty string if it has none.
+func eventTypeOf(event map[string]interface{}) string {
+	eventData, ok := eventDataOf(event)
+	if !ok {
+		return ""
+	}
+	eventType, _ := eventData["type"].(string)
+	return eventType
+}
+
+// deadLetter writes the given event to the
+// dead letter store, if one is configured.
+func (handler *Handler) deadLetter(
+	eventId string,
+	event map[string]interface{},
+	processErr error,
+	attempts int,
+) {
+	if handler.deadLetters == nil {
+		return
+	}
+	err := handler.deadLetters.Add(
+		DeadLetter{
+			EventId:   eventId,
+			EventType: eventTypeOf(event),
+			Error:     processErr.Error(),
+			Attempts:  attempts,
+			FailedAt:  time.Now().UTC(),
+			Event:     event,
+		},
+	)
+	if err != nil {
+		handler.logger.Error(
+			"failed to write dead letter",
+			zap.String("err", err.Error()),
+			zap.String("eventId", eventId),
+		)
+		return
+	}
+	handler.logger.Info(
+		"wrote dead letter",
+		zap.String("eventId", eventId),
+	)
+}
+
+// abandon records that the given event was not
+// processed and saves it with the pending events,
+// if any, so it can be processed on the next start.
+func (handler *Handler) abandon(
+	eventId string,
+	event map[string]interface{},
+) {
+	handler.abandonedMutex.Lock()
+	handler.abandoned = append(handler.abandoned, eventId)
+	handler.abandonedMutex.Unlock()
+
+	handler.logger.Warn(
+		"abandoned event",
+		zap.String("eventId", eventId),
+	)
+	if handler.pendingEvents == nil {
+		return
+	}
+	err := handler.pendingEvents.Add(
+		DeadLetter{
+			EventId:   eventId,
+			EventType: eventTypeOf(event),
+			Error:     "abandoned during shutdown",
+			FailedAt:  time.Now().UTC(),
+			Event:     event,
+		},
+	)
+	if err != nil {
+		handler.logger.Error(
+			"failed to save pending event",
+			zap.String("err", err.Error()),
+			zap.String("eventId", eventId),
+		)
 	}
 }
 
@@ -118,6 +662,8 @@ func (handler *Handler) Process(
 func (handler *Handler) hasAlreadyProcessed(
 	eventId string,
 ) bool {
+	handler.processedMutex.Lock()
+	defer handler.processedMutex.Unlock()
 	for id := handler.processedQueue.Front(); id != nil; id = id.Next() {
 		if eventId == id.Value {
 			return true
@@ -129,12 +675,18 @@ func (handler *Handler) hasAlreadyProcessed(
 // processed adds the event to the front
 // of the queue of already-processed events
 // and removes the last event if the queue
-// size is greater than processedQueueMaxLength
+// size is greater than its max size
 func (handler *Handler) processed(
 	eventId string,
 ) {
+	handler.processedMutex.Lock()
+	defer handler.processedMutex.Unlock()
 	handler.processedQueue.PushFront(eventId)
-	if handler.processedQueue.Len() > processedQueueMaxLength {
+	maxLength := handler.processedQueueSize
+	if maxLength < 1 {
+		maxLength = processedQueueMaxLength
+	}
+	for handler.processedQueue.Len() > maxLength {
 		handler.processedQueue.Remove(handler.processedQueue.Back())
 	}
 }
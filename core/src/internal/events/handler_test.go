@@ -3,15 +3,20 @@ package events
 import (
 	"bytes"
 	"container/list"
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/brianvoe/gofakeit/v6"
+	"github.com/drewnorman/jt-slackbot/core/internal/responders"
 	"github.com/drewnorman/jt-slackbot/core/internal/slack"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -88,12 +93,35 @@ func fakeSlackHttpClient(
 	return slackHttpClient
 }
 
+type fakeResponder struct {
+	respond func(request *responders.Request) (*responders.Response, error)
+}
+
+func (responder *fakeResponder) Respond(
+	ctx context.Context,
+	request *responders.Request,
+) (*responders.Response, error) {
+	return responder.respond(request)
+}
+
+func fakeStaticResponder(text string) *fakeResponder {
+	return &fakeResponder{
+		respond: func(request *responders.Request) (*responders.Response, error) {
+			return &responders.Response{
+				Text:       text,
+				Confidence: 1,
+			}, nil
+		},
+	}
+}
+
 type genericAppMentionHandler struct {
 	Processed bool
 	process   func(eventData map[string]interface{}) error
 }
 
 func (handler *genericAppMentionHandler) Process(
+	ctx context.Context,
 	eventData map[string]interface{},
 ) error {
 	err := handler.process(eventData)
@@ -131,6 +159,7 @@ func TestNewHandler(t *testing.T) {
 						t,
 						map[string]interface{}{},
 					),
+					Responder: fakeStaticResponder(gofakeit.LoremIpsumSentence(5)),
 				},
 			},
 			wantErr: false,
@@ -143,6 +172,7 @@ func TestNewHandler(t *testing.T) {
 						t,
 						map[string]interface{}{},
 					),
+					Responder: fakeStaticResponder(gofakeit.LoremIpsumSentence(5)),
 				},
 			},
 			wantErr: true,
@@ -153,6 +183,20 @@ func TestNewHandler(t *testing.T) {
 				params: &Parameters{
 					Logger:          fakeZapLogger(),
 					SlackHttpClient: nil,
+					Responder:       fakeStaticResponder(gofakeit.LoremIpsumSentence(5)),
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingResponder",
+			args: args{
+				params: &Parameters{
+					Logger: fakeZapLogger(),
+					SlackHttpClient: fakeSlackHttpClient(
+						t,
+						map[string]interface{}{},
+					),
 				},
 			},
 			wantErr: true,
@@ -204,7 +248,7 @@ func TestHandler_Process(t *testing.T) {
 			},
 		},
 		{
-			name: "ReturnsWithAppMentionError",
+			name: "ContinuesAfterAppMentionError",
 			args: args{
 				events:   make(chan map[string]interface{}),
 				complete: make(chan struct{}),
@@ -215,8 +259,13 @@ func TestHandler_Process(t *testing.T) {
 							"type": "app_mention",
 						},
 					},
+					{
+						"event_id": gofakeit.UUID(),
+						"event": map[string]interface{}{
+							"type": "app_mention",
+						},
+					},
 				},
-				leaveEventsChannelOpenAfterWrite: true,
 				checkIfAppMentionProcessed:       true,
 				appMentionShouldNotHaveProcessed: true,
 				appMentionHandler: fakeAppMentionHandler(
@@ -304,9 +353,12 @@ func TestHandler_Process(t *testing.T) {
 				logger:            fakeZapLogger(),
 				processedQueue:    list.New(),
 				appMentionHandler: tt.args.appMentionHandler,
+				botFilter:         newBotFilter(&botFilterParameters{}),
+				retryPolicy:       newRetryPolicy(1, time.Millisecond),
+				eventTimeout:      defaultEventTimeout,
 			}
 
-			go handler.Process(tt.args.events, tt.args.complete)
+			go handler.Process(context.Background(), tt.args.events, tt.args.complete)
 
 			for _, event := range tt.args.fakeEvents {
 				tt.args.events <- event
@@ -338,3 +390,511 @@ func TestHandler_Process(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_ProcessDeadLettersFailedEvents(t *testing.T) {
+	store := fakeDeadLetterStore(t)
+	attempts := 0
+	handler := &Handler{
+		logger:         fakeZapLogger(),
+		processedQueue: list.New(),
+		appMentionHandler: fakeAppMentionHandler(
+			func(eventData map[string]interface{}) error {
+				attempts++
+				return &fakeTemporaryError{temporary: true}
+			},
+		),
+		botFilter:    newBotFilter(&botFilterParameters{}),
+		retryPolicy:  newRetryPolicy(2, time.Millisecond),
+		deadLetters:  store,
+		eventTimeout: defaultEventTimeout,
+	}
+
+	events := make(chan map[string]interface{})
+	complete := make(chan struct{})
+	go handler.Process(context.Background(), events, complete)
+
+	eventId := gofakeit.UUID()
+	events <- map[string]interface{}{
+		"event_id": eventId,
+		"event": map[string]interface{}{
+			"type": "app_mention",
+		},
+	}
+	close(events)
+	<-complete
+
+	if attempts != 2 {
+		t.Errorf("Process() attempts = %v, want %v", attempts, 2)
+	}
+	letters, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 || letters[0].EventId != eventId || letters[0].Attempts != 2 {
+		t.Errorf("Process() dead letters = %v, want %v", letters, eventId)
+	}
+}
+
+type fallibleRoundTripper func(req *http.Request) (*http.Response, error)
+
+func (handler fallibleRoundTripper) RoundTrip(
+	req *http.Request,
+) (*http.Response, error) {
+	return handler(req)
+}
+
+func TestHandler_ProcessDoesNotRepeatReplies(t *testing.T) {
+	transportError := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("fake reset")}
+	tests := []struct {
+		name           string
+		failedMethod   string
+		responderFails bool
+		wantResponses  int
+	}{
+		{
+			name:          "PostFailsAfterSending",
+			failedMethod:  "chat.postMessage",
+			wantResponses: 1,
+		},
+		{
+			name:          "ReactionFailsAfterPost",
+			failedMethod:  "reactions.add",
+			wantResponses: 1,
+		},
+		{
+			name:           "RetriesResponderFailures",
+			responderFails: true,
+			wantResponses:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := make(map[string]int)
+			slackHttpClient, err := slack.NewHttpClient(
+				&slack.HttpClientParameters{
+					Logger:   fakeZapLogger(),
+					ApiUrl:   "https://slack.test/api/",
+					AppToken: gofakeit.UUID(),
+					BotToken: gofakeit.UUID(),
+					HttpClient: &http.Client{
+						Transport: fallibleRoundTripper(
+							func(req *http.Request) (*http.Response, error) {
+								method := strings.TrimPrefix(req.URL.Path, "/api/")
+								calls[method]++
+								if method == tt.failedMethod {
+									return nil, transportError
+								}
+								header := http.Header{}
+								header.Add("Content-Type", "application/json")
+								return &http.Response{
+									StatusCode: 200,
+									Header:     header,
+									Body: ioutil.NopCloser(
+										bytes.NewBufferString(`{"ok": true, "ts": "1610000000.000200"}`),
+									),
+								}, nil
+							},
+						),
+					},
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			responses := 0
+			handler, err := NewHandler(
+				&Parameters{
+					Logger:          fakeZapLogger(),
+					SlackHttpClient: slackHttpClient,
+					Responder: &fakeResponder{
+						respond: func(request *responders.Request) (*responders.Response, error) {
+							responses++
+							if tt.responderFails && responses == 1 {
+								return nil, transportError
+							}
+							return &responders.Response{
+								Text:       "Woof!",
+								Confidence: 1,
+								Reactions:  []string{"dog"},
+							}, nil
+						},
+					},
+					MaxAttempts:   3,
+					RetryDelay:    time.Millisecond,
+					UserRateLimit: RateLimit{Count: 1, Period: time.Minute},
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			events := make(chan map[string]interface{})
+			complete := make(chan struct{})
+			go handler.Process(context.Background(), events, complete)
+			events <- fakeAppMentionEvent("hello")
+			close(events)
+			<-complete
+
+			if responses != tt.wantResponses {
+				t.Errorf("Process() responses = %v, want %v", responses, tt.wantResponses)
+			}
+			if calls["chat.postMessage"] != 1 {
+				t.Errorf("Process() posts = %v, want %v", calls["chat.postMessage"], 1)
+			}
+			if calls["chat.postEphemeral"] != 0 {
+				t.Errorf("Process() notices = %v, want %v", calls["chat.postEphemeral"], 0)
+			}
+		})
+	}
+}
+
+func TestHandler_ProcessAbandonsEventsWhenCancelled(t *testing.T) {
+	deadLetters := fakeDeadLetterStore(t)
+	pending := fakeDeadLetterStore(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := &Handler{
+		logger:         fakeZapLogger(),
+		processedQueue: list.New(),
+		appMentionHandler: fakeAppMentionHandler(
+			func(eventData map[string]interface{}) error {
+				started <- struct{}{}
+				<-release
+				return nil
+			},
+		),
+		botFilter:     newBotFilter(&botFilterParameters{}),
+		retryPolicy:   newRetryPolicy(2, time.Millisecond),
+		deadLetters:   deadLetters,
+		pendingEvents: pending,
+		eventTimeout:  defaultEventTimeout,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan map[string]interface{})
+	complete := make(chan struct{})
+	go handler.Process(ctx, events, complete)
+
+	inFlight := gofakeit.UUID()
+	events <- map[string]interface{}{
+		"event_id": inFlight,
+		"event": map[string]interface{}{
+			"type": "app_mention",
+		},
+	}
+	<-started
+	cancel()
+
+	queued := gofakeit.UUID()
+	events <- map[string]interface{}{
+		"event_id": queued,
+		"event": map[string]interface{}{
+			"type": "app_mention",
+		},
+	}
+	close(events)
+	<-complete
+
+	abandoned := handler.Abandoned()
+	if len(abandoned) != 2 || abandoned[0] != inFlight || abandoned[1] != queued {
+		t.Errorf("Abandoned() = %v, want %v", abandoned, []string{inFlight, queued})
+	}
+	letters, err := pending.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 2 || letters[0].EventType != "app_mention" {
+		t.Errorf("Process() pending events = %v, want %v", letters, abandoned)
+	}
+	letters, err = deadLetters.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 0 {
+		t.Errorf("Process() dead letters = %v, want none", letters)
+	}
+}
+
+func TestHandler_Replay(t *testing.T) {
+	failing := gofakeit.UUID()
+	handler := &Handler{
+		logger:         fakeZapLogger(),
+		processedQueue: list.New(),
+		appMentionHandler: fakeAppMentionHandler(
+			func(eventData map[string]interface{}) error {
+				if eventData["event_id"] == failing {
+					return errors.New("fake app mention event handler error")
+				}
+				return nil
+			},
+		),
+		botFilter:    newBotFilter(&botFilterParameters{}),
+		retryPolicy:  newRetryPolicy(1, time.Millisecond),
+		eventTimeout: defaultEventTimeout,
+	}
+
+	letters := []DeadLetter{
+		{
+			EventId:  gofakeit.UUID(),
+			Attempts: 1,
+			Event: map[string]interface{}{
+				"event_id": gofakeit.UUID(),
+				"event": map[string]interface{}{
+					"type": "app_mention",
+				},
+			},
+		},
+		{
+			EventId:  failing,
+			Attempts: 1,
+			Event: map[string]interface{}{
+				"event_id": failing,
+				"event": map[string]interface{}{
+					"type": "app_mention",
+				},
+			},
+		},
+	}
+
+	remaining := handler.Replay(context.Background(), letters)
+	if len(remaining) != 1 || remaining[0].EventId != failing || remaining[0].Attempts != 2 {
+		t.Errorf("Replay() = %v, want %v", remaining, failing)
+	}
+}
+
+func TestHandler_Processed(t *testing.T) {
+	handler := &Handler{
+		logger:         fakeZapLogger(),
+		processedQueue: list.New(),
+	}
+
+	var want []string
+	for i := 0; i < processedQueueMaxLength+2; i++ {
+		eventId := gofakeit.UUID()
+		handler.processed(eventId)
+		want = append([]string{eventId}, want...)
+	}
+	want = want[:processedQueueMaxLength]
+
+	if got := handler.Processed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Processed() = %v, want %v", got, want)
+	}
+}
+
+func TestHandler_Reconfigure(t *testing.T) {
+	type args struct {
+		params *Parameters
+	}
+	tests := []struct {
+		name                string
+		args                args
+		wantMaxAttempts     int
+		wantRateLimiterKept bool
+		wantErr             bool
+	}{
+		{
+			name: "ReplacesSettings",
+			args: args{
+				params: &Parameters{
+					Logger: fakeZapLogger(),
+					SlackHttpClient: fakeSlackHttpClient(
+						t,
+						map[string]interface{}{},
+					),
+					Responder:     fakeStaticResponder(gofakeit.LoremIpsumSentence(5)),
+					MaxAttempts:   7,
+					UserRateLimit: RateLimit{Count: 2, Period: time.Minute},
+				},
+			},
+			wantMaxAttempts:     7,
+			wantRateLimiterKept: true,
+			wantErr:             false,
+		},
+		{
+			name: "RestartsChangedRateLimits",
+			args: args{
+				params: &Parameters{
+					Logger: fakeZapLogger(),
+					SlackHttpClient: fakeSlackHttpClient(
+						t,
+						map[string]interface{}{},
+					),
+					Responder:     fakeStaticResponder(gofakeit.LoremIpsumSentence(5)),
+					MaxAttempts:   2,
+					UserRateLimit: RateLimit{Count: 5, Period: time.Minute},
+				},
+			},
+			wantMaxAttempts:     2,
+			wantRateLimiterKept: false,
+			wantErr:             false,
+		},
+		{
+			name: "KeepsSettingsWhenInvalid",
+			args: args{
+				params: &Parameters{
+					Logger: fakeZapLogger(),
+					SlackHttpClient: fakeSlackHttpClient(
+						t,
+						map[string]interface{}{},
+					),
+					MaxAttempts: 7,
+				},
+			},
+			wantMaxAttempts:     2,
+			wantRateLimiterKept: true,
+			wantErr:             true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := NewHandler(
+				&Parameters{
+					Logger: fakeZapLogger(),
+					SlackHttpClient: fakeSlackHttpClient(
+						t,
+						map[string]interface{}{},
+					),
+					Responder:     fakeStaticResponder(gofakeit.LoremIpsumSentence(5)),
+					MaxAttempts:   2,
+					UserRateLimit: RateLimit{Count: 2, Period: time.Minute},
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			replies := handler.appMentionHandler.(*AppMentionHandler).replies
+			rateLimiter := handler.appMentionHandler.(*AppMentionHandler).rateLimiter
+
+			err = handler.Reconfigure(tt.args.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Reconfigure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if handler.retryPolicy.maxAttempts != tt.wantMaxAttempts {
+				t.Errorf("Reconfigure() = %v, want %v", handler.retryPolicy.maxAttempts, tt.wantMaxAttempts)
+			}
+			if handler.appMentionHandler.(*AppMentionHandler).replies != replies {
+				t.Errorf("Reconfigure() error = %v, wantErr %v", errors.New("tracked replies were replaced"), false)
+			}
+			kept := handler.appMentionHandler.(*AppMentionHandler).rateLimiter == rateLimiter
+			if kept != tt.wantRateLimiterKept {
+				t.Errorf("Reconfigure() rate limiter kept = %v, want %v", kept, tt.wantRateLimiterKept)
+			}
+		})
+	}
+}
+
+func TestHandler_Invoke(t *testing.T) {
+	tests := []struct {
+		name          string
+		process       func(eventData map[string]interface{}) error
+		wantTransient bool
+		wantErr       bool
+	}{
+		{
+			name: "ReturnsHandlerResult",
+			process: func(eventData map[string]interface{}) error {
+				return nil
+			},
+			wantErr: false,
+		},
+		{
+			name: "RecoversFromPanic",
+			process: func(eventData map[string]interface{}) error {
+				authorizations := eventData["authorizations"].([]interface{})
+				_ = authorizations[0]
+				return nil
+			},
+			wantErr: true,
+		},
+		{
+			name: "TimesOut",
+			process: func(eventData map[string]interface{}) error {
+				time.Sleep(50 * time.Millisecond)
+				return nil
+			},
+			wantErr: true,
+		},
+		{
+			name: "ReturnsTransientErrors",
+			process: func(eventData map[string]interface{}) error {
+				return &fakeTemporaryError{temporary: true}
+			},
+			wantTransient: true,
+			wantErr:       true,
+		},
+		{
+			name: "DoesNotRetryWhileTimedOutAttemptRuns",
+			process: func(eventData map[string]interface{}) error {
+				time.Sleep(50 * time.Millisecond)
+				return &fakeTemporaryError{temporary: true}
+			},
+			wantTransient: false,
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &Handler{
+				logger:            fakeZapLogger(),
+				processedQueue:    list.New(),
+				appMentionHandler: fakeAppMentionHandler(tt.process),
+				botFilter:         newBotFilter(&botFilterParameters{}),
+				retryPolicy:       newRetryPolicy(1, time.Millisecond),
+				eventTimeout:      10 * time.Millisecond,
+			}
+			eventData := map[string]interface{}{
+				"type": "app_mention",
+			}
+			err := handler.invoke(
+				context.Background(),
+				gofakeit.UUID(),
+				map[string]interface{}{
+					"event": eventData,
+				},
+				eventData,
+			)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("invoke() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && isTransient(err) != tt.wantTransient {
+				t.Errorf("invoke() transient = %v, want %v", isTransient(err), tt.wantTransient)
+			}
+		})
+	}
+}
+
+func TestEventIdOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		event  map[string]interface{}
+		want   string
+		wantOk bool
+	}{
+		{
+			name:   "Event",
+			event:  map[string]interface{}{"event_id": "Ev1"},
+			want:   "Ev1",
+			wantOk: true,
+		},
+		{
+			name:   "Interaction",
+			event:  fakeBlockActionsPayload(suggestionActionId+"_0", "0", "hello"),
+			want:   "1234.5678",
+			wantOk: true,
+		},
+		{
+			name:   "MissingId",
+			event:  map[string]interface{}{},
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := eventIdOf(tt.event)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("eventIdOf() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"strings"
+)
+
+// interactionTypeBlockActions is the type of
+// interaction payloads sent when a user clicks
+// a button in a message.
+const interactionTypeBlockActions = "block_actions"
+
+// A SuggestionAnswerer responds to suggestions
+// chosen by users.
+type SuggestionAnswerer interface {
+	AnswerSuggestion(
+		ctx context.Context,
+		channelId string,
+		messageTs string,
+		threadTs string,
+		userId string,
+		index int,
+		label string,
+	) error
+}
+
+// An InteractionHandler processes interactions
+// with the messages of the app.
+type InteractionHandler struct {
+	logger             *zap.Logger
+	suggestionAnswerer SuggestionAnswerer
+}
+
+// InteractionHandlerParameters describe how
+// to create a new InteractionHandler.
+type InteractionHandlerParameters struct {
+	Logger             *zap.Logger
+	SuggestionAnswerer SuggestionAnswerer
+}
+
+// NewInteractionHandler returns a new instance
+// of InteractionHandler according to the given
+// parameters.
+func NewInteractionHandler(
+	params *InteractionHandlerParameters,
+) (*InteractionHandler, error) {
+	if params.Logger == nil {
+		return nil, errors.New("missing logger")
+	}
+	if params.SuggestionAnswerer == nil {
+		return nil, errors.New("missing suggestion answerer")
+	}
+	return &InteractionHandler{
+		logger:             params.Logger,
+		suggestionAnswerer: params.SuggestionAnswerer,
+	}, nil
+}
+
+// Process processes the given block actions
+// payload, answering any suggestion chosen.
+// Other actions are ignored.
+func (handler *InteractionHandler) Process(
+	ctx context.Context,
+	payload map[string]interface{},
+) error {
+	actions, ok := payload["actions"].([]interface{})
+	if !ok || len(actions) == 0 {
+		return fmt.Errorf("failed to determine actions from payload %v", payload)
+	}
+	action, ok := actions[0].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("failed to determine action from payload %v", payload)
+	}
+	actionId, _ := action["action_id"].(string)
+	if !strings.HasPrefix(actionId, suggestionActionId) {
+		handler.logger.Debug(
+			"skipping unrecognized action",
+			zap.String("actionId", actionId),
+		)
+		return nil
+	}
+	value, _ := action["value"].(string)
+	index, threadTs, err := parseSuggestionValue(value)
+	if err != nil {
+		return fmt.Errorf("failed to determine suggestion from action %v", action)
+	}
+	var label string
+	if text, ok := action["text"].(map[string]interface{}); ok {
+		label, _ = text["text"].(string)
+	}
+
+	user, ok := payload["user"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("failed to determine user from payload %v", payload)
+	}
+	userId, ok := user["id"].(string)
+	if !ok {
+		return fmt.Errorf("failed to determine user id from payload %v", payload)
+	}
+	channel, ok := payload["channel"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("failed to determine channel from payload %v", payload)
+	}
+	channelId, ok := channel["id"].(string)
+	if !ok {
+		return fmt.Errorf("failed to determine channel id from payload %v", payload)
+	}
+	message, ok := payload["message"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("failed to determine message from payload %v", payload)
+	}
+	messageTs, ok := message["ts"].(string)
+	if !ok {
+		return fmt.Errorf("failed to determine message ts from payload %v", payload)
+	}
+
+	return handler.suggestionAnswerer.AnswerSuggestion(
+		ctx,
+		channelId,
+		messageTs,
+		threadTs,
+		userId,
+		index,
+		label,
+	)
+}
@@ -0,0 +1,164 @@
+package events
+
+import (
+	"context"
+	"testing"
+)
+
+type fakeSuggestionAnswerer struct {
+	channelId string
+	messageTs string
+	threadTs  string
+	userId    string
+	index     int
+	label     string
+}
+
+func (answerer *fakeSuggestionAnswerer) AnswerSuggestion(
+	ctx context.Context,
+	channelId string,
+	messageTs string,
+	threadTs string,
+	userId string,
+	index int,
+	label string,
+) error {
+	answerer.channelId = channelId
+	answerer.messageTs = messageTs
+	answerer.threadTs = threadTs
+	answerer.userId = userId
+	answerer.index = index
+	answerer.label = label
+	return nil
+}
+
+func fakeBlockActionsPayload(actionId string, value string, label string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       interactionTypeBlockActions,
+		"trigger_id": "1234.5678",
+		"user":       map[string]interface{}{"id": "U1"},
+		"channel":    map[string]interface{}{"id": "C1"},
+		"message":    map[string]interface{}{"ts": "1610000000.000200"},
+		"actions": []interface{}{
+			map[string]interface{}{
+				"action_id": actionId,
+				"value":     value,
+				"text": map[string]interface{}{
+					"type": "plain_text",
+					"text": label,
+				},
+			},
+		},
+	}
+}
+
+func TestNewInteractionHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  *InteractionHandlerParameters
+		wantErr bool
+	}{
+		{
+			name: "ReturnsHandler",
+			params: &InteractionHandlerParameters{
+				Logger:             fakeZapLogger(),
+				SuggestionAnswerer: &fakeSuggestionAnswerer{},
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingLogger",
+			params: &InteractionHandlerParameters{
+				SuggestionAnswerer: &fakeSuggestionAnswerer{},
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingSuggestionAnswerer",
+			params: &InteractionHandlerParameters{
+				Logger: fakeZapLogger(),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewInteractionHandler(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewInteractionHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInteractionHandler_Process(t *testing.T) {
+	tests := []struct {
+		name         string
+		payload      map[string]interface{}
+		wantIndex    int
+		wantThreadTs string
+		wantLabel    string
+		wantErr      bool
+	}{
+		{
+			name:      "AnswersSuggestion",
+			payload:   fakeBlockActionsPayload(suggestionActionId+"_1", "1", "Who is a good dog?"),
+			wantIndex: 1,
+			wantLabel: "Who is a good dog?",
+			wantErr:   false,
+		},
+		{
+			name:         "AnswersSuggestionInThread",
+			payload:      fakeBlockActionsPayload(suggestionActionId+"_1", "1:1610000000.000100", "Who is a good dog?"),
+			wantIndex:    1,
+			wantThreadTs: "1610000000.000100",
+			wantLabel:    "Who is a good dog?",
+			wantErr:      false,
+		},
+		{
+			name:      "IgnoresOtherActions",
+			payload:   fakeBlockActionsPayload("something_else", "value", "Value"),
+			wantLabel: "",
+			wantErr:   false,
+		},
+		{
+			name:    "InvalidIndex",
+			payload: fakeBlockActionsPayload(suggestionActionId+"_0", "Who is a good dog?", "Who is a good dog?"),
+			wantErr: true,
+		},
+		{
+			name: "MissingActions",
+			payload: map[string]interface{}{
+				"type": interactionTypeBlockActions,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answerer := &fakeSuggestionAnswerer{}
+			handler, err := NewInteractionHandler(
+				&InteractionHandlerParameters{
+					Logger:             fakeZapLogger(),
+					SuggestionAnswerer: answerer,
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = handler.Process(context.Background(), tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if answerer.index != tt.wantIndex || answerer.label != tt.wantLabel {
+				t.Errorf("Process() suggestion = %v %v, want %v %v", answerer.index, answerer.label, tt.wantIndex, tt.wantLabel)
+			}
+			if answerer.threadTs != tt.wantThreadTs {
+				t.Errorf("Process() threadTs = %v, want %v", answerer.threadTs, tt.wantThreadTs)
+			}
+			if tt.wantLabel != "" && answerer.messageTs != "1610000000.000200" {
+				t.Errorf("Process() messageTs = %v, want %v", answerer.messageTs, "1610000000.000200")
+			}
+		})
+	}
+}
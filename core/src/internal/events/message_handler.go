@@ -0,0 +1,132 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"strings"
+)
+
+// A ReplySyncer keeps the replies of the app
+// in sync with the messages they answered.
+type ReplySyncer interface {
+	Reanswer(ctx context.Context, channelId string, sourceTs string, text string) error
+	Retract(ctx context.Context, channelId string, sourceTs string) error
+}
+
+// A MessageHandler processes message events
+// and keeps replies of the app in sync when
+// the messages they answered are edited or
+// deleted.
+type MessageHandler struct {
+	logger               *zap.Logger
+	replySyncer          ReplySyncer
+	syncReplies          bool
+	syncRepliesByChannel map[string]bool
+}
+
+// MessageHandlerParameters describe how
+// to create a new MessageHandler.
+type MessageHandlerParameters struct {
+	Logger               *zap.Logger
+	ReplySyncer          ReplySyncer
+	SyncReplies          bool
+	SyncRepliesByChannel map[string]bool
+}
+
+// NewMessageHandler returns a new instance
+// of MessageHandler according to the given
+// parameters.
+func NewMessageHandler(
+	params *MessageHandlerParameters,
+) (*MessageHandler, error) {
+	if params.Logger == nil {
+		return nil, errors.New("missing logger")
+	}
+	if params.ReplySyncer == nil {
+		return nil, errors.New("missing reply syncer")
+	}
+	syncRepliesByChannel := params.SyncRepliesByChannel
+	if syncRepliesByChannel == nil {
+		syncRepliesByChannel = make(map[string]bool)
+	}
+	return &MessageHandler{
+		logger:               params.Logger,
+		replySyncer:          params.ReplySyncer,
+		syncReplies:          params.SyncReplies,
+		syncRepliesByChannel: syncRepliesByChannel,
+	}, nil
+}
+
+// Process processes the given event data,
+// re-answering edited messages and retracting
+// replies to deleted messages. Other message
+// events are ignored.
+func (handler *MessageHandler) Process(
+	ctx context.Context,
+	data map[string]interface{},
+) error {
+	eventData, ok := data["event"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("failed to determine event data from data %v", data)
+	}
+	subtype, _ := eventData["subtype"].(string)
+	if subtype != "message_changed" && subtype != "message_deleted" {
+		return nil
+	}
+
+	channelId, ok := eventData["channel"].(string)
+	if !ok {
+		return fmt.Errorf("failed to determine channel from event data %v", eventData)
+	}
+	if !handler.shouldSyncReplies(channelId) {
+		handler.logger.Debug(
+			"reply sync disabled for channel",
+			zap.String("channelId", channelId),
+		)
+		return nil
+	}
+
+	switch subtype {
+	case "message_changed":
+		message, ok := eventData["message"].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("failed to determine message from event data %v", eventData)
+		}
+		ts, ok := message["ts"].(string)
+		if !ok {
+			return fmt.Errorf("failed to determine timestamp from message %v", message)
+		}
+		text, _ := message["text"].(string)
+		appUserId := appUserIdFromData(data)
+		if appUserId == "" || !strings.Contains(text, "<@"+appUserId+">") {
+			return handler.replySyncer.Retract(ctx, channelId, ts)
+		}
+		return handler.replySyncer.Reanswer(
+			ctx,
+			channelId,
+			ts,
+			stripAppMention(text, appUserId),
+		)
+	default:
+		ts, ok := eventData["deleted_ts"].(string)
+		if !ok {
+			return fmt.Errorf("failed to determine deleted timestamp from event data %v", eventData)
+		}
+		return handler.replySyncer.Retract(ctx, channelId, ts)
+	}
+}
+
+// shouldSyncReplies returns true if replies
+// should be kept in sync with their messages
+// in the channel matching the given channelId.
+func (handler *MessageHandler) shouldSyncReplies(
+	channelId string,
+) bool {
+	syncReplies, ok := handler.syncRepliesByChannel[channelId]
+	if !ok {
+		return handler.syncReplies
+	}
+	return syncReplies
+}
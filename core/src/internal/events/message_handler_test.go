@@ -0,0 +1,240 @@
+package events
+
+import (
+	"context"
+	"testing"
+)
+
+type fakeReplySyncer struct {
+	reanswered []string
+	retracted  []string
+}
+
+func (syncer *fakeReplySyncer) Reanswer(
+	ctx context.Context,
+	channelId string,
+	sourceTs string,
+	text string,
+) error {
+	syncer.reanswered = append(syncer.reanswered, channelId+":"+sourceTs+":"+text)
+	return nil
+}
+
+func (syncer *fakeReplySyncer) Retract(
+	ctx context.Context,
+	channelId string,
+	sourceTs string,
+) error {
+	syncer.retracted = append(syncer.retracted, channelId+":"+sourceTs)
+	return nil
+}
+
+func fakeMessageEvent(
+	eventData map[string]interface{},
+) map[string]interface{} {
+	return map[string]interface{}{
+		"event_id": "Ev1",
+		"authorizations": []interface{}{
+			map[string]interface{}{
+				"user_id": "UAPP",
+			},
+		},
+		"event": eventData,
+	}
+}
+
+func TestNewMessageHandler(t *testing.T) {
+	type args struct {
+		params *MessageHandlerParameters
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "ReturnsNewHandler",
+			args: args{
+				params: &MessageHandlerParameters{
+					Logger:      fakeZapLogger(),
+					ReplySyncer: &fakeReplySyncer{},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingLogger",
+			args: args{
+				params: &MessageHandlerParameters{
+					ReplySyncer: &fakeReplySyncer{},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingReplySyncer",
+			args: args{
+				params: &MessageHandlerParameters{
+					Logger: fakeZapLogger(),
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMessageHandler(tt.args.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf(
+					"NewMessageHandler() error = %v, wantErr %v",
+					err,
+					tt.wantErr,
+				)
+			}
+		})
+	}
+}
+
+func TestMessageHandler_Process(t *testing.T) {
+	type args struct {
+		syncReplies          bool
+		syncRepliesByChannel map[string]bool
+		eventData            map[string]interface{}
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantReanswered []string
+		wantRetracted  []string
+		wantErr        bool
+	}{
+		{
+			name: "ReanswersEditedMention",
+			args: args{
+				syncReplies: true,
+				eventData: map[string]interface{}{
+					"type":    "message",
+					"subtype": "message_changed",
+					"channel": "C1",
+					"message": map[string]interface{}{
+						"ts":   "1.1",
+						"text": "<@UAPP> hello",
+					},
+				},
+			},
+			wantReanswered: []string{"C1:1.1: hello"},
+		},
+		{
+			name: "RetractsWhenMentionRemoved",
+			args: args{
+				syncReplies: true,
+				eventData: map[string]interface{}{
+					"type":    "message",
+					"subtype": "message_changed",
+					"channel": "C1",
+					"message": map[string]interface{}{
+						"ts":   "1.1",
+						"text": "hello",
+					},
+				},
+			},
+			wantRetracted: []string{"C1:1.1"},
+		},
+		{
+			name: "RetractsDeletedMessage",
+			args: args{
+				syncReplies: true,
+				eventData: map[string]interface{}{
+					"type":       "message",
+					"subtype":    "message_deleted",
+					"channel":    "C1",
+					"deleted_ts": "1.1",
+				},
+			},
+			wantRetracted: []string{"C1:1.1"},
+		},
+		{
+			name: "IgnoresOtherMessages",
+			args: args{
+				syncReplies: true,
+				eventData: map[string]interface{}{
+					"type":    "message",
+					"channel": "C1",
+					"text":    "<@UAPP> hello",
+				},
+			},
+		},
+		{
+			name: "SkipsDisabledChannels",
+			args: args{
+				syncReplies: true,
+				syncRepliesByChannel: map[string]bool{
+					"C1": false,
+				},
+				eventData: map[string]interface{}{
+					"type":       "message",
+					"subtype":    "message_deleted",
+					"channel":    "C1",
+					"deleted_ts": "1.1",
+				},
+			},
+		},
+		{
+			name: "SyncsEnabledChannels",
+			args: args{
+				syncReplies: false,
+				syncRepliesByChannel: map[string]bool{
+					"C1": true,
+				},
+				eventData: map[string]interface{}{
+					"type":       "message",
+					"subtype":    "message_deleted",
+					"channel":    "C1",
+					"deleted_ts": "1.1",
+				},
+			},
+			wantRetracted: []string{"C1:1.1"},
+		},
+		{
+			name: "MissingDeletedTimestamp",
+			args: args{
+				syncReplies: true,
+				eventData: map[string]interface{}{
+					"type":    "message",
+					"subtype": "message_deleted",
+					"channel": "C1",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syncer := &fakeReplySyncer{}
+			handler, err := NewMessageHandler(
+				&MessageHandlerParameters{
+					Logger:               fakeZapLogger(),
+					ReplySyncer:          syncer,
+					SyncReplies:          tt.args.syncReplies,
+					SyncRepliesByChannel: tt.args.syncRepliesByChannel,
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = handler.Process(context.Background(), fakeMessageEvent(tt.args.eventData))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(syncer.reanswered) != len(tt.wantReanswered) ||
+				(len(tt.wantReanswered) > 0 && syncer.reanswered[0] != tt.wantReanswered[0]) {
+				t.Errorf("Process() reanswered = %v, want %v", syncer.reanswered, tt.wantReanswered)
+			}
+			if len(syncer.retracted) != len(tt.wantRetracted) ||
+				(len(tt.wantRetracted) > 0 && syncer.retracted[0] != tt.wantRetracted[0]) {
+				t.Errorf("Process() retracted = %v, want %v", syncer.retracted, tt.wantRetracted)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// A RateLimit allows Count events per Period.
+// A RateLimit with a Count of zero or less
+// allows any number of events.
+type RateLimit struct {
+	Count  int
+	Period time.Duration
+}
+
+// RateLimitStats report how many times each
+// rate limit has been triggered and how many
+// throttling notices have been sent.
+type RateLimitStats struct {
+	User    int
+	Channel int
+	Global  int
+	Notices int
+}
+
+// Scopes reported by rateLimiter.allow.
+const (
+	rateLimitScopeNone    = ""
+	rateLimitScopeUser    = "user"
+	rateLimitScopeChannel = "channel"
+	rateLimitScopeGlobal  = "global"
+)
+
+// A tokenBucket holds up to capacity tokens
+// and refills at a constant rate.
+type tokenBucket struct {
+	capacity   float64
+	tokens     float64
+	refillRate float64
+	updatedAt  time.Time
+}
+
+// newTokenBucket returns a new full tokenBucket
+// for the given rate limit.
+func newTokenBucket(limit RateLimit, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		capacity:   float64(limit.Count),
+		tokens:     float64(limit.Count),
+		refillRate: float64(limit.Count) / limit.Period.Seconds(),
+		updatedAt:  now,
+	}
+}
+
+// refill adds the tokens accumulated since
+// the bucket was last updated.
+func (bucket *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(bucket.updatedAt).Seconds()
+	if elapsed > 0 {
+		bucket.tokens += elapsed * bucket.refillRate
+		if bucket.tokens > bucket.capacity {
+			bucket.tokens = bucket.capacity
+		}
+	}
+	bucket.updatedAt = now
+}
+
+// available returns true if the bucket holds
+// at least one token.
+func (bucket *tokenBucket) available(now time.Time) bool {
+	bucket.refill(now)
+	return bucket.tokens >= 1
+}
+
+// full returns true if the bucket has
+// refilled completely.
+func (bucket *tokenBucket) full(now time.Time) bool {
+	bucket.refill(now)
+	return bucket.tokens >= bucket.capacity
+}
+
+// A throttle records the bucket that last
+// throttled a user and whether the user has
+// been notified since they were allowed.
+type throttle struct {
+	bucket   *tokenBucket
+	notified bool
+}
+
+// A rateLimiter applies token bucket limits
+// per user, per channel, and globally.
+type rateLimiter struct {
+	mutex          sync.Mutex
+	userLimit      RateLimit
+	channelLimit   RateLimit
+	globalLimit    RateLimit
+	userBuckets    map[string]*tokenBucket
+	channelBuckets map[string]*tokenBucket
+	globalBucket   *tokenBucket
+	throttled      map[string]*throttle
+	stats          RateLimitStats
+	now            func() time.Time
+}
+
+// newRateLimiter returns a new rateLimiter
+// enforcing the given limits.
+func newRateLimiter(
+	userLimit RateLimit,
+	channelLimit RateLimit,
+	globalLimit RateLimit,
+) *rateLimiter {
+	limiter := &rateLimiter{
+		userLimit:      userLimit,
+		channelLimit:   channelLimit,
+		globalLimit:    globalLimit,
+		userBuckets:    make(map[string]*tokenBucket),
+		channelBuckets: make(map[string]*tokenBucket),
+		throttled:      make(map[string]*throttle),
+		now:            time.Now,
+	}
+	if rateLimitEnabled(globalLimit) {
+		limiter.globalBucket = newTokenBucket(globalLimit, limiter.now())
+	}
+	return limiter
+}
+
+// allow takes a token from the buckets of the
+// given user, the given channel, and the global
+// bucket, returning false and the scope of the
+// first limit that would be exceeded instead.
+// No tokens are taken unless all buckets allow
+// the event.
+func (limiter *rateLimiter) allow(
+	userId string,
+	channelId string,
+) (bool, string) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	now := limiter.now()
+	limiter.forgetFullBuckets(now)
+
+	var buckets []*tokenBucket
+	if rateLimitEnabled(limiter.userLimit) {
+		bucket := bucketFor(limiter.userBuckets, userId, limiter.userLimit, now)
+		if !bucket.available(now) {
+			limiter.stats.User++
+			limiter.throttle(userId, bucket)
+			return false, rateLimitScopeUser
+		}
+		buckets = append(buckets, bucket)
+	}
+	if rateLimitEnabled(limiter.channelLimit) {
+		bucket := bucketFor(limiter.channelBuckets, channelId, limiter.channelLimit, now)
+		if !bucket.available(now) {
+			limiter.stats.Channel++
+			limiter.throttle(userId, bucket)
+			return false, rateLimitScopeChannel
+		}
+		buckets = append(buckets, bucket)
+	}
+	if limiter.globalBucket != nil {
+		if !limiter.globalBucket.available(now) {
+			limiter.stats.Global++
+			limiter.throttle(userId, limiter.globalBucket)
+			return false, rateLimitScopeGlobal
+		}
+		buckets = append(buckets, limiter.globalBucket)
+	}
+
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	delete(limiter.throttled, userId)
+	return true, rateLimitScopeNone
+}
+
+// throttle records that the given user was
+// throttled by the given bucket. The caller
+// must hold the mutex.
+func (limiter *rateLimiter) throttle(userId string, bucket *tokenBucket) {
+	if throttled, ok := limiter.throttled[userId]; ok {
+		throttled.bucket = bucket
+		return
+	}
+	limiter.throttled[userId] = &throttle{bucket: bucket}
+}
+
+// shouldNotify returns true the first time it is
+// called for the given user since the user was
+// last allowed through or the bucket that
+// throttled them refilled.
+func (limiter *rateLimiter) shouldNotify(userId string) bool {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	throttled, ok := limiter.throttled[userId]
+	if !ok {
+		throttled = &throttle{}
+		limiter.throttled[userId] = throttled
+	}
+	if throttled.notified {
+		return false
+	}
+	throttled.notified = true
+	limiter.stats.Notices++
+	return true
+}
+
+// Stats returns how many times each limit
+// has been triggered.
+func (limiter *rateLimiter) Stats() RateLimitStats {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	return limiter.stats
+}
+
+// sameLimits returns true if the given
+// rateLimiter enforces the same limits.
+func (limiter *rateLimiter) sameLimits(other *rateLimiter) bool {
+	return limiter.userLimit == other.userLimit &&
+		limiter.channelLimit == other.channelLimit &&
+		limiter.globalLimit == other.globalLimit
+}
+
+// forgetFullBuckets drops per-user and
+// per-channel buckets that have refilled
+// completely, since they are equivalent to
+// new buckets, along with the users they
+// throttled, whatever the scope.
+func (limiter *rateLimiter) forgetFullBuckets(now time.Time) {
+	for key, bucket := range limiter.userBuckets {
+		if bucket.full(now) {
+			delete(limiter.userBuckets, key)
+		}
+	}
+	for key, bucket := range limiter.channelBuckets {
+		if bucket.full(now) {
+			delete(limiter.channelBuckets, key)
+		}
+	}
+	for userId, throttled := range limiter.throttled {
+		if throttled.bucket == nil || throttled.bucket.full(now) {
+			delete(limiter.throttled, userId)
+		}
+	}
+}
+
+// bucketFor returns the bucket for the given
+// key, creating it if it does not exist.
+func bucketFor(
+	buckets map[string]*tokenBucket,
+	key string,
+	limit RateLimit,
+	now time.Time,
+) *tokenBucket {
+	bucket, ok := buckets[key]
+	if !ok {
+		bucket = newTokenBucket(limit, now)
+		buckets[key] = bucket
+	}
+	return bucket
+}
+
+// rateLimitEnabled returns true if the given rate
+// limit restricts events.
+func rateLimitEnabled(limit RateLimit) bool {
+	return limit.Count > 0 && limit.Period > 0
+}
@@ -0,0 +1,182 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	type request struct {
+		userId    string
+		channelId string
+	}
+	tests := []struct {
+		name      string
+		limiter   *rateLimiter
+		requests  []request
+		wantAllow bool
+		wantScope string
+	}{
+		{
+			name:    "AllowsWithoutLimits",
+			limiter: newRateLimiter(RateLimit{}, RateLimit{}, RateLimit{}),
+			requests: []request{
+				{"U1", "C1"},
+				{"U1", "C1"},
+				{"U1", "C1"},
+			},
+			wantAllow: true,
+			wantScope: rateLimitScopeNone,
+		},
+		{
+			name: "LimitsUsers",
+			limiter: newRateLimiter(
+				RateLimit{Count: 2, Period: time.Minute},
+				RateLimit{},
+				RateLimit{},
+			),
+			requests: []request{
+				{"U1", "C1"},
+				{"U1", "C2"},
+				{"U1", "C3"},
+			},
+			wantAllow: false,
+			wantScope: rateLimitScopeUser,
+		},
+		{
+			name: "LimitsUsersIndependently",
+			limiter: newRateLimiter(
+				RateLimit{Count: 2, Period: time.Minute},
+				RateLimit{},
+				RateLimit{},
+			),
+			requests: []request{
+				{"U1", "C1"},
+				{"U1", "C1"},
+				{"U2", "C1"},
+			},
+			wantAllow: true,
+			wantScope: rateLimitScopeNone,
+		},
+		{
+			name: "LimitsChannels",
+			limiter: newRateLimiter(
+				RateLimit{},
+				RateLimit{Count: 2, Period: time.Minute},
+				RateLimit{},
+			),
+			requests: []request{
+				{"U1", "C1"},
+				{"U2", "C1"},
+				{"U3", "C1"},
+			},
+			wantAllow: false,
+			wantScope: rateLimitScopeChannel,
+		},
+		{
+			name: "LimitsGlobally",
+			limiter: newRateLimiter(
+				RateLimit{},
+				RateLimit{},
+				RateLimit{Count: 2, Period: time.Minute},
+			),
+			requests: []request{
+				{"U1", "C1"},
+				{"U2", "C2"},
+				{"U3", "C3"},
+			},
+			wantAllow: false,
+			wantScope: rateLimitScopeGlobal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var allowed bool
+			var scope string
+			for _, request := range tt.requests {
+				allowed, scope = tt.limiter.allow(request.userId, request.channelId)
+			}
+			if allowed != tt.wantAllow || scope != tt.wantScope {
+				t.Errorf(
+					"allow() = %v, %q, want %v, %q",
+					allowed,
+					scope,
+					tt.wantAllow,
+					tt.wantScope,
+				)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_Refills(t *testing.T) {
+	limiter := newRateLimiter(
+		RateLimit{Count: 1, Period: time.Minute},
+		RateLimit{},
+		RateLimit{},
+	)
+	now := time.Now()
+	limiter.now = func() time.Time {
+		return now
+	}
+
+	if allowed, _ := limiter.allow("U1", "C1"); !allowed {
+		t.Errorf("allow() = %v, want %v", allowed, true)
+	}
+	if allowed, _ := limiter.allow("U1", "C1"); allowed {
+		t.Errorf("allow() = %v, want %v", allowed, false)
+	}
+
+	now = now.Add(time.Minute)
+	if allowed, _ := limiter.allow("U1", "C1"); !allowed {
+		t.Errorf("allow() = %v, want %v", allowed, true)
+	}
+}
+
+func TestRateLimiter_ShouldNotifyOnce(t *testing.T) {
+	limiter := newRateLimiter(
+		RateLimit{Count: 1, Period: time.Minute},
+		RateLimit{},
+		RateLimit{},
+	)
+	limiter.allow("U1", "C1")
+	limiter.allow("U1", "C1")
+	limiter.allow("U1", "C1")
+
+	if !limiter.shouldNotify("U1") {
+		t.Errorf("shouldNotify() = %v, want %v", false, true)
+	}
+	if limiter.shouldNotify("U1") {
+		t.Errorf("shouldNotify() = %v, want %v", true, false)
+	}
+
+	stats := limiter.Stats()
+	if stats.User != 2 || stats.Notices != 1 {
+		t.Errorf("Stats() = %+v, want %+v", stats, RateLimitStats{User: 2, Notices: 1})
+	}
+}
+
+func TestRateLimiter_ForgetsNoticesOnRefill(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(
+		RateLimit{},
+		RateLimit{Count: 1, Period: time.Minute},
+		RateLimit{},
+	)
+	limiter.now = func() time.Time { return now }
+	limiter.allow("U1", "C1")
+	limiter.allow("U2", "C1")
+
+	if !limiter.shouldNotify("U2") {
+		t.Errorf("shouldNotify() = %v, want %v", false, true)
+	}
+	if limiter.shouldNotify("U2") {
+		t.Errorf("shouldNotify() = %v, want %v", true, false)
+	}
+
+	now = now.Add(time.Minute)
+	limiter.allow("U1", "C2")
+	if len(limiter.throttled) != 0 {
+		t.Errorf("len(throttled) = %d, want %d", len(limiter.throttled), 0)
+	}
+}
@@ -0,0 +1,134 @@
+package events
+
+import (
+	"container/list"
+	"sync"
+)
+
+// A trackedReply links a message that
+// mentioned the app to the reply the app
+// sent in response.
+type trackedReply struct {
+	channelId    string
+	sourceTs     string
+	threadTs     string
+	replyTs      string
+	senderUserId string
+	input        string
+	reply        string
+	suggestions  []string
+}
+
+// A replyTracker remembers the most recent
+// replies sent by the app so they can be
+// found again by their source message or
+// by the reply itself.
+type replyTracker struct {
+	mutex     sync.Mutex
+	queue     *list.List
+	bySource  map[string]*list.Element
+	byReply   map[string]*list.Element
+	maxLength int
+}
+
+// replyTrackerMaxLength defines the max
+// number of replies to track at any
+// given time.
+const replyTrackerMaxLength = 500
+
+// newReplyTracker returns a new replyTracker
+// that remembers at most maxLength replies.
+func newReplyTracker(maxLength int) *replyTracker {
+	return &replyTracker{
+		queue:     list.New(),
+		bySource:  make(map[string]*list.Element),
+		byReply:   make(map[string]*list.Element),
+		maxLength: maxLength,
+	}
+}
+
+// track adds the given reply to the front of
+// the tracker and forgets the oldest reply if
+// the tracker holds more than its max length.
+func (tracker *replyTracker) track(reply trackedReply) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	key := messageKey(reply.channelId, reply.sourceTs)
+	if element, ok := tracker.bySource[key]; ok {
+		tracker.remove(element)
+	}
+	element := tracker.queue.PushFront(reply)
+	tracker.bySource[key] = element
+	tracker.byReply[messageKey(reply.channelId, reply.replyTs)] = element
+
+	if tracker.queue.Len() > tracker.maxLength {
+		tracker.remove(tracker.queue.Back())
+	}
+}
+
+// findBySource returns the reply sent in
+// response to the message matching the given
+// channelId and timestamp, if any.
+func (tracker *replyTracker) findBySource(
+	channelId string,
+	sourceTs string,
+) (trackedReply, bool) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	element, ok := tracker.bySource[messageKey(channelId, sourceTs)]
+	if !ok {
+		return trackedReply{}, false
+	}
+	return element.Value.(trackedReply), true
+}
+
+// forgetBySource stops tracking the reply sent
+// in response to the message matching the given
+// channelId and timestamp.
+func (tracker *replyTracker) forgetBySource(
+	channelId string,
+	sourceTs string,
+) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	element, ok := tracker.bySource[messageKey(channelId, sourceTs)]
+	if !ok {
+		return
+	}
+	tracker.remove(element)
+}
+
+// findByReply returns the reply matching the
+// given channelId and reply timestamp, if any.
+func (tracker *replyTracker) findByReply(
+	channelId string,
+	replyTs string,
+) (trackedReply, bool) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	element, ok := tracker.byReply[messageKey(channelId, replyTs)]
+	if !ok {
+		return trackedReply{}, false
+	}
+	return element.Value.(trackedReply), true
+}
+
+// remove stops tracking the reply held by the
+// given element. The caller must hold the mutex.
+func (tracker *replyTracker) remove(element *list.Element) {
+	tracker.queue.Remove(element)
+	reply := element.Value.(trackedReply)
+	delete(tracker.bySource, messageKey(reply.channelId, reply.sourceTs))
+	delete(tracker.byReply, messageKey(reply.channelId, reply.replyTs))
+}
+
+// messageKey returns a key identifying the
+// message matching the given channelId and
+// timestamp.
+func messageKey(channelId string, ts string) string {
+	return channelId + ":" + ts
+}
@@ -0,0 +1,74 @@
+package events
+
+import (
+	"testing"
+)
+
+func TestReplyTracker_FindBySource(t *testing.T) {
+	tracker := newReplyTracker(2)
+	tracker.track(trackedReply{channelId: "C1", sourceTs: "1", replyTs: "10"})
+	tracker.track(trackedReply{channelId: "C1", sourceTs: "2", replyTs: "20"})
+
+	reply, ok := tracker.findBySource("C1", "2")
+	if !ok || reply.replyTs != "20" {
+		t.Errorf("findBySource() = %v, %v, want %v, %v", reply.replyTs, ok, "20", true)
+	}
+
+	_, ok = tracker.findBySource("C2", "2")
+	if ok {
+		t.Errorf("findBySource() = %v, want %v", ok, false)
+	}
+}
+
+func TestReplyTracker_ForgetsOldestReplies(t *testing.T) {
+	tracker := newReplyTracker(2)
+	tracker.track(trackedReply{channelId: "C1", sourceTs: "1", replyTs: "10"})
+	tracker.track(trackedReply{channelId: "C1", sourceTs: "2", replyTs: "20"})
+	tracker.track(trackedReply{channelId: "C1", sourceTs: "3", replyTs: "30"})
+
+	_, ok := tracker.findBySource("C1", "1")
+	if ok {
+		t.Errorf("findBySource() = %v, want %v", ok, false)
+	}
+	_, ok = tracker.findBySource("C1", "3")
+	if !ok {
+		t.Errorf("findBySource() = %v, want %v", ok, true)
+	}
+}
+
+func TestReplyTracker_ForgetBySource(t *testing.T) {
+	tracker := newReplyTracker(2)
+	tracker.track(trackedReply{channelId: "C1", sourceTs: "1", replyTs: "10"})
+	tracker.forgetBySource("C1", "1")
+
+	_, ok := tracker.findBySource("C1", "1")
+	if ok {
+		t.Errorf("findBySource() = %v, want %v", ok, false)
+	}
+	if tracker.queue.Len() != 0 {
+		t.Errorf("queue.Len() = %v, want %v", tracker.queue.Len(), 0)
+	}
+}
+
+func TestReplyTracker_FindByReply(t *testing.T) {
+	tracker := newReplyTracker(2)
+	tracker.track(trackedReply{channelId: "C1", sourceTs: "1", replyTs: "10"})
+	tracker.track(trackedReply{channelId: "C1", sourceTs: "2", replyTs: "20"})
+	tracker.track(trackedReply{channelId: "C1", sourceTs: "3", replyTs: "30"})
+
+	reply, ok := tracker.findByReply("C1", "20")
+	if !ok || reply.sourceTs != "2" {
+		t.Errorf("findByReply() = %v, %v, want %v, %v", reply.sourceTs, ok, "2", true)
+	}
+
+	_, ok = tracker.findByReply("C1", "10")
+	if ok {
+		t.Errorf("findByReply() = %v, want %v", ok, false)
+	}
+
+	tracker.forgetBySource("C1", "3")
+	_, ok = tracker.findByReply("C1", "30")
+	if ok {
+		t.Errorf("findByReply() = %v, want %v", ok, false)
+	}
+}
@@ -0,0 +1,174 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// A retryPolicy determines how many times and
+// how quickly an event that failed with a
+// transient error is processed again.
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	sleep       func(context.Context, time.Duration) error
+}
+
+// defaultMaxAttempts, defaultRetryDelay, and
+// maxRetryDelay determine the default retry
+// behavior for failed events.
+const (
+	defaultMaxAttempts = 3
+	defaultRetryDelay  = time.Second
+	maxRetryDelay      = 30 * time.Second
+)
+
+// newRetryPolicy returns a new retryPolicy
+// allowing the given number of attempts and
+// doubling the given delay after each attempt.
+func newRetryPolicy(
+	maxAttempts int,
+	baseDelay time.Duration,
+) *retryPolicy {
+	if maxAttempts < 1 {
+		maxAttempts = defaultMaxAttempts
+	}
+	if baseDelay <= 0 {
+		baseDelay = defaultRetryDelay
+	}
+	return &retryPolicy{
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+		maxDelay:    maxRetryDelay,
+		sleep:       sleepContext,
+	}
+}
+
+// run calls process until it succeeds, fails
+// with an error that is not transient, or the
+// max attempts have been reached, and returns
+// the number of attempts made with the last
+// error encountered. Retries stop early when
+// the given context is done.
+func (policy *retryPolicy) run(
+	ctx context.Context,
+	process func() error,
+) (int, error) {
+	attempts := 0
+	for {
+		attempts++
+		err := process()
+		if err == nil {
+			return attempts, nil
+		}
+		if attempts >= policy.maxAttempts || !isTransient(err) {
+			return attempts, err
+		}
+		if policy.sleep(ctx, policy.delay(attempts)) != nil {
+			return attempts, err
+		}
+	}
+}
+
+// sleepContext waits for the given delay or
+// until the given context is done, returning
+// the error of the context in the latter case.
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// delay returns the backoff to wait after
+// the given number of attempts.
+func (policy *retryPolicy) delay(attempts int) time.Duration {
+	delay := policy.baseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= policy.maxDelay {
+			return policy.maxDelay
+		}
+	}
+	return delay
+}
+
+// A finalError is an error that must not be
+// retried, because the event already had an
+// effect in Slack, such as a posted reply, or
+// because the failed attempt may still be
+// running.
+type finalError struct {
+	err error
+}
+
+// Error returns the message of the wrapped error.
+func (err *finalError) Error() string {
+	return err.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (err *finalError) Unwrap() error {
+	return err.err
+}
+
+// final marks the given error, if any, as one
+// that must not be retried.
+func final(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &finalError{err: err}
+}
+
+// attemptKey is the context key holding the
+// number of the current attempt at an event.
+type attemptKey struct{}
+
+// withAttempt returns a context carrying the
+// given attempt number.
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// retried returns true if the given context
+// carries an attempt after the first, so steps
+// that already happened, such as taking a rate
+// limit token, are not repeated.
+func retried(ctx context.Context) bool {
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt > 1
+}
+
+// isTransient returns true if the given error
+// is likely to go away when retried, such as
+// network failures, timeouts, and errors that
+// report themselves as temporary, unless it
+// was marked final.
+func isTransient(err error) bool {
+	var finalErr *finalError
+	if errors.As(err, &finalErr) {
+		return false
+	}
+	var temporary interface {
+		Temporary() bool
+	}
+	if errors.As(err, &temporary) && temporary.Temporary() {
+		return true
+	}
+	var timeout interface {
+		Timeout() bool
+	}
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
@@ -0,0 +1,175 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+type fakeTemporaryError struct {
+	temporary bool
+}
+
+func (err *fakeTemporaryError) Error() string {
+	return "fake temporary error"
+}
+
+func (err *fakeTemporaryError) Temporary() bool {
+	return err.temporary
+}
+
+func TestRetryPolicy_Run(t *testing.T) {
+	tests := []struct {
+		name         string
+		errs         []error
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "Succeeds",
+			errs:         []error{nil},
+			wantAttempts: 1,
+			wantErr:      false,
+		},
+		{
+			name: "RetriesTransientErrors",
+			errs: []error{
+				&fakeTemporaryError{temporary: true},
+				nil,
+			},
+			wantAttempts: 2,
+			wantErr:      false,
+		},
+		{
+			name: "StopsAtMaxAttempts",
+			errs: []error{
+				&fakeTemporaryError{temporary: true},
+				&fakeTemporaryError{temporary: true},
+				&fakeTemporaryError{temporary: true},
+				nil,
+			},
+			wantAttempts: 3,
+			wantErr:      true,
+		},
+		{
+			name: "DoesNotRetryFinalErrors",
+			errs: []error{
+				final(&fakeTemporaryError{temporary: true}),
+				nil,
+			},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name: "DoesNotRetryPermanentErrors",
+			errs: []error{
+				errors.New("fake permanent error"),
+				nil,
+			},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var slept []time.Duration
+			policy := newRetryPolicy(3, time.Second)
+			policy.sleep = func(ctx context.Context, delay time.Duration) error {
+				slept = append(slept, delay)
+				return nil
+			}
+
+			calls := 0
+			attempts, err := policy.run(context.Background(), func() error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("run() attempts = %v, want %v", attempts, tt.wantAttempts)
+			}
+			if len(slept) != attempts-1 && !tt.wantErr {
+				t.Errorf("run() slept = %v, want %v", len(slept), attempts-1)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_RunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	policy := newRetryPolicy(3, time.Hour)
+	attempts, err := policy.run(ctx, func() error {
+		return &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	})
+	if err == nil {
+		t.Errorf("run() error = %v, wantErr %v", err, true)
+	}
+	if attempts != 1 {
+		t.Errorf("run() attempts = %v, want %v", attempts, 1)
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := newRetryPolicy(10, time.Second)
+	want := []time.Duration{
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+	}
+	for index, delay := range want {
+		got := policy.delay(index + 1)
+		if got != delay {
+			t.Errorf("delay(%d) = %v, want %v", index+1, got, delay)
+		}
+	}
+	if got := policy.delay(20); got != maxRetryDelay {
+		t.Errorf("delay(20) = %v, want %v", got, maxRetryDelay)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "TemporaryError",
+			err:  &fakeTemporaryError{temporary: true},
+			want: true,
+		},
+		{
+			name: "WrappedTemporaryError",
+			err:  fmt.Errorf("wrapped: %w", &fakeTemporaryError{temporary: true}),
+			want: true,
+		},
+		{
+			name: "NetworkError",
+			err: &net.OpError{
+				Op:  "dial",
+				Err: errors.New("connection refused"),
+			},
+			want: true,
+		},
+		{
+			name: "PermanentError",
+			err:  errors.New("fake permanent error"),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.want {
+				t.Errorf("isTransient() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/drewnorman/jt-slackbot/core/internal/dialog"
+	"go.uber.org/zap"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// A Trainer teaches the dialog service new
+// responses or makes it forget them.
+type Trainer interface {
+	Train(ctx context.Context, training *dialog.Training) error
+}
+
+// RecalledReplies are the replies of the
+// dialog service remembered in case it fails,
+// which are forgotten once it is retrained.
+type RecalledReplies interface {
+	Forget(text string)
+}
+
+// A trainingCommand is an instruction to teach
+// or forget a response, given to the app in a
+// mention rather than a message to respond to.
+type trainingCommand struct {
+	action    string
+	statement string
+	response  string
+}
+
+// commandPattern recognizes mentions that
+// start with a command keyword.
+var commandPattern = regexp.MustCompile(
+	`(?is)^\s*(learn|teach|forget)\s*:\s*(.*)$`,
+)
+
+// teachPattern recognizes the body of a
+// teach command, such as "when someone says
+// X, answer Y".
+var teachPattern = regexp.MustCompile(
+	`(?is)^when\s+(?:someone|somebody|anyone|anybody|people)\s+says?\s+(.+?)\s*,\s*(?:answer|reply|respond|say)(?:\s+with)?\s+(.+)$`,
+)
+
+// forgetPattern recognizes the body of a
+// forget command, such as "when someone says
+// X" or simply "X".
+var forgetPattern = regexp.MustCompile(
+	`(?is)^(?:when\s+(?:someone|somebody|anyone|anybody|people)\s+says?\s+)?(.+)$`,
+)
+
+// commandUsage explains how to give commands
+// when a command could not be understood.
+const commandUsage = "I didn't catch that. Try `learn: when someone says X, answer Y` " +
+	"or `forget: when someone says X`."
+
+// commandForbidden is sent to users who give
+// commands but are not trainers.
+const commandForbidden = "Sorry, only my trainers can teach me new tricks."
+
+// commandFailed is sent to trainers when the
+// dialog service could not be trained.
+const commandFailed = "Sorry, I couldn't take that in right now. Try me again in a bit."
+
+// parseCommand returns the command given in
+// the given text. The second return value is
+// false if the text is not a command at all,
+// and the command is nil if the text starts
+// like a command but could not be understood.
+func parseCommand(text string) (*trainingCommand, bool) {
+	matches := commandPattern.FindStringSubmatch(text)
+	if matches == nil {
+		return nil, false
+	}
+	keyword := strings.ToLower(matches[1])
+	body := strings.TrimSpace(matches[2])
+
+	if keyword == "forget" {
+		parts := forgetPattern.FindStringSubmatch(body)
+		if parts == nil {
+			return nil, true
+		}
+		statement := unquote(parts[1])
+		if statement == "" {
+			return nil, true
+		}
+		return &trainingCommand{
+			action:    dialog.TrainingForget,
+			statement: statement,
+		}, true
+	}
+
+	parts := teachPattern.FindStringSubmatch(body)
+	if parts == nil {
+		return nil, true
+	}
+	statement := unquote(parts[1])
+	response := unquote(parts[2])
+	if statement == "" || response == "" {
+		return nil, true
+	}
+	return &trainingCommand{
+		action:    dialog.TrainingTeach,
+		statement: statement,
+		response:  response,
+	}, true
+}
+
+// unquote trims whitespace, trailing periods,
+// and surrounding quotes from the given text.
+func unquote(text string) string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimRight(text, ".")
+	for _, quotes := range []string{`""`, "''", "“”", "‘’", "``"} {
+		runes := []rune(quotes)
+		open, close := string(runes[0]), string(runes[1])
+		if len(text) >= len(open)+len(close) &&
+			strings.HasPrefix(text, open) &&
+			strings.HasSuffix(text, close) {
+			return strings.TrimSpace(text[len(open) : len(text)-len(close)])
+		}
+	}
+	return text
+}
+
+// confirmation returns the message confirming
+// that the given command was carried out.
+func (command *trainingCommand) confirmation() string {
+	if command.action == dialog.TrainingForget {
+		return fmt.Sprintf("Okay, I forgot what I learned about \"%s\".", command.statement)
+	}
+	return fmt.Sprintf(
+		"Got it! When someone says \"%s\", I'll answer \"%s\".",
+		command.statement,
+		command.response,
+	)
+}
+
+// failure returns the message explaining that
+// the given command could not be carried out
+// because of the given error.
+func (command *trainingCommand) failure(err error) string {
+	var statusErr *dialog.StatusError
+	if command.action == dialog.TrainingForget &&
+		errors.As(err, &statusErr) &&
+		statusErr.StatusCode == http.StatusNotFound {
+		return fmt.Sprintf("I never learned anything about \"%s\", so there's nothing to forget.", command.statement)
+	}
+	return commandFailed
+}
+
+// runCommand carries out the given command from
+// the sender of the given event if they are a
+// trainer and lets them know the outcome in an
+// ephemeral message. Failures to train are
+// reported to the trainer rather than retried,
+// since the trainer can simply try again.
+func (handler *AppMentionHandler) runCommand(
+	ctx context.Context,
+	event *appMentionEvent,
+	command *trainingCommand,
+) error {
+	notice := commandUsage
+	switch {
+	case !handler.trainers[event.senderUserId]:
+		handler.logger.Info(
+			"refused command from non-trainer",
+			zap.String("userId", event.senderUserId),
+		)
+		notice = commandForbidden
+	case command != nil:
+		err := handler.trainer.Train(
+			ctx,
+			&dialog.Training{
+				Action:    command.action,
+				Statement: command.statement,
+				Response:  command.response,
+				UserId:    event.senderUserId,
+			},
+		)
+		if err != nil {
+			handler.logger.Warn(
+				"failed to train dialog service",
+				zap.String("action", command.action),
+				zap.String("userId", event.senderUserId),
+				zap.String("err", err.Error()),
+			)
+			notice = command.failure(err)
+			break
+		}
+		if handler.responseCache != nil {
+			handler.responseCache.Invalidate(command.statement)
+		}
+		if handler.recalledReplies != nil {
+			handler.recalledReplies.Forget(command.statement)
+		}
+		handler.logger.Info(
+			"trained dialog service",
+			zap.String("action", command.action),
+			zap.String("userId", event.senderUserId),
+		)
+		notice = command.confirmation()
+	}
+	return final(
+		handler.slackHttpClient.SendEphemeralMessage(
+			ctx,
+			notice,
+			event.channelId,
+			event.senderUserId,
+		),
+	)
+}
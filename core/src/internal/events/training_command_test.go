@@ -0,0 +1,235 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"github.com/drewnorman/jt-slackbot/core/internal/dialog"
+	"net/http"
+	"testing"
+)
+
+type fakeTrainer struct {
+	trainings []*dialog.Training
+	err       error
+}
+
+func (trainer *fakeTrainer) Train(
+	ctx context.Context,
+	training *dialog.Training,
+) error {
+	trainer.trainings = append(trainer.trainings, training)
+	return trainer.err
+}
+
+type fakeRecalledReplies struct {
+	forgotten []string
+}
+
+func (recalled *fakeRecalledReplies) Forget(text string) {
+	recalled.forgotten = append(recalled.forgotten, text)
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		want   *trainingCommand
+		wantOk bool
+	}{
+		{
+			name: "Learn",
+			text: " learn: when someone says who is a good dog, answer Me!",
+			want: &trainingCommand{
+				action:    dialog.TrainingTeach,
+				statement: "who is a good dog",
+				response:  "Me!",
+			},
+			wantOk: true,
+		},
+		{
+			name: "TeachQuoted",
+			text: `Teach: When anyone says "Sit.", reply with "I'd rather not."`,
+			want: &trainingCommand{
+				action:    dialog.TrainingTeach,
+				statement: "Sit.",
+				response:  "I'd rather not.",
+			},
+			wantOk: true,
+		},
+		{
+			name: "Forget",
+			text: "forget: when someone says who is a good dog",
+			want: &trainingCommand{
+				action:    dialog.TrainingForget,
+				statement: "who is a good dog",
+			},
+			wantOk: true,
+		},
+		{
+			name: "ForgetStatement",
+			text: `forget: "roll over"`,
+			want: &trainingCommand{
+				action:    dialog.TrainingForget,
+				statement: "roll over",
+			},
+			wantOk: true,
+		},
+		{
+			name:   "Malformed",
+			text:   "learn: to fetch",
+			want:   nil,
+			wantOk: true,
+		},
+		{
+			name:   "NotACommand",
+			text:   "what did you learn today?",
+			want:   nil,
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseCommand(tt.text)
+			if ok != tt.wantOk {
+				t.Errorf("parseCommand() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("parseCommand() = %v, want %v", got, tt.want)
+			}
+			if got != nil && *got != *tt.want {
+				t.Errorf("parseCommand() = %v, want %v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestAppMentionHandler_ProcessCommand(t *testing.T) {
+	tests := []struct {
+		name          string
+		senderUserId  string
+		text          string
+		trainErr      error
+		wantTrainings int
+		wantNotices   int
+		wantForgotten int
+		wantErr       bool
+	}{
+		{
+			name:          "TrainerTeaches",
+			senderUserId:  "U1",
+			text:          "learn: when someone says hi, answer Woof!",
+			wantTrainings: 1,
+			wantNotices:   1,
+			wantForgotten: 1,
+			wantErr:       false,
+		},
+		{
+			name:          "NonTrainerRefused",
+			senderUserId:  "U2",
+			text:          "learn: when someone says hi, answer Woof!",
+			wantTrainings: 0,
+			wantNotices:   1,
+			wantErr:       false,
+		},
+		{
+			name:          "MalformedCommand",
+			senderUserId:  "U1",
+			text:          "forget:",
+			wantTrainings: 0,
+			wantNotices:   1,
+			wantErr:       false,
+		},
+		{
+			name:          "TrainingFails",
+			senderUserId:  "U1",
+			text:          "forget: hi",
+			trainErr:      errors.New("unavailable"),
+			wantTrainings: 1,
+			wantNotices:   1,
+			wantErr:       false,
+		},
+		{
+			name:          "NothingToForget",
+			senderUserId:  "U1",
+			text:          "forget: hi",
+			trainErr:      &dialog.StatusError{StatusCode: http.StatusNotFound},
+			wantTrainings: 1,
+			wantNotices:   1,
+			wantErr:       false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := make(map[string]int)
+			trainer := &fakeTrainer{err: tt.trainErr}
+			recalled := &fakeRecalledReplies{}
+			handler, err := NewAppMentionHandler(
+				&AppMentionHandlerParameters{
+					Logger: fakeZapLogger(),
+					SlackHttpClient: fakeRoutedSlackHttpClient(
+						t,
+						map[string]map[string]interface{}{
+							"chat.postEphemeral": {"ok": true},
+						},
+						calls,
+					),
+					Responder:       fakeStaticResponder("Woof!"),
+					Trainer:         trainer,
+					TrainerUserIds:  []string{"U1"},
+					RecalledReplies: recalled,
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			eventData := fakeAppMentionEvent(tt.text)
+			eventData["event"].(map[string]interface{})["user"] = tt.senderUserId
+			err = handler.Process(context.Background(), eventData)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(trainer.trainings) != tt.wantTrainings {
+				t.Errorf("Process() trainings = %v, want %v", len(trainer.trainings), tt.wantTrainings)
+			}
+			if len(recalled.forgotten) != tt.wantForgotten {
+				t.Errorf("Process() forgotten = %v, want %v", len(recalled.forgotten), tt.wantForgotten)
+			}
+			if calls["chat.postEphemeral"] != tt.wantNotices {
+				t.Errorf("Process() notices = %v, want %v", calls["chat.postEphemeral"], tt.wantNotices)
+			}
+			if calls["chat.postMessage"] != 0 {
+				t.Errorf("Process() messages sent = %v, want %v", calls["chat.postMessage"], 0)
+			}
+		})
+	}
+}
+
+func TestTrainingCommand_Failure(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *trainingCommand
+		err     error
+		want    string
+	}{
+		{
+			name:    "NothingToForget",
+			command: &trainingCommand{action: dialog.TrainingForget, statement: "hi"},
+			err:     &dialog.StatusError{StatusCode: http.StatusNotFound},
+			want:    "I never learned anything about \"hi\", so there's nothing to forget.",
+		},
+		{
+			name:    "Unavailable",
+			command: &trainingCommand{action: dialog.TrainingTeach, statement: "hi", response: "Woof!"},
+			err:     &dialog.StatusError{StatusCode: http.StatusServiceUnavailable},
+			want:    commandFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.command.failure(tt.err); got != tt.want {
+				t.Errorf("failure() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
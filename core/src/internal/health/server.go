@@ -0,0 +1,298 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Check returns an error if the dependency
+// it checks is not ready.
+type Check func(ctx context.Context) error
+
+// A health.Server reports over HTTP whether the
+// process is alive at /healthz, whether it is
+// alive and its live checks pass at /livez and
+// whether all of its dependencies are ready at
+// /readyz, and may expose metrics at /metrics.
+// Live checks cover what restarting the process
+// could fix, unlike outages of other services.
+type Server struct {
+	logger       *zap.Logger
+	addr         string
+	checks       map[string]Check
+	liveChecks   map[string]Check
+	checkTimeout time.Duration
+	metrics      http.Handler
+}
+
+// health.ServerParameters describe how to
+// create a new health.Server. LiveChecks
+// names those of the Checks that /livez runs.
+type ServerParameters struct {
+	Logger       *zap.Logger
+	Addr         string
+	Checks       map[string]Check
+	LiveChecks   []string
+	CheckTimeout time.Duration
+	Metrics      http.Handler
+}
+
+// A Report describes the readiness of the
+// process and of each of its dependencies.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckReport `json:"checks,omitempty"`
+}
+
+// A CheckReport describes the readiness of
+// a single dependency.
+type CheckReport struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Statuses reported for the process and
+// its dependencies.
+const (
+	StatusOk      = "ok"
+	StatusFailing = "failing"
+)
+
+// defaultCheckTimeout defines the duration of
+// time each check may take before the
+// dependency is reported as failing.
+const defaultCheckTimeout = 2 * time.Second
+
+// shutdownTimeout defines the duration of time
+// to wait for open requests when the server
+// is stopped.
+const shutdownTimeout = 5 * time.Second
+
+// NewServer returns a new health.Server
+// according to the given parameters.
+func NewServer(params *ServerParameters) (*Server, error) {
+	if params.Logger == nil {
+		return nil, errors.New("missing logger")
+	}
+	if params.Addr == "" {
+		return nil, errors.New("missing address")
+	}
+	checkTimeout := defaultCheckTimeout
+	if params.CheckTimeout > 0 {
+		checkTimeout = params.CheckTimeout
+	}
+	liveChecks := make(map[string]Check)
+	for _, name := range params.LiveChecks {
+		check, ok := params.Checks[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown live check %q", name)
+		}
+		liveChecks[name] = check
+	}
+	return &Server{
+		logger:       params.Logger,
+		addr:         params.Addr,
+		checks:       params.Checks,
+		liveChecks:   liveChecks,
+		checkTimeout: checkTimeout,
+		metrics:      params.Metrics,
+	}, nil
+}
+
+// Run serves the health endpoints until the
+// given context is done or the server fails.
+func (server *Server) Run(ctx context.Context) error {
+	httpServer := &http.Server{
+		Addr:    server.addr,
+		Handler: server.Handler(),
+	}
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(
+				context.Background(),
+				shutdownTimeout,
+			)
+			defer cancel()
+			_ = httpServer.Shutdown(shutdownCtx)
+		case <-stopped:
+		}
+	}()
+
+	server.logger.Info(
+		"serving health endpoints",
+		zap.String("addr", server.addr),
+	)
+	err := httpServer.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Handler returns the HTTP handler serving
+// the health endpoints and the metrics.
+func (server *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", server.serveLiveness)
+	mux.HandleFunc("/livez", server.serveLiveChecks)
+	mux.HandleFunc("/readyz", server.serveReadiness)
+	if server.metrics != nil {
+		mux.Handle("/metrics", server.metrics)
+	}
+	return mux
+}
+
+// Ready runs every check concurrently and
+// returns a report of the results.
+func (server *Server) Ready(ctx context.Context) *Report {
+	return server.run(ctx, server.checks)
+}
+
+// Live runs the live checks concurrently and
+// returns a report of the results.
+func (server *Server) Live(ctx context.Context) *Report {
+	return server.run(ctx, server.liveChecks)
+}
+
+// run runs the given checks concurrently and
+// returns a report of the results.
+func (server *Server) run(ctx context.Context, checks map[string]Check) *Report {
+	report := &Report{
+		Status: StatusOk,
+		Checks: make(map[string]CheckReport),
+	}
+	var mutex sync.Mutex
+	var wait sync.WaitGroup
+	for name, check := range checks {
+		wait.Add(1)
+		go func(name string, check Check) {
+			defer wait.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, server.checkTimeout)
+			defer cancel()
+			checkReport := CheckReport{Status: StatusOk}
+			err := check(checkCtx)
+			if err != nil {
+				checkReport.Status = StatusFailing
+				checkReport.Error = err.Error()
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			report.Checks[name] = checkReport
+			if err != nil {
+				report.Status = StatusFailing
+			}
+		}(name, check)
+	}
+	wait.Wait()
+	return report
+}
+
+// serveLiveness reports that the process is
+// alive and able to serve requests.
+func (server *Server) serveLiveness(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	server.writeReport(w, &Report{Status: StatusOk})
+}
+
+// serveLiveChecks reports whether every
+// live check passes.
+func (server *Server) serveLiveChecks(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	report := server.Live(r.Context())
+	if report.Status != StatusOk {
+		server.logger.Debug(
+			"not live",
+			zap.Any("checks", report.Checks),
+		)
+	}
+	server.writeReport(w, report)
+}
+
+// serveReadiness reports whether every
+// dependency is ready.
+func (server *Server) serveReadiness(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	report := server.Ready(r.Context())
+	if report.Status != StatusOk {
+		server.logger.Debug(
+			"not ready",
+			zap.Any("checks", report.Checks),
+		)
+	}
+	server.writeReport(w, report)
+}
+
+// writeReport writes the given report as JSON
+// with a status code matching its status.
+func (server *Server) writeReport(
+	w http.ResponseWriter,
+	report *Report,
+) {
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != StatusOk {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	err := json.NewEncoder(w).Encode(report)
+	if err != nil {
+		server.logger.Warn(
+			"failed to write health report",
+			zap.String("err", err.Error()),
+		)
+	}
+}
+
+// Probe requests the given path of the health
+// server listening on the given address and
+// returns an error unless it reports ok.
+func Probe(ctx context.Context, addr string, path string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"GET",
+		"http://"+net.JoinHostPort(host, port)+path,
+		nil,
+	)
+	if err != nil {
+		return errors.New("failed to init request")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fakeZapLogger() *zap.Logger {
+	return zap.New(
+		zapcore.NewCore(
+			zapcore.NewJSONEncoder(
+				zap.NewProductionEncoderConfig(),
+			),
+			zapcore.AddSync(
+				os.NewFile(0, os.DevNull),
+			),
+			zap.FatalLevel,
+		),
+	)
+}
+
+func fakeCheck(err error) Check {
+	return func(ctx context.Context) error {
+		return err
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	type args struct {
+		params *ServerParameters
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "ReturnsServer",
+			args: args{
+				params: &ServerParameters{
+					Logger: fakeZapLogger(),
+					Addr:   ":8080",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingLogger",
+			args: args{
+				params: &ServerParameters{
+					Addr: ":8080",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingAddr",
+			args: args{
+				params: &ServerParameters{
+					Logger: fakeZapLogger(),
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "UnknownLiveCheck",
+			args: args{
+				params: &ServerParameters{
+					Logger:     fakeZapLogger(),
+					Addr:       ":8080",
+					LiveChecks: []string{"slack"},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewServer(tt.args.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewServer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServer_Handler(t *testing.T) {
+	type args struct {
+		path       string
+		checks     map[string]Check
+		liveChecks []string
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantCode   int
+		wantStatus string
+		wantChecks map[string]string
+	}{
+		{
+			name: "Alive",
+			args: args{
+				path: "/healthz",
+				checks: map[string]Check{
+					"slack": fakeCheck(errors.New("disconnected")),
+				},
+			},
+			wantCode:   http.StatusOK,
+			wantStatus: StatusOk,
+		},
+		{
+			name: "Live",
+			args: args{
+				path: "/livez",
+				checks: map[string]Check{
+					"slack":  fakeCheck(nil),
+					"dialog": fakeCheck(errors.New("unreachable")),
+				},
+				liveChecks: []string{"slack"},
+			},
+			wantCode:   http.StatusOK,
+			wantStatus: StatusOk,
+			wantChecks: map[string]string{
+				"slack": StatusOk,
+			},
+		},
+		{
+			name: "NotLive",
+			args: args{
+				path: "/livez",
+				checks: map[string]Check{
+					"slack":  fakeCheck(errors.New("disconnected")),
+					"dialog": fakeCheck(nil),
+				},
+				liveChecks: []string{"slack"},
+			},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: StatusFailing,
+			wantChecks: map[string]string{
+				"slack": StatusFailing,
+			},
+		},
+		{
+			name: "Ready",
+			args: args{
+				path: "/readyz",
+				checks: map[string]Check{
+					"slack":  fakeCheck(nil),
+					"dialog": fakeCheck(nil),
+				},
+			},
+			wantCode:   http.StatusOK,
+			wantStatus: StatusOk,
+			wantChecks: map[string]string{
+				"slack":  StatusOk,
+				"dialog": StatusOk,
+			},
+		},
+		{
+			name: "NotReady",
+			args: args{
+				path: "/readyz",
+				checks: map[string]Check{
+					"slack":  fakeCheck(nil),
+					"dialog": fakeCheck(errors.New("unreachable")),
+				},
+			},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: StatusFailing,
+			wantChecks: map[string]string{
+				"slack":  StatusOk,
+				"dialog": StatusFailing,
+			},
+		},
+		{
+			name: "CheckTimesOut",
+			args: args{
+				path: "/readyz",
+				checks: map[string]Check{
+					"dialog": func(ctx context.Context) error {
+						<-ctx.Done()
+						return ctx.Err()
+					},
+				},
+			},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: StatusFailing,
+			wantChecks: map[string]string{
+				"dialog": StatusFailing,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := NewServer(
+				&ServerParameters{
+					Logger:       fakeZapLogger(),
+					Addr:         ":8080",
+					Checks:       tt.args.checks,
+					LiveChecks:   tt.args.liveChecks,
+					CheckTimeout: 10 * time.Millisecond,
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			server.Handler().ServeHTTP(
+				recorder,
+				httptest.NewRequest("GET", tt.args.path, nil),
+			)
+			if recorder.Code != tt.wantCode {
+				t.Errorf("Handler() code = %v, want %v", recorder.Code, tt.wantCode)
+			}
+
+			var report Report
+			err = json.NewDecoder(recorder.Body).Decode(&report)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Status != tt.wantStatus {
+				t.Errorf("Handler() status = %v, want %v", report.Status, tt.wantStatus)
+			}
+			if len(report.Checks) != len(tt.wantChecks) {
+				t.Errorf("Handler() checks = %v, want %v", report.Checks, tt.wantChecks)
+			}
+			for name, status := range tt.wantChecks {
+				if report.Checks[name].Status != status {
+					t.Errorf("Handler() %v = %v, want %v", name, report.Checks[name].Status, status)
+				}
+			}
+		})
+	}
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		wantErr    bool
+	}{
+		{
+			name:       "Ok",
+			statusCode: http.StatusOK,
+			wantErr:    false,
+		},
+		{
+			name:       "Failing",
+			statusCode: http.StatusServiceUnavailable,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(
+				http.HandlerFunc(
+					func(w http.ResponseWriter, r *http.Request) {
+						if r.URL.Path != "/readyz" {
+							t.Errorf("Probe() path = %v, want %v", r.URL.Path, "/readyz")
+						}
+						w.WriteHeader(tt.statusCode)
+					},
+				),
+			)
+			defer server.Close()
+
+			err := Probe(
+				context.Background(),
+				strings.TrimPrefix(server.URL, "http://"),
+				"/readyz",
+			)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Probe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"github.com/drewnorman/jt-slackbot/core/internal/metrics"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// A Lease is held by at most one replica at
+// a time. Backends other than a shared file
+// lock only need to implement this interface.
+type Lease interface {
+	// Acquire takes the lease if it is free, or
+	// renews it if it is already held, returning
+	// true if the caller holds the lease.
+	Acquire(ctx context.Context) (bool, error)
+
+	// Release gives up the lease if it is held.
+	Release() error
+}
+
+// An Elector campaigns for a Lease so that only
+// the replica holding it, the leader, processes
+// events while the others stand by.
+type Elector struct {
+	logger        *zap.Logger
+	lease         Lease
+	retryInterval time.Duration
+	metrics       *metrics.Metrics
+	mutex         sync.Mutex
+	leading       bool
+	changed       chan struct{}
+}
+
+// ElectorParameters describe how to create a
+// new Elector.
+type ElectorParameters struct {
+	Logger        *zap.Logger
+	Lease         Lease
+	RetryInterval time.Duration
+	Metrics       *metrics.Metrics
+}
+
+// defaultRetryInterval defines how often the
+// lease is acquired or renewed if no interval
+// is specified. Standbys take over within
+// about this long after the leader dies.
+const defaultRetryInterval = 2 * time.Second
+
+// NewElector returns a new Elector according
+// to the given parameters.
+func NewElector(params *ElectorParameters) (*Elector, error) {
+	if params.Logger == nil {
+		return nil, errors.New("missing logger")
+	}
+	if params.Lease == nil {
+		return nil, errors.New("missing lease")
+	}
+	retryInterval := defaultRetryInterval
+	if params.RetryInterval > 0 {
+		retryInterval = params.RetryInterval
+	}
+	return &Elector{
+		logger:        params.Logger,
+		lease:         params.Lease,
+		retryInterval: retryInterval,
+		metrics:       params.Metrics,
+		changed:       make(chan struct{}),
+	}, nil
+}
+
+// Run acquires or renews the lease at every
+// retry interval until the given context is
+// done, then releases it. The Elector stops
+// leading as soon as the lease cannot be
+// renewed, so that two replicas never lead
+// at once.
+func (elector *Elector) Run(ctx context.Context) error {
+	elector.metrics.Leading(false)
+	ticker := time.NewTicker(elector.retryInterval)
+	defer ticker.Stop()
+	for {
+		held, err := elector.lease.Acquire(ctx)
+		if err != nil {
+			elector.logger.Warn(
+				"failed to acquire leader lease",
+				zap.String("err", err.Error()),
+			)
+			held = false
+		}
+		elector.setLeading(held)
+
+		select {
+		case <-ctx.Done():
+			elector.setLeading(false)
+			return elector.lease.Release()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Leading returns true if the Elector holds
+// the lease.
+func (elector *Elector) Leading() bool {
+	elector.mutex.Lock()
+	defer elector.mutex.Unlock()
+	return elector.leading
+}
+
+// Changed returns a channel that is closed the
+// next time the Elector starts or stops leading.
+func (elector *Elector) Changed() <-chan struct{} {
+	elector.mutex.Lock()
+	defer elector.mutex.Unlock()
+	return elector.changed
+}
+
+// setLeading records whether the Elector holds
+// the lease, logging and announcing any change.
+func (elector *Elector) setLeading(leading bool) {
+	elector.mutex.Lock()
+	changed := elector.leading != leading
+	elector.leading = leading
+	if changed {
+		close(elector.changed)
+		elector.changed = make(chan struct{})
+	}
+	elector.mutex.Unlock()
+
+	elector.metrics.Leading(leading)
+	if !changed {
+		return
+	}
+	if leading {
+		elector.logger.Info("became leader")
+		return
+	}
+	elector.logger.Info("standing by for leader")
+}
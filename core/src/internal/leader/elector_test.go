@@ -0,0 +1,154 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func fakeZapLogger() *zap.Logger {
+	return zap.New(
+		zapcore.NewCore(
+			zapcore.NewJSONEncoder(
+				zap.NewProductionEncoderConfig(),
+			),
+			zapcore.AddSync(
+				os.NewFile(0, os.DevNull),
+			),
+			zap.FatalLevel,
+		),
+	)
+}
+
+type fakeLease struct {
+	mutex    sync.Mutex
+	held     bool
+	err      error
+	released bool
+}
+
+func (lease *fakeLease) Acquire(ctx context.Context) (bool, error) {
+	lease.mutex.Lock()
+	defer lease.mutex.Unlock()
+	return lease.held, lease.err
+}
+
+func (lease *fakeLease) Release() error {
+	lease.mutex.Lock()
+	defer lease.mutex.Unlock()
+	lease.released = true
+	return nil
+}
+
+func (lease *fakeLease) set(held bool, err error) {
+	lease.mutex.Lock()
+	defer lease.mutex.Unlock()
+	lease.held = held
+	lease.err = err
+}
+
+func TestNewElector(t *testing.T) {
+	type args struct {
+		params *ElectorParameters
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "ReturnsElector",
+			args: args{
+				params: &ElectorParameters{
+					Logger: fakeZapLogger(),
+					Lease:  &fakeLease{},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingLogger",
+			args: args{
+				params: &ElectorParameters{
+					Lease: &fakeLease{},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingLease",
+			args: args{
+				params: &ElectorParameters{
+					Logger: fakeZapLogger(),
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewElector(tt.args.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewElector() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestElector_Run(t *testing.T) {
+	lease := &fakeLease{}
+	elector, err := NewElector(
+		&ElectorParameters{
+			Logger:        fakeZapLogger(),
+			Lease:         lease,
+			RetryInterval: time.Millisecond,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() {
+		stopped <- elector.Run(ctx)
+	}()
+
+	waitForLeading := func(want bool) {
+		deadline := time.Now().Add(time.Second)
+		for elector.Leading() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("Leading() = %v, want %v", !want, want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	waitForLeading(false)
+	leadershipChanged := elector.Changed()
+	lease.set(true, nil)
+	waitForLeading(true)
+	select {
+	case <-leadershipChanged:
+	default:
+		t.Errorf("Changed() was not closed when leading")
+	}
+	lease.set(true, errors.New("fake lease error"))
+	waitForLeading(false)
+	lease.set(true, nil)
+	waitForLeading(true)
+
+	cancel()
+	err = <-stopped
+	if err != nil {
+		t.Errorf("Run() error = %v, wantErr %v", err, false)
+	}
+	if elector.Leading() || !lease.released {
+		t.Errorf("Run() did not release the lease when stopped")
+	}
+}
@@ -0,0 +1,96 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// A FileLease is a Lease held through an
+// exclusive lock on a file shared by every
+// replica. The operating system releases the
+// lock as soon as the holding process dies,
+// so a standby takes over at its next try.
+type FileLease struct {
+	path   string
+	holder string
+	mutex  sync.Mutex
+	file   *os.File
+}
+
+// NewFileLease returns a new FileLease locking
+// the file at the given path and recording the
+// given holder in it while the lease is held.
+func NewFileLease(path string, holder string) (*FileLease, error) {
+	if path == "" {
+		return nil, errors.New("missing path")
+	}
+	if holder == "" {
+		return nil, errors.New("missing holder")
+	}
+	return &FileLease{
+		path:   path,
+		holder: holder,
+	}, nil
+}
+
+// Acquire locks the file unless another process
+// holds the lock, returning true if the lock is
+// held by the FileLease.
+func (lease *FileLease) Acquire(ctx context.Context) (bool, error) {
+	lease.mutex.Lock()
+	defer lease.mutex.Unlock()
+	if lease.file != nil {
+		return true, nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(lease.path), 0755)
+	if err != nil {
+		return false, err
+	}
+	file, err := os.OpenFile(lease.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return false, err
+	}
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		_ = file.Close()
+		return false, nil
+	}
+	if err != nil {
+		_ = file.Close()
+		return false, err
+	}
+
+	err = file.Truncate(0)
+	if err == nil {
+		_, err = file.WriteAt([]byte(lease.holder+"\n"), 0)
+	}
+	if err != nil {
+		_ = file.Close()
+		return false, err
+	}
+	lease.file = file
+	return true, nil
+}
+
+// Release unlocks the file if the lock is held
+// by the FileLease.
+func (lease *FileLease) Release() error {
+	lease.mutex.Lock()
+	defer lease.mutex.Unlock()
+	if lease.file == nil {
+		return nil
+	}
+	file := lease.file
+	lease.file = nil
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,98 @@
+package leader
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewFileLease(t *testing.T) {
+	type args struct {
+		path   string
+		holder string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "ReturnsFileLease",
+			args: args{
+				path:   "/tmp/leader.lock",
+				holder: "replica-1",
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingPath",
+			args: args{
+				holder: "replica-1",
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingHolder",
+			args: args{
+				path: "/tmp/leader.lock",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFileLease(tt.args.path, tt.args.holder)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewFileLease() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileLease_Acquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	first, err := NewFileLease(path, "replica-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewFileLease(path, "replica-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	held, err := first.Acquire(ctx)
+	if err != nil || !held {
+		t.Errorf("Acquire() = %v, error = %v, want %v", held, err, true)
+	}
+	held, err = first.Acquire(ctx)
+	if err != nil || !held {
+		t.Errorf("Acquire() = %v, error = %v, want %v", held, err, true)
+	}
+	held, err = second.Acquire(ctx)
+	if err != nil || held {
+		t.Errorf("Acquire() = %v, error = %v, want %v", held, err, false)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "replica-1\n" {
+		t.Errorf("Acquire() holder = %q, want %q", data, "replica-1\n")
+	}
+
+	err = first.Release()
+	if err != nil {
+		t.Errorf("Release() error = %v, wantErr %v", err, false)
+	}
+	held, err = second.Acquire(ctx)
+	if err != nil || !held {
+		t.Errorf("Acquire() = %v, error = %v, want %v", held, err, true)
+	}
+	err = second.Release()
+	if err != nil {
+		t.Errorf("Release() error = %v, wantErr %v", err, false)
+	}
+}
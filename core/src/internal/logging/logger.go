@@ -8,10 +8,14 @@ import (
 )
 
 // LoggerParameters specify how a new
-// zap.Logger should be created.
+// zap.Logger should be created. If an
+// AtomicLevel is given, it is used instead
+// of the Level so that the level can be
+// changed while the zap.Logger is in use.
 type LoggerParameters struct {
-	Level   zapcore.Level
-	Writers []io.Writer
+	Level       zapcore.Level
+	AtomicLevel *zap.AtomicLevel
+	Writers     []io.Writer
 }
 
 // NewLogger returns a new instance of
@@ -41,6 +45,11 @@ func NewLogger(params LoggerParameters) *zap.Logger {
 		}
 	}
 
+	var level zapcore.LevelEnabler = params.Level
+	if params.AtomicLevel != nil {
+		level = params.AtomicLevel
+	}
+
 	encoderConfig := zap.NewProductionEncoderConfig()
 	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
@@ -50,7 +59,7 @@ func NewLogger(params LoggerParameters) *zap.Logger {
 				encoderConfig,
 			),
 			writeSyncer,
-			params.Level,
+			level,
 		),
 	)
 }
@@ -2,12 +2,18 @@ package logging
 
 import (
 	"errors"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"io"
 	"os"
 	"testing"
 )
 
+func fakeAtomicLevel(level zapcore.Level) *zap.AtomicLevel {
+	atomicLevel := zap.NewAtomicLevelAt(level)
+	return &atomicLevel
+}
+
 func TestNewLogger(t *testing.T) {
 	type args struct {
 		params        *LoggerParameters
@@ -33,6 +39,16 @@ func TestNewLogger(t *testing.T) {
 				expectedLevel: zapcore.ErrorLevel,
 			},
 		},
+		{
+			name: "ReturnsLoggerWithAtomicLevel",
+			args: args{
+				params: &LoggerParameters{
+					Level:       zapcore.ErrorLevel,
+					AtomicLevel: fakeAtomicLevel(zapcore.WarnLevel),
+				},
+				expectedLevel: zapcore.WarnLevel,
+			},
+		},
 		{
 			name: "MultipleWriters",
 			args: args{
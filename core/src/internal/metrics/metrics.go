@@ -0,0 +1,311 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+// Metrics collects Prometheus metrics about the
+// Slack connection, event processing, and calls
+// to the Slack API and the dialog service. A nil
+// *Metrics is valid and records nothing, so
+// components may be created without one.
+type Metrics struct {
+	registry          *prometheus.Registry
+	connects          prometheus.Counter
+	disconnects       *prometheus.CounterVec
+	envelopes         *prometheus.CounterVec
+	ackLatency        prometheus.Histogram
+	events            *prometheus.CounterVec
+	eventDurations    *prometheus.HistogramVec
+	rateLimited       *prometheus.CounterVec
+	cacheLookups      *prometheus.CounterVec
+	slackApiCalls     *prometheus.CounterVec
+	slackApiDurations *prometheus.HistogramVec
+	dialogCalls       *prometheus.CounterVec
+	dialogDurations   *prometheus.HistogramVec
+	leader            prometheus.Gauge
+}
+
+// namespace prefixes the names of all metrics.
+const namespace = "jt_slackbot"
+
+// Outcomes of handled events.
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+	OutcomeIgnored   = "ignored"
+	OutcomeDuplicate = "duplicate"
+	OutcomeAbandoned = "abandoned"
+)
+
+// Codes recorded for Slack API calls that did
+// not return an error code of their own.
+const (
+	CodeOk            = "ok"
+	CodeRateLimited   = "ratelimited"
+	CodeRequestFailed = "request_failed"
+	CodeBadResponse   = "bad_response"
+)
+
+// New returns a new instance of Metrics with
+// every metric registered, along with the Go
+// runtime and process metrics.
+func New() *Metrics {
+	metrics := &Metrics{
+		registry: prometheus.NewRegistry(),
+		connects: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "socket_mode_connects_total",
+				Help:      "Connections made to Slack in Socket Mode.",
+			},
+		),
+		disconnects: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "socket_mode_disconnects_total",
+				Help:      "Disconnections from Slack in Socket Mode by reason.",
+			},
+			[]string{"reason"},
+		),
+		envelopes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "envelopes_total",
+				Help:      "Socket Mode envelopes received by type.",
+			},
+			[]string{"type"},
+		),
+		ackLatency: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "envelope_ack_seconds",
+				Help:      "Time taken to acknowledge Socket Mode envelopes.",
+				Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+			},
+		),
+		events: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "events_total",
+				Help:      "Events handled by type and outcome.",
+			},
+			[]string{"type", "outcome"},
+		),
+		eventDurations: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "event_duration_seconds",
+				Help:      "Time taken to process events, including retries, by type.",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"type"},
+		),
+		rateLimited: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "rate_limited_total",
+				Help:      "App mentions left unanswered by the rate limits by scope.",
+			},
+			[]string{"scope"},
+		),
+		cacheLookups: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "response_cache_lookups_total",
+				Help:      "Lookups in the response cache by result: hit or miss.",
+			},
+			[]string{"result"},
+		),
+		slackApiCalls: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "slack_api_calls_total",
+				Help:      "Slack API calls by method and result code.",
+			},
+			[]string{"method", "code"},
+		),
+		slackApiDurations: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "slack_api_call_duration_seconds",
+				Help:      "Time taken by Slack API calls by method.",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"method"},
+		),
+		dialogCalls: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "dialog_requests_total",
+				Help:      "Requests to the dialog service by endpoint and result.",
+			},
+			[]string{"endpoint", "result"},
+		),
+		dialogDurations: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "dialog_request_duration_seconds",
+				Help:      "Time taken by requests to the dialog service by endpoint.",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"endpoint"},
+		),
+		leader: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "leader",
+				Help:      "Whether this replica holds the leader lease and processes events.",
+			},
+		),
+	}
+	metrics.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		metrics.connects,
+		metrics.disconnects,
+		metrics.envelopes,
+		metrics.ackLatency,
+		metrics.events,
+		metrics.eventDurations,
+		metrics.rateLimited,
+		metrics.cacheLookups,
+		metrics.slackApiCalls,
+		metrics.slackApiDurations,
+		metrics.dialogCalls,
+		metrics.dialogDurations,
+		metrics.leader,
+	)
+	return metrics
+}
+
+// Handler returns the HTTP handler exposing
+// the metrics to Prometheus.
+func (metrics *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
+}
+
+// Connected records a connection to Slack.
+func (metrics *Metrics) Connected() {
+	if metrics == nil {
+		return
+	}
+	metrics.connects.Inc()
+}
+
+// Disconnected records a disconnection from
+// Slack for the given reason.
+func (metrics *Metrics) Disconnected(reason string) {
+	if metrics == nil {
+		return
+	}
+	metrics.disconnects.WithLabelValues(reason).Inc()
+}
+
+// EnvelopeReceived records an envelope of the
+// given type received from Slack.
+func (metrics *Metrics) EnvelopeReceived(envelopeType string) {
+	if metrics == nil {
+		return
+	}
+	metrics.envelopes.WithLabelValues(envelopeType).Inc()
+}
+
+// EnvelopeAcknowledged records how long it took
+// to acknowledge an envelope.
+func (metrics *Metrics) EnvelopeAcknowledged(latency time.Duration) {
+	if metrics == nil {
+		return
+	}
+	metrics.ackLatency.Observe(latency.Seconds())
+}
+
+// EventHandled records the outcome of handling
+// an event of the given type. The duration is
+// only recorded for events that were processed.
+func (metrics *Metrics) EventHandled(
+	eventType string,
+	outcome string,
+	duration time.Duration,
+) {
+	if metrics == nil {
+		return
+	}
+	metrics.events.WithLabelValues(eventType, outcome).Inc()
+	if outcome == OutcomeSucceeded || outcome == OutcomeFailed {
+		metrics.eventDurations.WithLabelValues(eventType).Observe(duration.Seconds())
+	}
+}
+
+// RateLimited records an app mention left
+// unanswered by the rate limit of the given
+// scope: user, channel or global.
+func (metrics *Metrics) RateLimited(scope string) {
+	if metrics == nil {
+		return
+	}
+	metrics.rateLimited.WithLabelValues(scope).Inc()
+}
+
+// CacheLookedUp records a lookup in the
+// response cache, which was a hit if hit is
+// true and a miss otherwise.
+func (metrics *Metrics) CacheLookedUp(hit bool) {
+	if metrics == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	metrics.cacheLookups.WithLabelValues(result).Inc()
+}
+
+// SlackApiCalled records a call to the given
+// Slack API method resulting in the given code.
+func (metrics *Metrics) SlackApiCalled(
+	method string,
+	code string,
+	duration time.Duration,
+) {
+	if metrics == nil {
+		return
+	}
+	metrics.slackApiCalls.WithLabelValues(method, code).Inc()
+	metrics.slackApiDurations.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+// DialogCalled records a request to the given
+// endpoint of the dialog service, which failed
+// if err is not nil.
+func (metrics *Metrics) DialogCalled(
+	endpoint string,
+	duration time.Duration,
+	err error,
+) {
+	if metrics == nil {
+		return
+	}
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	metrics.dialogCalls.WithLabelValues(endpoint, result).Inc()
+	metrics.dialogDurations.WithLabelValues(endpoint).Observe(duration.Seconds())
+}
+
+// Leading records whether this replica is
+// the leader.
+func (metrics *Metrics) Leading(leading bool) {
+	if metrics == nil {
+		return
+	}
+	if leading {
+		metrics.leader.Set(1)
+		return
+	}
+	metrics.leader.Set(0)
+}
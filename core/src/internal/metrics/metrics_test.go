@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics_Handler(t *testing.T) {
+	tests := []struct {
+		name   string
+		record func(metrics *Metrics)
+		want   string
+	}{
+		{
+			name: "Leads",
+			record: func(metrics *Metrics) {
+				metrics.Leading(true)
+			},
+			want: "jt_slackbot_leader 1",
+		},
+		{
+			name: "Connects",
+			record: func(metrics *Metrics) {
+				metrics.Connected()
+			},
+			want: "jt_slackbot_socket_mode_connects_total 1",
+		},
+		{
+			name: "DisconnectsByReason",
+			record: func(metrics *Metrics) {
+				metrics.Disconnected("refresh_requested")
+			},
+			want: `jt_slackbot_socket_mode_disconnects_total{reason="refresh_requested"} 1`,
+		},
+		{
+			name: "EnvelopesByType",
+			record: func(metrics *Metrics) {
+				metrics.EnvelopeReceived("events_api")
+				metrics.EnvelopeReceived("events_api")
+			},
+			want: `jt_slackbot_envelopes_total{type="events_api"} 2`,
+		},
+		{
+			name: "AckLatency",
+			record: func(metrics *Metrics) {
+				metrics.EnvelopeAcknowledged(time.Millisecond)
+			},
+			want: "jt_slackbot_envelope_ack_seconds_count 1",
+		},
+		{
+			name: "EventsByTypeAndOutcome",
+			record: func(metrics *Metrics) {
+				metrics.EventHandled("app_mention", OutcomeFailed, time.Second)
+			},
+			want: `jt_slackbot_events_total{outcome="failed",type="app_mention"} 1`,
+		},
+		{
+			name: "EventDurations",
+			record: func(metrics *Metrics) {
+				metrics.EventHandled("app_mention", OutcomeSucceeded, time.Second)
+				metrics.EventHandled("app_mention", OutcomeIgnored, 0)
+			},
+			want: `jt_slackbot_event_duration_seconds_count{type="app_mention"} 1`,
+		},
+		{
+			name: "RateLimitedByScope",
+			record: func(metrics *Metrics) {
+				metrics.RateLimited("channel")
+			},
+			want: `jt_slackbot_rate_limited_total{scope="channel"} 1`,
+		},
+		{
+			name: "CacheLookupsByResult",
+			record: func(metrics *Metrics) {
+				metrics.CacheLookedUp(true)
+				metrics.CacheLookedUp(false)
+			},
+			want: `jt_slackbot_response_cache_lookups_total{result="hit"} 1`,
+		},
+		{
+			name: "SlackApiCallsByMethodAndCode",
+			record: func(metrics *Metrics) {
+				metrics.SlackApiCalled("chat.postMessage", CodeRateLimited, time.Second)
+			},
+			want: `jt_slackbot_slack_api_calls_total{code="ratelimited",method="chat.postMessage"} 1`,
+		},
+		{
+			name: "DialogErrors",
+			record: func(metrics *Metrics) {
+				metrics.DialogCalled("converse", time.Second, errors.New("unreachable"))
+			},
+			want: `jt_slackbot_dialog_requests_total{endpoint="converse",result="error"} 1`,
+		},
+		{
+			name: "DialogLatency",
+			record: func(metrics *Metrics) {
+				metrics.DialogCalled("converse", time.Second, nil)
+			},
+			want: `jt_slackbot_dialog_request_duration_seconds_count{endpoint="converse"} 1`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := New()
+			tt.record(metrics)
+
+			recorder := httptest.NewRecorder()
+			metrics.Handler().ServeHTTP(
+				recorder,
+				httptest.NewRequest("GET", "/metrics", nil),
+			)
+			body, err := ioutil.ReadAll(recorder.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(body), tt.want) {
+				t.Errorf("Handler() = %v, want %v", string(body), tt.want)
+			}
+		})
+	}
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	var metrics *Metrics
+	metrics.Connected()
+	metrics.Disconnected("shutdown")
+	metrics.EnvelopeReceived("hello")
+	metrics.EnvelopeAcknowledged(time.Millisecond)
+	metrics.EventHandled("message", OutcomeSucceeded, time.Second)
+	metrics.RateLimited("user")
+	metrics.CacheLookedUp(true)
+	metrics.SlackApiCalled("auth.test", CodeOk, time.Second)
+	metrics.DialogCalled("converse", time.Second, nil)
+	metrics.Leading(true)
+}
@@ -0,0 +1,233 @@
+package responders
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"github.com/drewnorman/jt-slackbot/core/internal/metrics"
+	"sync"
+	"time"
+)
+
+// A CachingResponder remembers the responses of
+// a responder to repeated messages for a while
+// so the responder is not asked again. Messages
+// are matched ignoring case and spacing, and
+// optionally only within the same channel.
+// Messages that continue a conversation are
+// never answered from the cache, since the
+// reply depends on what was said before.
+type CachingResponder struct {
+	responder      Responder
+	ttl            time.Duration
+	size           int
+	scopeByChannel bool
+	now            func() time.Time
+	mutex          sync.Mutex
+	order          *list.List
+	entries        map[string]*list.Element
+	bypass         bool
+	stats          CacheStats
+	metrics        *metrics.Metrics
+}
+
+// responders.CachingResponderParameters describe
+// how a new CachingResponder should be created.
+type CachingResponderParameters struct {
+	Responder      Responder
+	Ttl            time.Duration
+	Size           int
+	ScopeByChannel bool
+	Metrics        *metrics.Metrics
+}
+
+// CacheStats report how well a CachingResponder
+// is doing and how many responses it holds.
+type CacheStats struct {
+	Hits      int
+	Misses    int
+	Evictions int
+	Size      int
+	Bypassed  bool
+}
+
+// A cacheEntry is a cached response that
+// expires at expiresAt.
+type cacheEntry struct {
+	key       string
+	text      string
+	response  Response
+	expiresAt time.Time
+}
+
+// NewCachingResponder returns a new
+// CachingResponder according to the given
+// parameters.
+func NewCachingResponder(
+	params *CachingResponderParameters,
+) (*CachingResponder, error) {
+	if params.Responder == nil {
+		return nil, errors.New("missing responder")
+	}
+	if params.Ttl <= 0 {
+		return nil, errors.New("ttl must be positive")
+	}
+	if params.Size < 1 {
+		return nil, errors.New("size must be at least 1")
+	}
+	return &CachingResponder{
+		responder:      params.Responder,
+		ttl:            params.Ttl,
+		size:           params.Size,
+		scopeByChannel: params.ScopeByChannel,
+		metrics:        params.Metrics,
+		now:            time.Now,
+		order:          list.New(),
+		entries:        make(map[string]*list.Element),
+	}, nil
+}
+
+// Respond returns the cached response to the
+// given request if there is one, or delegates
+// to the responder and caches its response.
+func (responder *CachingResponder) Respond(
+	ctx context.Context,
+	request *Request,
+) (*Response, error) {
+	if responder.bypassed() || len(request.History) > 0 {
+		return responder.responder.Respond(ctx, request)
+	}
+
+	key := responder.key(request)
+	response, ok := responder.lookup(key)
+	responder.metrics.CacheLookedUp(ok)
+	if ok {
+		return response, nil
+	}
+
+	response, err := responder.responder.Respond(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	responder.store(key, request.Text, response)
+	return response, nil
+}
+
+// Purge removes every cached response.
+func (responder *CachingResponder) Purge() {
+	responder.mutex.Lock()
+	defer responder.mutex.Unlock()
+
+	responder.order.Init()
+	responder.entries = make(map[string]*list.Element)
+}
+
+// Invalidate removes the cached responses to
+// the given message text in every channel.
+func (responder *CachingResponder) Invalidate(text string) {
+	responder.mutex.Lock()
+	defer responder.mutex.Unlock()
+
+	normalized := recallKey(text)
+	for element := responder.order.Front(); element != nil; {
+		next := element.Next()
+		if recallKey(element.Value.(*cacheEntry).text) == normalized {
+			responder.remove(element)
+		}
+		element = next
+	}
+}
+
+// SetBypass makes the responder skip the cache
+// entirely while bypass is true.
+func (responder *CachingResponder) SetBypass(bypass bool) {
+	responder.mutex.Lock()
+	defer responder.mutex.Unlock()
+	responder.bypass = bypass
+}
+
+// Stats returns the hit and miss counts and
+// the current size of the cache.
+func (responder *CachingResponder) Stats() CacheStats {
+	responder.mutex.Lock()
+	defer responder.mutex.Unlock()
+
+	stats := responder.stats
+	stats.Size = responder.order.Len()
+	stats.Bypassed = responder.bypass
+	return stats
+}
+
+// bypassed returns true if the cache is
+// being bypassed.
+func (responder *CachingResponder) bypassed() bool {
+	responder.mutex.Lock()
+	defer responder.mutex.Unlock()
+	return responder.bypass
+}
+
+// key returns the cache key for the given
+// request.
+func (responder *CachingResponder) key(request *Request) string {
+	key := recallKey(request.Text)
+	if responder.scopeByChannel {
+		key = request.ChannelId + ":" + key
+	}
+	return key
+}
+
+// lookup returns a copy of the unexpired
+// response cached under the given key.
+func (responder *CachingResponder) lookup(key string) (*Response, bool) {
+	responder.mutex.Lock()
+	defer responder.mutex.Unlock()
+
+	element, ok := responder.entries[key]
+	if ok && responder.now().After(element.Value.(*cacheEntry).expiresAt) {
+		responder.remove(element)
+		ok = false
+	}
+	if !ok {
+		responder.stats.Misses++
+		return nil, false
+	}
+	responder.stats.Hits++
+	responder.order.MoveToFront(element)
+	response := element.Value.(*cacheEntry).response
+	return &response, true
+}
+
+// store caches the given response under the
+// given key, evicting the least recently used
+// response if the cache is full.
+func (responder *CachingResponder) store(
+	key string,
+	text string,
+	response *Response,
+) {
+	responder.mutex.Lock()
+	defer responder.mutex.Unlock()
+
+	if element, ok := responder.entries[key]; ok {
+		responder.remove(element)
+	}
+	responder.entries[key] = responder.order.PushFront(
+		&cacheEntry{
+			key:       key,
+			text:      text,
+			response:  *response,
+			expiresAt: responder.now().Add(responder.ttl),
+		},
+	)
+	if responder.order.Len() > responder.size {
+		responder.remove(responder.order.Back())
+		responder.stats.Evictions++
+	}
+}
+
+// remove removes the entry held by the given
+// element. The caller must hold the mutex.
+func (responder *CachingResponder) remove(element *list.Element) {
+	responder.order.Remove(element)
+	delete(responder.entries, element.Value.(*cacheEntry).key)
+}
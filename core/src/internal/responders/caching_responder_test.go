@@ -0,0 +1,246 @@
+package responders
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewCachingResponder(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  *CachingResponderParameters
+		wantErr bool
+	}{
+		{
+			name: "ReturnsCachingResponder",
+			params: &CachingResponderParameters{
+				Responder: &fakeResponder{text: "answer"},
+				Ttl:       time.Minute,
+				Size:      10,
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingResponder",
+			params: &CachingResponderParameters{
+				Ttl:  time.Minute,
+				Size: 10,
+			},
+			wantErr: true,
+		},
+		{
+			name: "InvalidTtl",
+			params: &CachingResponderParameters{
+				Responder: &fakeResponder{text: "answer"},
+				Size:      10,
+			},
+			wantErr: true,
+		},
+		{
+			name: "InvalidSize",
+			params: &CachingResponderParameters{
+				Responder: &fakeResponder{text: "answer"},
+				Ttl:       time.Minute,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCachingResponder(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewCachingResponder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCachingResponder_Respond(t *testing.T) {
+	tests := []struct {
+		name           string
+		scopeByChannel bool
+		err            error
+		first          *Request
+		second         *Request
+		advance        time.Duration
+		bypass         bool
+		wantCalls      int
+		wantHits       int
+		wantErr        bool
+	}{
+		{
+			name:      "HitsRepeatedQuestion",
+			first:     &Request{Text: "What is JT?", ChannelId: "C1"},
+			second:    &Request{Text: "  what is   jt? ", ChannelId: "C2"},
+			wantCalls: 1,
+			wantHits:  1,
+			wantErr:   false,
+		},
+		{
+			name:           "MissesOtherChannel",
+			scopeByChannel: true,
+			first:          &Request{Text: "What is JT?", ChannelId: "C1"},
+			second:         &Request{Text: "What is JT?", ChannelId: "C2"},
+			wantCalls:      2,
+			wantHits:       0,
+			wantErr:        false,
+		},
+		{
+			name:      "MissesExpired",
+			first:     &Request{Text: "What is JT?", ChannelId: "C1"},
+			second:    &Request{Text: "What is JT?", ChannelId: "C1"},
+			advance:   2 * time.Minute,
+			wantCalls: 2,
+			wantHits:  0,
+			wantErr:   false,
+		},
+		{
+			name:  "SkipsRequestsWithHistory",
+			first: &Request{Text: "What is JT?", ChannelId: "C1"},
+			second: &Request{
+				Text:      "What is JT?",
+				ChannelId: "C1",
+				History:   []*Message{{UserId: "U1", Text: "Tell me about dogs"}},
+			},
+			wantCalls: 2,
+			wantHits:  0,
+			wantErr:   false,
+		},
+		{
+			name: "DoesNotCacheRequestsWithHistory",
+			first: &Request{
+				Text:      "What is JT?",
+				ChannelId: "C1",
+				History:   []*Message{{UserId: "U1", Text: "Tell me about dogs"}},
+			},
+			second:    &Request{Text: "What is JT?", ChannelId: "C1"},
+			wantCalls: 2,
+			wantHits:  0,
+			wantErr:   false,
+		},
+		{
+			name:      "Bypassed",
+			first:     &Request{Text: "What is JT?", ChannelId: "C1"},
+			second:    &Request{Text: "What is JT?", ChannelId: "C1"},
+			bypass:    true,
+			wantCalls: 2,
+			wantHits:  0,
+			wantErr:   false,
+		},
+		{
+			name:      "DoesNotCacheErrors",
+			err:       errors.New("unavailable"),
+			first:     &Request{Text: "What is JT?", ChannelId: "C1"},
+			second:    &Request{Text: "What is JT?", ChannelId: "C1"},
+			wantCalls: 2,
+			wantHits:  0,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &fakeFailingResponder{err: tt.err}
+			responder, err := NewCachingResponder(
+				&CachingResponderParameters{
+					Responder:      inner,
+					Ttl:            time.Minute,
+					Size:           10,
+					ScopeByChannel: tt.scopeByChannel,
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			now := time.Now()
+			responder.now = func() time.Time { return now }
+			responder.SetBypass(tt.bypass)
+
+			_, _ = responder.Respond(context.Background(), tt.first)
+			now = now.Add(tt.advance)
+			response, err := responder.Respond(context.Background(), tt.second)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Respond() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && response.Text != "recovered" {
+				t.Errorf("Respond() text = %v, want recovered", response.Text)
+			}
+			if inner.calls != tt.wantCalls {
+				t.Errorf("Respond() calls = %v, want %v", inner.calls, tt.wantCalls)
+			}
+			if hits := responder.Stats().Hits; hits != tt.wantHits {
+				t.Errorf("Stats() hits = %v, want %v", hits, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestCachingResponder_Evicts(t *testing.T) {
+	responder, err := NewCachingResponder(
+		&CachingResponderParameters{
+			Responder: &fakeResponder{text: "answer"},
+			Ttl:       time.Minute,
+			Size:      2,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"one", "two", "three"} {
+		_, _ = responder.Respond(context.Background(), &Request{Text: text})
+	}
+	stats := responder.Stats()
+	if stats.Size != 2 || stats.Evictions != 1 || stats.Misses != 3 {
+		t.Errorf("Stats() = %+v, want size 2, 1 eviction and 3 misses", stats)
+	}
+}
+
+func TestCachingResponder_Purge(t *testing.T) {
+	tests := []struct {
+		name     string
+		purge    func(responder *CachingResponder)
+		wantSize int
+	}{
+		{
+			name:     "PurgesEverything",
+			purge:    func(responder *CachingResponder) { responder.Purge() },
+			wantSize: 0,
+		},
+		{
+			name: "InvalidatesQuestion",
+			purge: func(responder *CachingResponder) {
+				responder.Invalidate("WHAT is jt?")
+			},
+			wantSize: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responder, err := NewCachingResponder(
+				&CachingResponderParameters{
+					Responder:      &fakeResponder{text: "answer"},
+					Ttl:            time.Minute,
+					Size:           10,
+					ScopeByChannel: true,
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			requests := []*Request{
+				{Text: "What is JT?", ChannelId: "C1"},
+				{Text: "What is JT?", ChannelId: "C2"},
+				{Text: "Who are you?", ChannelId: "C1"},
+			}
+			for _, request := range requests {
+				_, _ = responder.Respond(context.Background(), request)
+			}
+			tt.purge(responder)
+			if size := responder.Stats().Size; size != tt.wantSize {
+				t.Errorf("Stats() size = %v, want %v", size, tt.wantSize)
+			}
+		})
+	}
+}
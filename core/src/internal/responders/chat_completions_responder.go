@@ -0,0 +1,175 @@
+package responders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/drewnorman/jt-slackbot/core/internal/secrets"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// A ChatCompletionsResponder replies to
+// messages using an OpenAI-compatible chat
+// completions HTTP API.
+type ChatCompletionsResponder struct {
+	baseUrl      string
+	apiKey       secrets.Secret
+	model        string
+	systemPrompt string
+	httpClient   *http.Client
+}
+
+// ChatCompletionsResponderParameters describe
+// how a new ChatCompletionsResponder should
+// be created.
+type ChatCompletionsResponderParameters struct {
+	BaseUrl      string
+	ApiKey       string
+	Model        string
+	SystemPrompt string
+	Timeout      time.Duration
+	HttpClient   *http.Client
+}
+
+// A chatMessage is a single message in a
+// chat completions request or response.
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// A chatCompletionsRequest is the body of a
+// chat completions request.
+type chatCompletionsRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	User     string        `json:"user,omitempty"`
+}
+
+// A chatCompletionsResponse is the body of a
+// chat completions response.
+type chatCompletionsResponse struct {
+	Choices []struct {
+		Message      chatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// defaultChatCompletionsTimeout specifies the
+// timeout for chat completions requests.
+const defaultChatCompletionsTimeout = 30 * time.Second
+
+// NewChatCompletionsResponder returns a new
+// ChatCompletionsResponder according to the
+// given parameters.
+func NewChatCompletionsResponder(
+	params *ChatCompletionsResponderParameters,
+) (*ChatCompletionsResponder, error) {
+	if params.BaseUrl == "" {
+		return nil, errors.New("missing base url")
+	}
+	if params.Model == "" {
+		return nil, errors.New("missing model")
+	}
+	httpClient := params.HttpClient
+	if httpClient == nil {
+		timeout := defaultChatCompletionsTimeout
+		if params.Timeout > 0 {
+			timeout = params.Timeout
+		}
+		httpClient = &http.Client{
+			Timeout: timeout,
+		}
+	}
+	return &ChatCompletionsResponder{
+		baseUrl:      strings.TrimSuffix(params.BaseUrl, "/") + "/",
+		apiKey:       secrets.New(params.ApiKey),
+		model:        params.Model,
+		systemPrompt: params.SystemPrompt,
+		httpClient:   httpClient,
+	}, nil
+}
+
+// Respond requests a chat completion for the
+// given request and replies with the first
+// choice.
+func (responder *ChatCompletionsResponder) Respond(
+	ctx context.Context,
+	request *Request,
+) (*Response, error) {
+	var messages []chatMessage
+	if responder.systemPrompt != "" {
+		messages = append(messages, chatMessage{
+			Role:    "system",
+			Content: responder.systemPrompt,
+		})
+	}
+	messages = append(messages, chatMessage{
+		Role:    "user",
+		Content: request.Text,
+	})
+
+	jsonData, err := json.Marshal(
+		chatCompletionsRequest{
+			Model:    responder.model,
+			Messages: messages,
+			User:     request.UserId,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		responder.baseUrl+"chat/completions",
+		bytes.NewReader(jsonData),
+	)
+	if err != nil {
+		return nil, errors.New("failed to init request")
+	}
+	req.Header.Add("Content-Type", "application/json")
+	if !responder.apiKey.Empty() {
+		req.Header.Add("Authorization", "Bearer "+responder.apiKey.Reveal())
+	}
+
+	resp, err := responder.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf(
+			"chat completions api responded with status %d",
+			resp.StatusCode,
+		)
+	}
+
+	var decoded chatCompletionsResponse
+	err = json.NewDecoder(resp.Body).Decode(&decoded)
+	if err != nil {
+		return nil, errors.New("failed to decode response")
+	}
+	if len(decoded.Choices) == 0 {
+		return nil, errors.New("no choices in response")
+	}
+	text := strings.TrimSpace(decoded.Choices[0].Message.Content)
+	if text == "" {
+		return nil, ErrNoResponse
+	}
+	return &Response{
+		Text:       text,
+		Confidence: 1,
+	}, nil
+}
@@ -0,0 +1,145 @@
+package responders
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/brianvoe/gofakeit/v6"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewChatCompletionsResponder(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  *ChatCompletionsResponderParameters
+		wantErr bool
+	}{
+		{
+			name: "ReturnsResponder",
+			params: &ChatCompletionsResponderParameters{
+				BaseUrl: gofakeit.URL(),
+				Model:   "fake-model",
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingBaseUrl",
+			params: &ChatCompletionsResponderParameters{
+				Model: "fake-model",
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingModel",
+			params: &ChatCompletionsResponderParameters{
+				BaseUrl: gofakeit.URL(),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewChatCompletionsResponder(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf(
+					"NewChatCompletionsResponder() error = %v, wantErr %v",
+					err,
+					tt.wantErr,
+				)
+			}
+		})
+	}
+}
+
+func TestChatCompletionsResponder_Respond(t *testing.T) {
+	apiKey := gofakeit.UUID()
+	reply := gofakeit.LoremIpsumSentence(5)
+
+	tests := []struct {
+		name    string
+		status  int
+		body    interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "ReturnsFirstChoice",
+			status: http.StatusOK,
+			body: map[string]interface{}{
+				"choices": []interface{}{
+					map[string]interface{}{
+						"message": map[string]interface{}{
+							"role":    "assistant",
+							"content": reply,
+						},
+					},
+				},
+			},
+			want:    reply,
+			wantErr: false,
+		},
+		{
+			name:    "NoChoices",
+			status:  http.StatusOK,
+			body:    map[string]interface{}{"choices": []interface{}{}},
+			wantErr: true,
+		},
+		{
+			name:    "UnexpectedStatus",
+			status:  http.StatusUnauthorized,
+			body:    map[string]interface{}{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/v1/chat/completions" {
+						t.Errorf("Respond() path = %v, want %v", r.URL.Path, "/v1/chat/completions")
+					}
+					if r.Header.Get("Authorization") != "Bearer "+apiKey {
+						t.Errorf("Respond() authorization = %v", r.Header.Get("Authorization"))
+					}
+					var request chatCompletionsRequest
+					err := json.NewDecoder(r.Body).Decode(&request)
+					if err != nil {
+						t.Errorf("Respond() error = %v", err)
+					}
+					if len(request.Messages) != 2 || request.Messages[0].Role != "system" {
+						t.Errorf("Respond() messages = %v", request.Messages)
+					}
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(tt.status)
+					_ = json.NewEncoder(w).Encode(tt.body)
+				}),
+			)
+			defer server.Close()
+
+			responder, err := NewChatCompletionsResponder(
+				&ChatCompletionsResponderParameters{
+					BaseUrl:      server.URL + "/v1",
+					ApiKey:       apiKey,
+					Model:        "fake-model",
+					SystemPrompt: "You are a good dog.",
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response, err := responder.Respond(
+				context.Background(),
+				&Request{Text: "Who is a good boy?"},
+			)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Respond() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && response.Text != tt.want {
+				t.Errorf("Respond() = %v, want %v", response.Text, tt.want)
+			}
+		})
+	}
+}
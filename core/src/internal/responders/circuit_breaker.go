@@ -0,0 +1,206 @@
+package responders
+
+import (
+	"context"
+	"errors"
+	"github.com/drewnorman/jt-slackbot/core/internal/dialog"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by a CircuitBreaker
+// while it refuses to call its responder.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// States of a CircuitBreaker.
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half-open"
+)
+
+// A CircuitBreaker stops calling a failing
+// Responder after a number of consecutive
+// failures. Once the cooldown has passed, a
+// limited number of probe requests are let
+// through and the circuit closes again if
+// they succeed.
+type CircuitBreaker struct {
+	logger           *zap.Logger
+	responder        Responder
+	failureThreshold int
+	cooldown         time.Duration
+	probes           int
+	now              func() time.Time
+	mutex            sync.Mutex
+	state            string
+	failures         int
+	openedAt         time.Time
+	probing          int
+	probeSuccesses   int
+}
+
+// responders.CircuitBreakerParameters describe
+// how a new responders.CircuitBreaker should be
+// created.
+type CircuitBreakerParameters struct {
+	Logger           *zap.Logger
+	Responder        Responder
+	FailureThreshold int
+	Cooldown         time.Duration
+	Probes           int
+}
+
+// NewCircuitBreaker returns a new closed
+// CircuitBreaker according to the given
+// parameters.
+func NewCircuitBreaker(
+	params *CircuitBreakerParameters,
+) (*CircuitBreaker, error) {
+	if params.Logger == nil {
+		return nil, errors.New("missing logger")
+	}
+	if params.Responder == nil {
+		return nil, errors.New("missing responder")
+	}
+	if params.FailureThreshold < 1 {
+		return nil, errors.New("failure threshold must be at least 1")
+	}
+	if params.Cooldown <= 0 {
+		return nil, errors.New("cooldown must be positive")
+	}
+	probes := params.Probes
+	if probes < 1 {
+		probes = 1
+	}
+	return &CircuitBreaker{
+		logger:           params.Logger,
+		responder:        params.Responder,
+		failureThreshold: params.FailureThreshold,
+		cooldown:         params.Cooldown,
+		probes:           probes,
+		now:              time.Now,
+		state:            circuitClosed,
+	}, nil
+}
+
+// Respond delegates the given request to the
+// responder unless the circuit is open, in which
+// case ErrCircuitOpen is returned immediately.
+func (breaker *CircuitBreaker) Respond(
+	ctx context.Context,
+	request *Request,
+) (*Response, error) {
+	err := breaker.acquire()
+	if err != nil {
+		return nil, err
+	}
+	response, err := breaker.responder.Respond(ctx, request)
+	breaker.record(ctx, err)
+	return response, err
+}
+
+// State returns the current state of the
+// circuit: closed, open or half-open.
+func (breaker *CircuitBreaker) State() string {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	return breaker.state
+}
+
+// acquire returns ErrCircuitOpen if a request
+// may not be made at the moment. Once the
+// cooldown has passed, the circuit becomes
+// half-open and admits up to probes requests
+// at a time.
+func (breaker *CircuitBreaker) acquire() error {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	if breaker.state == circuitOpen {
+		if breaker.now().Sub(breaker.openedAt) < breaker.cooldown {
+			return ErrCircuitOpen
+		}
+		breaker.transition(circuitHalfOpen)
+		breaker.probing = 0
+		breaker.probeSuccesses = 0
+	}
+	if breaker.state == circuitHalfOpen {
+		if breaker.probing >= breaker.probes {
+			return ErrCircuitOpen
+		}
+		breaker.probing++
+	}
+	return nil
+}
+
+// record updates the circuit with the outcome
+// of a request made with the given context.
+// Requests the responder chose not to answer
+// or rejected as invalid do not count as
+// failures, and requests given up by the
+// caller do not count at all.
+func (breaker *CircuitBreaker) record(ctx context.Context, err error) {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	if err != nil && (ctx.Err() != nil || errors.Is(err, context.Canceled)) {
+		if breaker.state == circuitHalfOpen {
+			breaker.probing--
+		}
+		return
+	}
+	failed := err != nil && !errors.Is(err, ErrNoResponse) && !rejected(err)
+	switch breaker.state {
+	case circuitHalfOpen:
+		breaker.probing--
+		if failed {
+			breaker.open()
+			return
+		}
+		breaker.probeSuccesses++
+		if breaker.probeSuccesses >= breaker.probes {
+			breaker.failures = 0
+			breaker.transition(circuitClosed)
+		}
+	case circuitClosed:
+		if !failed {
+			breaker.failures = 0
+			return
+		}
+		breaker.failures++
+		if breaker.failures >= breaker.failureThreshold {
+			breaker.open()
+		}
+	}
+}
+
+// open opens the circuit, starting the cooldown.
+func (breaker *CircuitBreaker) open() {
+	breaker.openedAt = breaker.now()
+	breaker.transition(circuitOpen)
+}
+
+// transition moves the circuit to the given
+// state, logging the change.
+func (breaker *CircuitBreaker) transition(state string) {
+	if breaker.state == state {
+		return
+	}
+	breaker.logger.Info(
+		"circuit breaker changed state",
+		zap.String("from", breaker.state),
+		zap.String("to", state),
+	)
+	breaker.state = state
+}
+
+// rejected returns true if the given error
+// shows the dialog service turned the request
+// down without failing, such as with a 4xx
+// status.
+func rejected(err error) bool {
+	var statusErr *dialog.StatusError
+	return errors.As(err, &statusErr) && !statusErr.Temporary()
+}
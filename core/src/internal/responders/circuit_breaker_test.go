@@ -0,0 +1,225 @@
+package responders
+
+import (
+	"context"
+	"errors"
+	"github.com/drewnorman/jt-slackbot/core/internal/dialog"
+	"go.uber.org/zap"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type fakeFailingResponder struct {
+	err   error
+	calls int
+}
+
+func (responder *fakeFailingResponder) Respond(
+	ctx context.Context,
+	request *Request,
+) (*Response, error) {
+	responder.calls++
+	if responder.err != nil {
+		return nil, responder.err
+	}
+	return &Response{
+		Text:       "recovered",
+		Confidence: 1,
+	}, nil
+}
+
+func TestNewCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  *CircuitBreakerParameters
+		wantErr bool
+	}{
+		{
+			name: "ReturnsCircuitBreaker",
+			params: &CircuitBreakerParameters{
+				Logger:           zap.NewNop(),
+				Responder:        &fakeFailingResponder{},
+				FailureThreshold: 3,
+				Cooldown:         time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingLogger",
+			params: &CircuitBreakerParameters{
+				Responder:        &fakeFailingResponder{},
+				FailureThreshold: 3,
+				Cooldown:         time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingResponder",
+			params: &CircuitBreakerParameters{
+				Logger:           zap.NewNop(),
+				FailureThreshold: 3,
+				Cooldown:         time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "InvalidFailureThreshold",
+			params: &CircuitBreakerParameters{
+				Logger:    zap.NewNop(),
+				Responder: &fakeFailingResponder{},
+				Cooldown:  time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "InvalidCooldown",
+			params: &CircuitBreakerParameters{
+				Logger:           zap.NewNop(),
+				Responder:        &fakeFailingResponder{},
+				FailureThreshold: 3,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCircuitBreaker(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewCircuitBreaker() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker_Respond(t *testing.T) {
+	responder := &fakeFailingResponder{err: errors.New("unavailable")}
+	breaker, err := NewCircuitBreaker(
+		&CircuitBreakerParameters{
+			Logger:           zap.NewNop(),
+			Responder:        responder,
+			FailureThreshold: 2,
+			Cooldown:         time.Minute,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	ctx := context.Background()
+	request := &Request{Text: "Hello"}
+
+	for i := 0; i < 2; i++ {
+		_, err = breaker.Respond(ctx, request)
+		if err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Respond() error = %v, want responder error", err)
+		}
+	}
+	if breaker.State() != circuitOpen {
+		t.Fatalf("State() = %v, want %v", breaker.State(), circuitOpen)
+	}
+
+	_, err = breaker.Respond(ctx, request)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Respond() error = %v, want %v", err, ErrCircuitOpen)
+	}
+	if responder.calls != 2 {
+		t.Errorf("calls = %v, want %v", responder.calls, 2)
+	}
+
+	now = now.Add(time.Minute)
+	_, err = breaker.Respond(ctx, request)
+	if err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Respond() error = %v, want responder error", err)
+	}
+	if breaker.State() != circuitOpen {
+		t.Errorf("State() = %v, want %v", breaker.State(), circuitOpen)
+	}
+
+	now = now.Add(time.Minute)
+	responder.err = nil
+	response, err := breaker.Respond(ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Text != "recovered" {
+		t.Errorf("Respond() = %v, want %v", response.Text, "recovered")
+	}
+	if breaker.State() != circuitClosed {
+		t.Errorf("State() = %v, want %v", breaker.State(), circuitClosed)
+	}
+}
+
+func TestCircuitBreaker_IgnoresNoResponse(t *testing.T) {
+	breaker, err := NewCircuitBreaker(
+		&CircuitBreakerParameters{
+			Logger:           zap.NewNop(),
+			Responder:        &fakeFailingResponder{err: ErrNoResponse},
+			FailureThreshold: 1,
+			Cooldown:         time.Minute,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = breaker.Respond(context.Background(), &Request{Text: "Hello"})
+	if breaker.State() != circuitClosed {
+		t.Errorf("State() = %v, want %v", breaker.State(), circuitClosed)
+	}
+}
+
+func TestCircuitBreaker_IgnoresCallerErrors(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want string
+	}{
+		{
+			name: "OpensOnServerErrors",
+			ctx:  context.Background(),
+			err:  &dialog.StatusError{StatusCode: http.StatusInternalServerError},
+			want: circuitOpen,
+		},
+		{
+			name: "IgnoresClientErrors",
+			ctx:  context.Background(),
+			err:  &dialog.StatusError{StatusCode: http.StatusBadRequest},
+			want: circuitClosed,
+		},
+		{
+			name: "IgnoresCancelledCallers",
+			ctx:  cancelled,
+			err:  context.Canceled,
+			want: circuitClosed,
+		},
+		{
+			name: "IgnoresCallerDeadlines",
+			ctx:  cancelled,
+			err:  context.DeadlineExceeded,
+			want: circuitClosed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker, err := NewCircuitBreaker(
+				&CircuitBreakerParameters{
+					Logger:           zap.NewNop(),
+					Responder:        &fakeFailingResponder{err: tt.err},
+					FailureThreshold: 1,
+					Cooldown:         time.Minute,
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = breaker.Respond(tt.ctx, &Request{Text: "Hello"})
+			if breaker.State() != tt.want {
+				t.Errorf("State() = %v, want %v", breaker.State(), tt.want)
+			}
+		})
+	}
+}
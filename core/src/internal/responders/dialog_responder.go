@@ -0,0 +1,98 @@
+package responders
+
+import (
+	"context"
+	"errors"
+	"github.com/drewnorman/jt-slackbot/core/internal/dialog"
+	"sync"
+)
+
+// A DialogResponder replies to messages
+// using the dialog service.
+type DialogResponder struct {
+	dialogClient *dialog.Client
+	mutex        sync.RWMutex
+}
+
+// NewDialogResponder returns a new
+// DialogResponder using the given client.
+func NewDialogResponder(
+	dialogClient *dialog.Client,
+) (*DialogResponder, error) {
+	if dialogClient == nil {
+		return nil, errors.New("missing dialog client")
+	}
+	return &DialogResponder{
+		dialogClient: dialogClient,
+	}, nil
+}
+
+// Respond requests a reply to the given
+// request from the dialog service.
+func (responder *DialogResponder) Respond(
+	ctx context.Context,
+	request *Request,
+) (*Response, error) {
+	responder.mutex.RLock()
+	dialogClient := responder.dialogClient
+	responder.mutex.RUnlock()
+	reply, err := dialogClient.Converse(
+		ctx,
+		conversationFromRequest(request),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		Text:        reply.Text,
+		Confidence:  reply.Confidence,
+		Reactions:   reply.Reactions,
+		Blocks:      reply.Blocks,
+		Suggestions: reply.Suggestions,
+	}, nil
+}
+
+// SetClient replaces the client of the dialog
+// service used for the next requests.
+func (responder *DialogResponder) SetClient(dialogClient *dialog.Client) {
+	responder.mutex.Lock()
+	defer responder.mutex.Unlock()
+	responder.dialogClient = dialogClient
+}
+
+// conversationFromRequest returns the dialog
+// conversation describing the given request.
+func conversationFromRequest(request *Request) *dialog.Conversation {
+	conversation := &dialog.Conversation{
+		Message: request.Text,
+		Locale:  request.Locale,
+	}
+	if request.ChannelId != "" {
+		conversation.SessionId = dialog.SessionId(
+			request.ChannelId,
+			request.ThreadTs,
+		)
+		conversation.Channel = &dialog.Channel{
+			Id:   request.ChannelId,
+			Type: request.ChannelType,
+		}
+	}
+	if request.UserId != "" {
+		conversation.User = &dialog.User{
+			Id:   request.UserId,
+			Name: request.UserName,
+		}
+	}
+	for _, message := range request.History {
+		conversation.History = append(
+			conversation.History,
+			&dialog.Message{
+				UserId:  message.UserId,
+				Text:    message.Text,
+				Ts:      message.Ts,
+				FromBot: message.FromBot,
+			},
+		)
+	}
+	return conversation
+}
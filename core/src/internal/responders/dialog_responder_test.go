@@ -0,0 +1,75 @@
+package responders
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/drewnorman/jt-slackbot/core/internal/dialog"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewDialogResponder(t *testing.T) {
+	_, err := NewDialogResponder(nil)
+	if err == nil {
+		t.Errorf("NewDialogResponder() error = %v, wantErr %v", err, true)
+	}
+}
+
+func TestDialogResponder_Respond(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var received map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&received)
+			if received["session_id"] == nil {
+				t.Errorf("Respond() session_id = %v, want session id", received["session_id"])
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"reply":      "Woof!",
+				"confidence": 0.5,
+				"reactions":  []string{"dog"},
+			})
+		}),
+	)
+	defer server.Close()
+
+	dialogClient, err := dialog.NewClient(
+		&dialog.ClientParameters{
+			Logger:  zap.NewNop(),
+			BaseUrl: server.URL,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responder, err := NewDialogResponder(dialogClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := responder.Respond(
+		context.Background(),
+		&Request{
+			Text:        "Hello",
+			UserId:      "U0123",
+			UserName:    "Jimmy",
+			ChannelId:   "C0123",
+			ChannelType: "channel",
+			History:     []*Message{{UserId: "U0123", Text: "Hi"}},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Text != "Woof!" {
+		t.Errorf("Respond() = %v, want %v", response.Text, "Woof!")
+	}
+	if response.Confidence != 0.5 {
+		t.Errorf("Respond() confidence = %v, want %v", response.Confidence, 0.5)
+	}
+	if len(response.Reactions) != 1 {
+		t.Errorf("Respond() reactions = %v, want %v", response.Reactions, []string{"dog"})
+	}
+}
@@ -0,0 +1,231 @@
+package responders
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+)
+
+// A FallbackResponder tries each of its
+// responders in order until one replies.
+type FallbackResponder struct {
+	logger     *zap.Logger
+	responders []Responder
+}
+
+// responders.FallbackResponderParameters
+// describe how a new FallbackResponder should
+// be created.
+type FallbackResponderParameters struct {
+	Logger     *zap.Logger
+	Responders []Responder
+}
+
+// NewFallbackResponder returns a new
+// FallbackResponder according to the given
+// parameters.
+func NewFallbackResponder(
+	params *FallbackResponderParameters,
+) (*FallbackResponder, error) {
+	if params.Logger == nil {
+		return nil, errors.New("missing logger")
+	}
+	if len(params.Responders) == 0 {
+		return nil, errors.New("missing responders")
+	}
+	for _, responder := range params.Responders {
+		if responder == nil {
+			return nil, errors.New("missing responder")
+		}
+	}
+	return &FallbackResponder{
+		logger:     params.Logger,
+		responders: params.Responders,
+	}, nil
+}
+
+// Respond returns the reply of the first
+// responder to answer the given request. If
+// a responder has nothing to say, the request
+// is not passed on. If every responder fails,
+// the last error is returned.
+func (responder *FallbackResponder) Respond(
+	ctx context.Context,
+	request *Request,
+) (*Response, error) {
+	var err error
+	for index, next := range responder.responders {
+		var response *Response
+		response, err = next.Respond(ctx, request)
+		if err == nil || errors.Is(err, ErrNoResponse) {
+			return response, err
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		responder.logger.Warn(
+			"responder failed, falling back",
+			zap.Int("index", index),
+			zap.String("err", err.Error()),
+		)
+	}
+	return nil, err
+}
+
+// A StaticResponder always replies with the
+// same text, such as an apology. The text is
+// chosen deliberately, so the responder is
+// fully confident in it.
+type StaticResponder struct {
+	text string
+}
+
+// NewStaticResponder returns a new
+// StaticResponder replying with the given text.
+func NewStaticResponder(text string) (*StaticResponder, error) {
+	if text == "" {
+		return nil, errors.New("missing text")
+	}
+	return &StaticResponder{
+		text: text,
+	}, nil
+}
+
+// Respond replies with the configured text.
+func (responder *StaticResponder) Respond(
+	ctx context.Context,
+	request *Request,
+) (*Response, error) {
+	return &Response{
+		Text:       responder.text,
+		Confidence: 1,
+	}, nil
+}
+
+// A RecallingResponder remembers the most
+// recent replies of a responder so they can be
+// given again when that responder fails.
+type RecallingResponder struct {
+	responder Responder
+	size      int
+	mutex     sync.Mutex
+	order     *list.List
+	answers   map[string]*list.Element
+}
+
+// A recalledAnswer is a remembered reply.
+type recalledAnswer struct {
+	key      string
+	response Response
+}
+
+// NewRecallingResponder returns a new
+// RecallingResponder remembering up to size
+// replies of the given responder.
+func NewRecallingResponder(
+	responder Responder,
+	size int,
+) (*RecallingResponder, error) {
+	if responder == nil {
+		return nil, errors.New("missing responder")
+	}
+	if size < 1 {
+		return nil, errors.New("size must be at least 1")
+	}
+	return &RecallingResponder{
+		responder: responder,
+		size:      size,
+		order:     list.New(),
+		answers:   make(map[string]*list.Element),
+	}, nil
+}
+
+// Respond delegates the given request to the
+// responder, remembering its reply. If the
+// responder fails, a remembered reply to the
+// same message is returned instead.
+func (responder *RecallingResponder) Respond(
+	ctx context.Context,
+	request *Request,
+) (*Response, error) {
+	key := recallKey(request.Text)
+	response, err := responder.responder.Respond(ctx, request)
+	if err == nil {
+		responder.remember(key, response)
+		return response, nil
+	}
+	if errors.Is(err, ErrNoResponse) {
+		return nil, err
+	}
+	recalled, ok := responder.recall(key)
+	if !ok {
+		return nil, err
+	}
+	return recalled, nil
+}
+
+// Forget removes the remembered reply to the
+// given message text, such as after the
+// dialog service was taught to answer it
+// differently.
+func (responder *RecallingResponder) Forget(text string) {
+	responder.mutex.Lock()
+	defer responder.mutex.Unlock()
+
+	key := recallKey(text)
+	if element, ok := responder.answers[key]; ok {
+		responder.order.Remove(element)
+		delete(responder.answers, key)
+	}
+}
+
+// remember stores the given response, evicting
+// the least recently used one if necessary.
+func (responder *RecallingResponder) remember(
+	key string,
+	response *Response,
+) {
+	responder.mutex.Lock()
+	defer responder.mutex.Unlock()
+
+	if element, ok := responder.answers[key]; ok {
+		element.Value.(*recalledAnswer).response = *response
+		responder.order.MoveToFront(element)
+		return
+	}
+	responder.answers[key] = responder.order.PushFront(
+		&recalledAnswer{
+			key:      key,
+			response: *response,
+		},
+	)
+	if responder.order.Len() > responder.size {
+		oldest := responder.order.Back()
+		responder.order.Remove(oldest)
+		delete(responder.answers, oldest.Value.(*recalledAnswer).key)
+	}
+}
+
+// recall returns a copy of the remembered
+// response for the given key.
+func (responder *RecallingResponder) recall(key string) (*Response, bool) {
+	responder.mutex.Lock()
+	defer responder.mutex.Unlock()
+
+	element, ok := responder.answers[key]
+	if !ok {
+		return nil, false
+	}
+	responder.order.MoveToFront(element)
+	response := element.Value.(*recalledAnswer).response
+	return &response, true
+}
+
+// recallKey normalizes message text so trivial
+// differences in case and spacing are ignored.
+func recallKey(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
@@ -0,0 +1,183 @@
+package responders
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func TestNewFallbackResponder(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  *FallbackResponderParameters
+		wantErr bool
+	}{
+		{
+			name: "ReturnsFallbackResponder",
+			params: &FallbackResponderParameters{
+				Logger:     zap.NewNop(),
+				Responders: []Responder{&fakeResponder{text: "first"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingLogger",
+			params: &FallbackResponderParameters{
+				Responders: []Responder{&fakeResponder{text: "first"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingResponders",
+			params: &FallbackResponderParameters{
+				Logger: zap.NewNop(),
+			},
+			wantErr: true,
+		},
+		{
+			name: "NilResponder",
+			params: &FallbackResponderParameters{
+				Logger:     zap.NewNop(),
+				Responders: []Responder{nil},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFallbackResponder(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewFallbackResponder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFallbackResponder_Respond(t *testing.T) {
+	apology, err := NewStaticResponder("Sorry!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		responders []Responder
+		want       string
+		wantErr    bool
+	}{
+		{
+			name: "FirstResponder",
+			responders: []Responder{
+				&fakeResponder{text: "first"},
+				apology,
+			},
+			want:    "first",
+			wantErr: false,
+		},
+		{
+			name: "FallsBack",
+			responders: []Responder{
+				&fakeFailingResponder{err: errors.New("unavailable")},
+				&fakeFailingResponder{err: ErrCircuitOpen},
+				apology,
+			},
+			want:    "Sorry!",
+			wantErr: false,
+		},
+		{
+			name: "StopsAtNoResponse",
+			responders: []Responder{
+				&fakeFailingResponder{err: ErrNoResponse},
+				apology,
+			},
+			wantErr: true,
+		},
+		{
+			name: "AllFail",
+			responders: []Responder{
+				&fakeFailingResponder{err: errors.New("unavailable")},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responder, err := NewFallbackResponder(
+				&FallbackResponderParameters{
+					Logger:     zap.NewNop(),
+					Responders: tt.responders,
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := responder.Respond(
+				context.Background(),
+				&Request{Text: "Hello"},
+			)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Respond() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && response.Text != tt.want {
+				t.Errorf("Respond() = %v, want %v", response.Text, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewStaticResponder(t *testing.T) {
+	_, err := NewStaticResponder("")
+	if err == nil {
+		t.Errorf("NewStaticResponder() error = %v, wantErr %v", err, true)
+	}
+}
+
+func TestRecallingResponder_Respond(t *testing.T) {
+	inner := &fakeFailingResponder{}
+	responder, err := NewRecallingResponder(inner, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	_, err = responder.Respond(ctx, &Request{Text: "Hello  there"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inner.err = errors.New("unavailable")
+	response, err := responder.Respond(ctx, &Request{Text: "hello there"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Text != "recovered" {
+		t.Errorf("Respond() = %v, want %v", response.Text, "recovered")
+	}
+
+	_, err = responder.Respond(ctx, &Request{Text: "Something else"})
+	if err == nil {
+		t.Errorf("Respond() error = %v, wantErr %v", err, true)
+	}
+}
+
+func TestRecallingResponder_Forget(t *testing.T) {
+	inner := &fakeFailingResponder{}
+	responder, err := NewRecallingResponder(inner, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	_, err = responder.Respond(ctx, &Request{Text: "Hello there"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	responder.Forget("  hello THERE ")
+
+	inner.err = errors.New("unavailable")
+	_, err = responder.Respond(ctx, &Request{Text: "Hello there"})
+	if err == nil {
+		t.Errorf("Respond() error = %v, wantErr %v", err, true)
+	}
+}
@@ -0,0 +1,251 @@
+package responders
+
+import (
+	"github.com/jdkato/prose/v2"
+	"strings"
+)
+
+// The intents a message may be classified into.
+const (
+	IntentHelp     = "help"
+	IntentGreeting = "greeting"
+	IntentCommand  = "command"
+	IntentQuestion = "question"
+	IntentChitChat = "chit-chat"
+)
+
+// defaultHelpKeywords are the words and phrases
+// asking for help if none are configured.
+var defaultHelpKeywords = []string{
+	"help",
+	"usage",
+	"commands",
+	"what can you do",
+}
+
+// defaultGreetingKeywords are the words and
+// phrases greeting the app if none are configured.
+var defaultGreetingKeywords = []string{
+	"hi",
+	"hello",
+	"hey",
+	"howdy",
+	"yo",
+	"hiya",
+	"good morning",
+	"good afternoon",
+	"good evening",
+}
+
+// defaultCommandVerbs are the words that start
+// commands, such as learn: or cache:, if none
+// are configured.
+var defaultCommandVerbs = []string{
+	"learn",
+	"forget",
+	"cache",
+}
+
+// maxHelpWords and maxGreetingWords limit how
+// long help requests and greetings may be, so
+// longer messages that only contain a keyword
+// are not mistaken for them.
+const (
+	maxHelpWords     = 5
+	maxGreetingWords = 4
+)
+
+// questionWords are the auxiliary verbs that
+// start yes or no questions.
+var questionWords = map[string]bool{
+	"is":     true,
+	"are":    true,
+	"was":    true,
+	"were":   true,
+	"am":     true,
+	"do":     true,
+	"does":   true,
+	"did":    true,
+	"have":   true,
+	"has":    true,
+	"had":    true,
+	"isn't":  true,
+	"aren't": true,
+	"don't":  true,
+}
+
+// questionTags are the part of speech tags of
+// words that start questions: wh-words and
+// modal verbs.
+var questionTags = map[string]bool{
+	"WDT": true,
+	"WP":  true,
+	"WP$": true,
+	"WRB": true,
+	"MD":  true,
+}
+
+// An IntentClassifier tokenizes and tags
+// messages to classify them into intents with
+// keyword and part of speech rules.
+type IntentClassifier struct {
+	model            *prose.Model
+	helpKeywords     [][]string
+	greetingKeywords [][]string
+	commandVerbs     map[string]bool
+}
+
+// responders.IntentClassifierParameters describe
+// how a new IntentClassifier should be created.
+type IntentClassifierParameters struct {
+	HelpKeywords     []string
+	GreetingKeywords []string
+	CommandVerbs     []string
+}
+
+// NewIntentClassifier returns a new
+// IntentClassifier according to the given
+// parameters, loading the tagging model.
+func NewIntentClassifier(
+	params *IntentClassifierParameters,
+) (*IntentClassifier, error) {
+	helpKeywords := params.HelpKeywords
+	if len(helpKeywords) == 0 {
+		helpKeywords = defaultHelpKeywords
+	}
+	greetingKeywords := params.GreetingKeywords
+	if len(greetingKeywords) == 0 {
+		greetingKeywords = defaultGreetingKeywords
+	}
+	commandVerbs := params.CommandVerbs
+	if len(commandVerbs) == 0 {
+		commandVerbs = defaultCommandVerbs
+	}
+	document, err := prose.NewDocument(
+		"",
+		prose.WithSegmentation(false),
+		prose.WithExtraction(false),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &IntentClassifier{
+		model:            document.Model,
+		helpKeywords:     splitKeywords(helpKeywords),
+		greetingKeywords: splitKeywords(greetingKeywords),
+		commandVerbs:     lowercaseSet(commandVerbs),
+	}, nil
+}
+
+// Classify returns the intent of the given text.
+// Anything that is not recognized as another
+// intent is chit-chat.
+func (classifier *IntentClassifier) Classify(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "/") || strings.HasPrefix(text, "!") {
+		return IntentCommand
+	}
+
+	document, err := prose.NewDocument(
+		text,
+		prose.UsingModel(classifier.model),
+		prose.WithSegmentation(false),
+		prose.WithExtraction(false),
+	)
+	if err != nil {
+		return IntentChitChat
+	}
+	tokens := document.Tokens()
+	if len(tokens) == 0 {
+		return IntentChitChat
+	}
+
+	var words []string
+	for _, token := range tokens {
+		if isWord(token) {
+			words = append(words, strings.ToLower(token.Text))
+		}
+	}
+
+	if len(tokens) > 1 && tokens[1].Text == ":" &&
+		classifier.commandVerbs[strings.ToLower(tokens[0].Text)] {
+		return IntentCommand
+	}
+	if len(words) <= maxHelpWords && containsAny(words, classifier.helpKeywords) {
+		return IntentHelp
+	}
+	if len(words) <= maxGreetingWords && startsWithAny(words, classifier.greetingKeywords) {
+		return IntentGreeting
+	}
+	if tokens[len(tokens)-1].Text == "?" || questionTags[tokens[0].Tag] ||
+		len(words) > 0 && questionWords[words[0]] {
+		return IntentQuestion
+	}
+	return IntentChitChat
+}
+
+// splitKeywords returns the lowercase words of
+// each of the given keywords.
+func splitKeywords(keywords []string) [][]string {
+	split := make([][]string, 0, len(keywords))
+	for _, keyword := range keywords {
+		words := strings.Fields(strings.ToLower(keyword))
+		if len(words) > 0 {
+			split = append(split, words)
+		}
+	}
+	return split
+}
+
+// lowercaseSet returns the set of the given
+// words in lowercase.
+func lowercaseSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[strings.ToLower(strings.TrimSpace(word))] = true
+	}
+	return set
+}
+
+// isWord returns true if the given token is a
+// word rather than punctuation.
+func isWord(token prose.Token) bool {
+	for _, r := range token.Text {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny returns true if any of the given
+// keywords appear in the given words.
+func containsAny(words []string, keywords [][]string) bool {
+	for start := range words {
+		if startsWithAny(words[start:], keywords) {
+			return true
+		}
+	}
+	return false
+}
+
+// startsWithAny returns true if the given words
+// start with any of the given keywords.
+func startsWithAny(words []string, keywords [][]string) bool {
+	for _, keyword := range keywords {
+		if len(keyword) > len(words) {
+			continue
+		}
+		matches := true
+		for i, word := range keyword {
+			if words[i] != word {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package responders
+
+import (
+	"testing"
+)
+
+func TestIntentClassifier_Classify(t *testing.T) {
+	classifier, err := NewIntentClassifier(&IntentClassifierParameters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"Help", "help", IntentHelp},
+		{"HelpPhrase", "what can you do?", IntentHelp},
+		{"Greeting", "Hey there!", IntentGreeting},
+		{"GreetingPhrase", "good morning J.T.", IntentGreeting},
+		{"LongGreeting", "hello, could you tell me where the park is?", IntentQuestion},
+		{"CommandVerb", "Cache: purge", IntentCommand},
+		{"UnknownCommandVerb", "note: treats are great", IntentChitChat},
+		{"SlashCommand", "/walk", IntentCommand},
+		{"WhQuestion", "how do I get a treat", IntentQuestion},
+		{"ModalQuestion", "can you sit", IntentQuestion},
+		{"YesNoQuestion", "is it time for a walk", IntentQuestion},
+		{"QuestionMark", "the ball, maybe?", IntentQuestion},
+		{"ChitChat", "I love treats", IntentChitChat},
+		{"Imperative", "tell me a joke", IntentChitChat},
+		{"Empty", "", IntentChitChat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifier.Classify(tt.text); got != tt.want {
+				t.Errorf("Classify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntentClassifier_ClassifyCustomKeywords(t *testing.T) {
+	classifier, err := NewIntentClassifier(
+		&IntentClassifierParameters{
+			HelpKeywords:     []string{"Need A Hand"},
+			GreetingKeywords: []string{"woof"},
+			CommandVerbs:     []string{"Fetch"},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"CustomHelp", "I need a hand", IntentHelp},
+		{"DefaultHelpReplaced", "help", IntentChitChat},
+		{"CustomGreeting", "Woof woof!", IntentGreeting},
+		{"DefaultGreetingReplaced", "hello", IntentChitChat},
+		{"CustomCommandVerb", "fetch: the ball", IntentCommand},
+		{"DefaultCommandVerbReplaced", "learn: sit", IntentChitChat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifier.Classify(tt.text); got != tt.want {
+				t.Errorf("Classify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
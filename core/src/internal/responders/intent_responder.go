@@ -0,0 +1,144 @@
+package responders
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+)
+
+// An IntentResponder classifies each message
+// and routes it to the responder handling its
+// intent, or to the default responder.
+type IntentResponder struct {
+	logger     *zap.Logger
+	classifier *IntentClassifier
+	responders map[string]Responder
+	fallback   Responder
+}
+
+// responders.IntentResponderParameters describe
+// how a new IntentResponder should be created.
+// Messages with intents missing from Responders
+// go to the Default responder.
+type IntentResponderParameters struct {
+	Logger     *zap.Logger
+	Classifier *IntentClassifier
+	Responders map[string]Responder
+	Default    Responder
+}
+
+// NewIntentResponder returns a new
+// IntentResponder according to the given
+// parameters.
+func NewIntentResponder(
+	params *IntentResponderParameters,
+) (*IntentResponder, error) {
+	if params.Logger == nil {
+		return nil, errors.New("missing logger")
+	}
+	if params.Classifier == nil {
+		return nil, errors.New("missing classifier")
+	}
+	if params.Default == nil {
+		return nil, errors.New("missing default responder")
+	}
+	for intent, responder := range params.Responders {
+		if responder == nil {
+			return nil, errors.New("missing responder for intent " + intent)
+		}
+	}
+	return &IntentResponder{
+		logger:     params.Logger,
+		classifier: params.Classifier,
+		responders: params.Responders,
+		fallback:   params.Default,
+	}, nil
+}
+
+// Respond delegates to the responder handling
+// the intent of the given request.
+func (responder *IntentResponder) Respond(
+	ctx context.Context,
+	request *Request,
+) (*Response, error) {
+	intent := responder.classifier.Classify(request.Text)
+	responder.logger.Debug(
+		"classified message",
+		zap.String("intent", intent),
+		zap.String("channelId", request.ChannelId),
+	)
+	if handler, ok := responder.responders[intent]; ok {
+		return handler.Respond(ctx, request)
+	}
+	return responder.fallback.Respond(ctx, request)
+}
+
+// A GreetingResponder greets the sender of
+// each message by name.
+type GreetingResponder struct{}
+
+// NewGreetingResponder returns a new
+// GreetingResponder.
+func NewGreetingResponder() *GreetingResponder {
+	return &GreetingResponder{}
+}
+
+// Respond greets the sender of the given request.
+func (responder *GreetingResponder) Respond(
+	ctx context.Context,
+	request *Request,
+) (*Response, error) {
+	text := "Woof! Hi there!"
+	if request.UserName != "" {
+		text = "Woof! Hi, " + request.UserName + "!"
+	}
+	return &Response{
+		Text:       text,
+		Confidence: 1,
+	}, nil
+}
+
+// A FaqResponder answers questions from its
+// rules, or with its reply if none match.
+type FaqResponder struct {
+	rules Responder
+	reply string
+}
+
+// responders.FaqResponderParameters describe
+// how a new FaqResponder should be created.
+// Rules are optional.
+type FaqResponderParameters struct {
+	Rules Responder
+	Reply string
+}
+
+// NewFaqResponder returns a new FaqResponder
+// according to the given parameters.
+func NewFaqResponder(params *FaqResponderParameters) (*FaqResponder, error) {
+	if params.Reply == "" {
+		return nil, errors.New("missing reply")
+	}
+	return &FaqResponder{
+		rules: params.Rules,
+		reply: params.Reply,
+	}, nil
+}
+
+// Respond answers the given request using the
+// rules, or with the reply if no rule matches.
+func (responder *FaqResponder) Respond(
+	ctx context.Context,
+	request *Request,
+) (*Response, error) {
+	if responder.rules != nil {
+		response, err := responder.rules.Respond(ctx, request)
+		if !errors.Is(err, ErrNoResponse) {
+			return response, err
+		}
+	}
+	return &Response{
+		Text:       responder.reply,
+		Confidence: 1,
+	}, nil
+}
@@ -0,0 +1,194 @@
+package responders
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"regexp"
+	"testing"
+)
+
+func TestNewIntentResponder(t *testing.T) {
+	classifier, err := NewIntentClassifier(&IntentClassifierParameters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		params  *IntentResponderParameters
+		wantErr bool
+	}{
+		{
+			name: "ReturnsIntentResponder",
+			params: &IntentResponderParameters{
+				Logger:     zap.NewNop(),
+				Classifier: classifier,
+				Responders: map[string]Responder{
+					IntentGreeting: NewGreetingResponder(),
+				},
+				Default: &fakeResponder{text: "dialog"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingLogger",
+			params: &IntentResponderParameters{
+				Classifier: classifier,
+				Default:    &fakeResponder{text: "dialog"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingClassifier",
+			params: &IntentResponderParameters{
+				Logger:  zap.NewNop(),
+				Default: &fakeResponder{text: "dialog"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingDefault",
+			params: &IntentResponderParameters{
+				Logger:     zap.NewNop(),
+				Classifier: classifier,
+			},
+			wantErr: true,
+		},
+		{
+			name: "NilResponder",
+			params: &IntentResponderParameters{
+				Logger:     zap.NewNop(),
+				Classifier: classifier,
+				Responders: map[string]Responder{
+					IntentHelp: nil,
+				},
+				Default: &fakeResponder{text: "dialog"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewIntentResponder(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewIntentResponder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIntentResponder_Respond(t *testing.T) {
+	classifier, err := NewIntentClassifier(&IntentClassifierParameters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	responder, err := NewIntentResponder(
+		&IntentResponderParameters{
+			Logger:     zap.NewNop(),
+			Classifier: classifier,
+			Responders: map[string]Responder{
+				IntentHelp:     &fakeResponder{text: "help"},
+				IntentGreeting: NewGreetingResponder(),
+			},
+			Default: &fakeResponder{text: "dialog"},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		request *Request
+		want    string
+	}{
+		{
+			name:    "Help",
+			request: &Request{Text: "help"},
+			want:    "help",
+		},
+		{
+			name:    "Greeting",
+			request: &Request{Text: "hi!", UserName: "Jimmy"},
+			want:    "Woof! Hi, Jimmy!",
+		},
+		{
+			name:    "GreetingWithoutName",
+			request: &Request{Text: "hi!"},
+			want:    "Woof! Hi there!",
+		},
+		{
+			name:    "ChitChat",
+			request: &Request{Text: "I love treats"},
+			want:    "dialog",
+		},
+		{
+			name:    "UnhandledIntent",
+			request: &Request{Text: "where is the ball?"},
+			want:    "dialog",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := responder.Respond(context.Background(), tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.Text != tt.want {
+				t.Errorf("Respond() = %v, want %v", response.Text, tt.want)
+			}
+		})
+	}
+}
+
+func TestFaqResponder_Respond(t *testing.T) {
+	rules, err := NewRuleResponder(
+		[]Rule{{Pattern: regexp.MustCompile(`(?i)where is the ball`), Reply: "Under the couch!"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		rules   Responder
+		request *Request
+		want    string
+	}{
+		{
+			name:    "AnswersFromRules",
+			rules:   rules,
+			request: &Request{Text: "where is the ball?"},
+			want:    "Under the couch!",
+		},
+		{
+			name:    "RepliesWithoutMatchingRule",
+			rules:   rules,
+			request: &Request{Text: "what is your favorite toy?"},
+			want:    "faq",
+		},
+		{
+			name:    "RepliesWithoutRules",
+			request: &Request{Text: "where is the ball?"},
+			want:    "faq",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responder, err := NewFaqResponder(
+				&FaqResponderParameters{
+					Rules: tt.rules,
+					Reply: "faq",
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := responder.Respond(context.Background(), tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.Text != tt.want {
+				t.Errorf("Respond() = %v, want %v", response.Text, tt.want)
+			}
+		})
+	}
+}
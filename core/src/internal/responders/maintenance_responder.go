@@ -0,0 +1,69 @@
+package responders
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// A MaintenanceResponder delegates to another
+// Responder except while maintenance mode is
+// on, when it replies with a fixed message
+// instead.
+type MaintenanceResponder struct {
+	responder Responder
+	reply     string
+	mutex     sync.Mutex
+	enabled   bool
+}
+
+// NewMaintenanceResponder returns a new
+// MaintenanceResponder delegating to the given
+// responder and replying with the given text
+// during maintenance.
+func NewMaintenanceResponder(
+	responder Responder,
+	reply string,
+) (*MaintenanceResponder, error) {
+	if responder == nil {
+		return nil, errors.New("missing responder")
+	}
+	if reply == "" {
+		return nil, errors.New("missing reply")
+	}
+	return &MaintenanceResponder{
+		responder: responder,
+		reply:     reply,
+	}, nil
+}
+
+// Respond replies with the maintenance message
+// if maintenance mode is on, or else with the
+// reply of the wrapped responder.
+func (responder *MaintenanceResponder) Respond(
+	ctx context.Context,
+	request *Request,
+) (*Response, error) {
+	if responder.Enabled() {
+		return &Response{
+			Text:       responder.reply,
+			Confidence: 1,
+		}, nil
+	}
+	return responder.responder.Respond(ctx, request)
+}
+
+// SetEnabled turns maintenance mode on or off.
+func (responder *MaintenanceResponder) SetEnabled(enabled bool) {
+	responder.mutex.Lock()
+	defer responder.mutex.Unlock()
+	responder.enabled = enabled
+}
+
+// Enabled returns true if maintenance mode
+// is on.
+func (responder *MaintenanceResponder) Enabled() bool {
+	responder.mutex.Lock()
+	defer responder.mutex.Unlock()
+	return responder.enabled
+}
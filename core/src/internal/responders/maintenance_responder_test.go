@@ -0,0 +1,91 @@
+package responders
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewMaintenanceResponder(t *testing.T) {
+	type args struct {
+		responder Responder
+		reply     string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "ReturnsMaintenanceResponder",
+			args: args{
+				responder: &fakeResponder{text: "Woof!"},
+				reply:     "Back soon!",
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingResponder",
+			args: args{
+				reply: "Back soon!",
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingReply",
+			args: args{
+				responder: &fakeResponder{text: "Woof!"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMaintenanceResponder(tt.args.responder, tt.args.reply)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewMaintenanceResponder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaintenanceResponder_Respond(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		want    string
+	}{
+		{
+			name:    "Delegates",
+			enabled: false,
+			want:    "Woof!",
+		},
+		{
+			name:    "RepliesDuringMaintenance",
+			enabled: true,
+			want:    "Back soon!",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responder, err := NewMaintenanceResponder(
+				&fakeResponder{text: "Woof!"},
+				"Back soon!",
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			responder.SetEnabled(tt.enabled)
+
+			got, err := responder.Respond(context.Background(), &Request{Text: "Who is a good dog?"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Text != tt.want {
+				t.Errorf("Respond() = %v, want %v", got.Text, tt.want)
+			}
+			if responder.Enabled() != tt.enabled {
+				t.Errorf("Enabled() = %v, want %v", responder.Enabled(), tt.enabled)
+			}
+		})
+	}
+}
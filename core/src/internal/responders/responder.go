@@ -0,0 +1,114 @@
+package responders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// A responders.Request describes a message
+// that should be responded to along with the
+// context it was sent in. Fields other than
+// Text are only set when known.
+type Request struct {
+	Text        string
+	UserId      string
+	UserName    string
+	ChannelId   string
+	ChannelType string
+	Ts          string
+	ThreadTs    string
+	Locale      string
+	History     []*Message
+}
+
+// A responders.Message is a previous message
+// in the conversation of a request.
+type Message struct {
+	UserId  string
+	Text    string
+	Ts      string
+	FromBot bool
+}
+
+// A responders.Response is a reply to a
+// message along with how confident the
+// responder is in the reply, from 0 to 1.
+// A response may also suggest reactions to
+// add to the message, Block Kit blocks to
+// render in place of the text, and messages
+// the sender may have meant instead.
+type Response struct {
+	Text        string
+	Confidence  float64
+	Reactions   []string
+	Blocks      []json.RawMessage
+	Suggestions []string
+}
+
+// A Responder produces replies to messages.
+type Responder interface {
+	Respond(ctx context.Context, request *Request) (*Response, error)
+}
+
+// ErrNoResponse is returned by a Responder
+// that has nothing to say about a message.
+var ErrNoResponse = errors.New("no response")
+
+// A responders.Router delegates to the
+// Responder configured for the channel of
+// each request.
+type Router struct {
+	fallback  Responder
+	byChannel map[string]Responder
+}
+
+// responders.RouterParameters describe how
+// a new responders.Router should be created.
+// Default and ByChannel name responders in
+// Responders.
+type RouterParameters struct {
+	Responders map[string]Responder
+	Default    string
+	ByChannel  map[string]string
+}
+
+// NewRouter returns a new responders.Router
+// according to the given parameters.
+func NewRouter(params *RouterParameters) (*Router, error) {
+	fallback, ok := params.Responders[params.Default]
+	if !ok || fallback == nil {
+		return nil, fmt.Errorf("unknown default responder %q", params.Default)
+	}
+	byChannel := make(map[string]Responder)
+	for channelId, name := range params.ByChannel {
+		responder, ok := params.Responders[name]
+		if !ok || responder == nil {
+			return nil, fmt.Errorf(
+				"unknown responder %q for channel %s",
+				name,
+				channelId,
+			)
+		}
+		byChannel[channelId] = responder
+	}
+	return &Router{
+		fallback:  fallback,
+		byChannel: byChannel,
+	}, nil
+}
+
+// Respond delegates the given request to the
+// Responder configured for its channel or to
+// the default Responder.
+func (router *Router) Respond(
+	ctx context.Context,
+	request *Request,
+) (*Response, error) {
+	responder, ok := router.byChannel[request.ChannelId]
+	if !ok {
+		responder = router.fallback
+	}
+	return responder.Respond(ctx, request)
+}
@@ -0,0 +1,109 @@
+package responders
+
+import (
+	"context"
+	"testing"
+)
+
+type fakeResponder struct {
+	text string
+}
+
+func (responder *fakeResponder) Respond(
+	ctx context.Context,
+	request *Request,
+) (*Response, error) {
+	return &Response{
+		Text:       responder.text,
+		Confidence: 1,
+	}, nil
+}
+
+func TestNewRouter(t *testing.T) {
+	available := map[string]Responder{
+		"first":  &fakeResponder{text: "first"},
+		"second": &fakeResponder{text: "second"},
+	}
+	tests := []struct {
+		name    string
+		params  *RouterParameters
+		wantErr bool
+	}{
+		{
+			name: "ReturnsRouter",
+			params: &RouterParameters{
+				Responders: available,
+				Default:    "first",
+				ByChannel: map[string]string{
+					"C1": "second",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "UnknownDefault",
+			params: &RouterParameters{
+				Responders: available,
+				Default:    "third",
+			},
+			wantErr: true,
+		},
+		{
+			name: "UnknownChannelResponder",
+			params: &RouterParameters{
+				Responders: available,
+				Default:    "first",
+				ByChannel: map[string]string{
+					"C1": "third",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRouter(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRouter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRouter_Respond(t *testing.T) {
+	router, err := NewRouter(
+		&RouterParameters{
+			Responders: map[string]Responder{
+				"first":  &fakeResponder{text: "first"},
+				"second": &fakeResponder{text: "second"},
+			},
+			Default: "first",
+			ByChannel: map[string]string{
+				"C2": "second",
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		channelId string
+		want      string
+	}{
+		{"C1", "first"},
+		{"C2", "second"},
+	}
+	for _, tt := range tests {
+		response, err := router.Respond(
+			context.Background(),
+			&Request{ChannelId: tt.channelId},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Text != tt.want {
+			t.Errorf("Respond() = %v, want %v", response.Text, tt.want)
+		}
+	}
+}
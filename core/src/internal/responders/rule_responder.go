@@ -0,0 +1,96 @@
+package responders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// A Rule replies with Reply to messages
+// matching Pattern. The reply may refer to
+// submatches of the pattern, such as $1.
+type Rule struct {
+	Pattern *regexp.Regexp
+	Reply   string
+}
+
+// A RuleResponder replies to messages using
+// the first Rule that matches them.
+type RuleResponder struct {
+	rules []Rule
+}
+
+// A ruleDefinition is the JSON form of a
+// Rule in a rules file.
+type ruleDefinition struct {
+	Pattern string `json:"pattern"`
+	Reply   string `json:"reply"`
+}
+
+// NewRuleResponder returns a new RuleResponder
+// using the given rules.
+func NewRuleResponder(rules []Rule) (*RuleResponder, error) {
+	if len(rules) == 0 {
+		return nil, errors.New("missing rules")
+	}
+	return &RuleResponder{
+		rules: rules,
+	}, nil
+}
+
+// LoadRules reads rules from the JSON file at
+// the given path. The file contains an array
+// of objects with pattern and reply fields.
+// Patterns are matched case-insensitively.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var definitions []ruleDefinition
+	err = json.Unmarshal(data, &definitions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+	rules := make([]Rule, 0, len(definitions))
+	for index, definition := range definitions {
+		if definition.Pattern == "" || definition.Reply == "" {
+			return nil, fmt.Errorf("rule %d is missing a pattern or reply", index)
+		}
+		pattern, err := regexp.Compile("(?i)" + definition.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d has an invalid pattern: %w", index, err)
+		}
+		rules = append(rules, Rule{
+			Pattern: pattern,
+			Reply:   definition.Reply,
+		})
+	}
+	return rules, nil
+}
+
+// Respond replies to the given request using
+// the first matching rule or returns
+// ErrNoResponse if no rule matches.
+func (responder *RuleResponder) Respond(
+	ctx context.Context,
+	request *Request,
+) (*Response, error) {
+	text := strings.TrimSpace(request.Text)
+	for _, rule := range responder.rules {
+		match := rule.Pattern.FindStringSubmatchIndex(text)
+		if match == nil {
+			continue
+		}
+		reply := rule.Pattern.ExpandString(nil, rule.Reply, text, match)
+		return &Response{
+			Text:       string(reply),
+			Confidence: 1,
+		}, nil
+	}
+	return nil, ErrNoResponse
+}
@@ -0,0 +1,106 @@
+package responders
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		contents  string
+		wantRules int
+		wantErr   bool
+	}{
+		{
+			name:      "LoadsRules",
+			contents:  `[{"pattern": "^hi", "reply": "Hello!"}, {"pattern": "walk", "reply": "Walk?!"}]`,
+			wantRules: 2,
+			wantErr:   false,
+		},
+		{
+			name:     "InvalidJson",
+			contents: `{"pattern": "^hi"`,
+			wantErr:  true,
+		},
+		{
+			name:     "InvalidPattern",
+			contents: `[{"pattern": "(", "reply": "Hello!"}]`,
+			wantErr:  true,
+		},
+		{
+			name:     "MissingReply",
+			contents: `[{"pattern": "^hi"}]`,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "rules")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "rules.json")
+			err = ioutil.WriteFile(path, []byte(tt.contents), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rules, err := LoadRules(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadRules() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(rules) != tt.wantRules {
+				t.Errorf("LoadRules() = %v, want %v", len(rules), tt.wantRules)
+			}
+		})
+	}
+}
+
+func TestRuleResponder_Respond(t *testing.T) {
+	responder, err := NewRuleResponder(
+		[]Rule{
+			{
+				Pattern: regexp.MustCompile(`(?i)^good (\w+)`),
+				Reply:   "Good $1 to you too!",
+			},
+			{
+				Pattern: regexp.MustCompile(`(?i)treat`),
+				Reply:   "Treat?!",
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text    string
+		want    string
+		wantErr error
+	}{
+		{" Good morning", "Good morning to you too!", nil},
+		{"Who wants a TREAT", "Treat?!", nil},
+		{"sit", "", ErrNoResponse},
+	}
+	for _, tt := range tests {
+		response, err := responder.Respond(
+			context.Background(),
+			&Request{Text: tt.text},
+		)
+		if err != tt.wantErr {
+			t.Errorf("Respond() error = %v, wantErr %v", err, tt.wantErr)
+			continue
+		}
+		if err == nil && response.Text != tt.want {
+			t.Errorf("Respond() = %v, want %v", response.Text, tt.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned by a Provider that
+// does not have the named secret.
+var ErrNotFound = errors.New("secret not found")
+
+// A Provider looks up secrets by name, such
+// as slack_bot_token, returning ErrNotFound
+// if it does not have the named secret.
+type Provider interface {
+	Secret(ctx context.Context, name string) (Secret, error)
+}
+
+// A FileProvider reads each secret from the
+// file named after it in a directory, such as
+// the /run/secrets directory Docker and
+// Kubernetes mount secrets into.
+type FileProvider struct {
+	dir string
+}
+
+// An ExecProvider runs a command to look up
+// each secret, such as a password manager or
+// vault client. The name of the secret is
+// passed as the last argument and the secret
+// is read from the output of the command.
+// A command that succeeds without output
+// does not have the secret.
+type ExecProvider struct {
+	command []string
+}
+
+// NewFileProvider returns a new instance of
+// FileProvider reading the given directory.
+func NewFileProvider(dir string) (*FileProvider, error) {
+	if dir == "" {
+		return nil, errors.New("missing directory")
+	}
+	return &FileProvider{
+		dir: dir,
+	}, nil
+}
+
+// NewExecProvider returns a new instance of
+// ExecProvider running the given command
+// and arguments.
+func NewExecProvider(command []string) (*ExecProvider, error) {
+	if len(command) == 0 || command[0] == "" {
+		return nil, errors.New("missing command")
+	}
+	return &ExecProvider{
+		command: command,
+	}, nil
+}
+
+// ReadFile returns the secret held in the
+// file at the given path without surrounding
+// whitespace, such as a trailing newline.
+func ReadFile(path string) (Secret, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Secret{}, err
+	}
+	return New(strings.TrimSpace(string(data))), nil
+}
+
+// Secret reads the named secret from the
+// directory of the provider.
+func (provider *FileProvider) Secret(
+	_ context.Context,
+	name string,
+) (Secret, error) {
+	err := validateName(name)
+	if err != nil {
+		return Secret{}, err
+	}
+	secret, err := ReadFile(filepath.Join(provider.dir, name))
+	if os.IsNotExist(err) {
+		return Secret{}, ErrNotFound
+	}
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to read secret %s: %s", name, err)
+	}
+	return secret, nil
+}
+
+// Secret runs the command of the provider to
+// look up the named secret.
+func (provider *ExecProvider) Secret(
+	ctx context.Context,
+	name string,
+) (Secret, error) {
+	err := validateName(name)
+	if err != nil {
+		return Secret{}, err
+	}
+	args := make([]string, 0, len(provider.command))
+	args = append(args, provider.command[1:]...)
+	args = append(args, name)
+	cmd := exec.CommandContext(ctx, provider.command[0], args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return Secret{}, fmt.Errorf("failed to look up secret %s: %s", name, message)
+	}
+	value := strings.TrimSpace(stdout.String())
+	if value == "" {
+		return Secret{}, ErrNotFound
+	}
+	return New(value), nil
+}
+
+// validateName returns an error unless the
+// given name of a secret is a plain name, so
+// that it cannot reach outside the directory
+// of a FileProvider or pass a flag to the
+// command of an ExecProvider.
+func validateName(name string) error {
+	if name == "" ||
+		strings.ContainsAny(name, `/\`) ||
+		strings.HasPrefix(name, ".") ||
+		strings.HasPrefix(name, "-") {
+		return fmt.Errorf("invalid secret name %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package secrets
+
+import (
+	"fmt"
+)
+
+// redacted replaces the value of a non-empty
+// Secret wherever it is formatted.
+const redacted = "[redacted]"
+
+// A Secret holds a sensitive value, such as a
+// token, that is never formatted, logged or
+// encoded. Only Reveal returns the value.
+type Secret struct {
+	value string
+}
+
+// New returns a Secret holding the given value.
+func New(value string) Secret {
+	return Secret{value: value}
+}
+
+// Reveal returns the value of the Secret.
+func (secret Secret) Reveal() string {
+	return secret.value
+}
+
+// Empty reports whether the Secret holds
+// no value.
+func (secret Secret) Empty() bool {
+	return secret.value == ""
+}
+
+// String returns a placeholder in place of
+// the value of the Secret, or nothing if
+// it is empty.
+func (secret Secret) String() string {
+	if secret.Empty() {
+		return ""
+	}
+	return redacted
+}
+
+// GoString returns the placeholder for the
+// %#v verb.
+func (secret Secret) GoString() string {
+	return fmt.Sprintf("secrets.Secret(%q)", secret.String())
+}
+
+// Format writes the placeholder for every
+// verb, so that not even %x or %+v reveal
+// the value of the Secret.
+func (secret Secret) Format(state fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && state.Flag('#'):
+		_, _ = fmt.Fprint(state, secret.GoString())
+	case verb == 'q':
+		_, _ = fmt.Fprintf(state, "%q", secret.String())
+	default:
+		_, _ = fmt.Fprint(state, secret.String())
+	}
+}
+
+// MarshalText encodes the placeholder, which
+// also covers JSON and YAML encoding.
+func (secret Secret) MarshalText() ([]byte, error) {
+	return []byte(secret.String()), nil
+}
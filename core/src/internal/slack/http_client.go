@@ -1,11 +1,20 @@
 package slack
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/drewnorman/jt-slackbot/core/internal/metrics"
+	"github.com/drewnorman/jt-slackbot/core/internal/secrets"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -15,9 +24,10 @@ import (
 type HttpClient struct {
 	logger     *zap.Logger
 	apiUrl     string
-	appToken   string
-	botToken   string
+	appToken   secrets.Secret
+	botToken   secrets.Secret
 	httpClient *http.Client
+	metrics    *metrics.Metrics
 }
 
 // slack.HttpClientParameters describe how
@@ -28,8 +38,58 @@ type HttpClientParameters struct {
 	AppToken   string
 	BotToken   string
 	HttpClient *http.Client
+	Timeout    time.Duration
+	Metrics    *metrics.Metrics
 }
 
+// A slack.Identity describes the bot user
+// associated with the bot token.
+type Identity struct {
+	UserId string
+	BotId  string
+	TeamId string
+}
+
+// A slack.User describes a member of
+// the workspace.
+type User struct {
+	Id          string
+	Name        string
+	DisplayName string
+	Locale      string
+}
+
+// A slack.Channel describes a conversation.
+// Type is one of channel, private_channel,
+// mpim, or im.
+type Channel struct {
+	Id   string
+	Name string
+	Type string
+}
+
+// A slack.Message describes a message
+// in a conversation.
+type Message struct {
+	UserId string
+	BotId  string
+	Text   string
+	Ts     string
+}
+
+// tracer records spans for requests to Slack.
+var tracer = otel.Tracer("github.com/drewnorman/jt-slackbot/core/internal/slack")
+
+// maxThreadReplies limits how many replies
+// are requested per page when looking for the
+// most recent messages of a thread.
+const maxThreadReplies = 200
+
+// maxJoinedChannels limits how many channels
+// are requested when listing the channels the
+// app is a member of.
+const maxJoinedChannels = 1000
+
 // defaultTimeout specifies the timeout for
 // requests in seconds
 const defaultTimeout = time.Duration(10) * time.Second
@@ -56,17 +116,22 @@ func NewHttpClient(
 	if params.HttpClient != nil {
 		httpClient = params.HttpClient
 	} else {
+		timeout := defaultTimeout
+		if params.Timeout > 0 {
+			timeout = params.Timeout
+		}
 		httpClient = &http.Client{
-			Timeout: defaultTimeout,
+			Timeout: timeout,
 		}
 	}
 
 	return &HttpClient{
 		logger:     params.Logger,
 		apiUrl:     params.ApiUrl,
-		appToken:   params.AppToken,
-		botToken:   params.BotToken,
+		appToken:   secrets.New(params.AppToken),
+		botToken:   secrets.New(params.BotToken),
 		httpClient: httpClient,
+		metrics:    params.Metrics,
 	}, nil
 }
 
@@ -77,9 +142,11 @@ func NewHttpClient(
 // that we intend to debug reconnecting to the
 // WebSocket server.
 func (client *HttpClient) RequestWssUrl(
+	ctx context.Context,
 	debugWssReconnects bool,
 ) (string, error) {
 	data, err := client.post(
+		ctx,
 		client.appToken,
 		"apps.connections.open",
 		map[string]string{},
@@ -98,16 +165,51 @@ func (client *HttpClient) RequestWssUrl(
 	return wssUrl, nil
 }
 
+// Identity makes a request to Slack to validate
+// the bot token and determine the user and bot
+// IDs of the app.
+func (client *HttpClient) Identity(ctx context.Context) (*Identity, error) {
+	data, err := client.post(
+		ctx,
+		client.botToken,
+		"auth.test",
+		map[string]string{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	success, ok := data["ok"].(bool)
+	if !ok || !success {
+		if message, ok := data["error"].(string); ok {
+			return nil, errors.New(message)
+		}
+		return nil, errors.New("failed to validate authentication")
+	}
+	userId, ok := data["user_id"].(string)
+	if !ok {
+		return nil, errors.New("no user id in response")
+	}
+	botId, _ := data["bot_id"].(string)
+	teamId, _ := data["team_id"].(string)
+	return &Identity{
+		UserId: userId,
+		BotId:  botId,
+		TeamId: teamId,
+	}, nil
+}
+
 // JoinChannel makes a request to Slack to
 // have the app to join the channel matching
 // the given channel ID.
 func (client *HttpClient) JoinChannel(
+	ctx context.Context,
 	channelId string,
 ) error {
 	if channelId == "" {
 		return errors.New("missing channel id")
 	}
 	data, err := client.post(
+		ctx,
 		client.botToken,
 		"conversations.join",
 		map[string]string{
@@ -119,15 +221,21 @@ func (client *HttpClient) JoinChannel(
 	}
 	success, ok := data["ok"].(bool)
 	if !ok || !success {
-		return errors.New(data["error"].(string))
+		if message, ok := data["error"].(string); ok {
+			return errors.New(message)
+		}
+		return errors.New("failed to join channel")
 	}
 	return nil
 }
 
 // PublicChannels returns an array of all public
 // public channels for the workspace.
-func (client *HttpClient) PublicChannels() ([]interface{}, error) {
+func (client *HttpClient) PublicChannels(
+	ctx context.Context,
+) ([]interface{}, error) {
 	data, err := client.get(
+		ctx,
 		client.botToken,
 		"conversations.list",
 		map[string]string{
@@ -146,12 +254,146 @@ func (client *HttpClient) PublicChannels() ([]interface{}, error) {
 	return channels, nil
 }
 
+// JoinedChannels makes a request to Slack for
+// the public and private channels the app is
+// a member of.
+func (client *HttpClient) JoinedChannels(
+	ctx context.Context,
+) ([]*Channel, error) {
+	data, err := client.get(
+		ctx,
+		client.botToken,
+		"users.conversations",
+		map[string]string{
+			"exclude_archived": "true",
+			"types":            "public_channel,private_channel",
+			"limit":            strconv.Itoa(maxJoinedChannels),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	success, ok := data["ok"].(bool)
+	if !ok || !success {
+		if message, ok := data["error"].(string); ok {
+			return nil, errors.New(message)
+		}
+		return nil, errors.New("failed to list joined channels")
+	}
+	channelsData, ok := data["channels"].([]interface{})
+	if !ok {
+		return nil, errors.New("no channels in response")
+	}
+	channels := make([]*Channel, 0, len(channelsData))
+	for _, channelData := range channelsData {
+		channelMap, ok := channelData.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		channel := &Channel{
+			Type: "channel",
+		}
+		channel.Id, _ = channelMap["id"].(string)
+		channel.Name, _ = channelMap["name"].(string)
+		if isPrivate, _ := channelMap["is_private"].(bool); isPrivate {
+			channel.Type = "private_channel"
+		}
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
+
 // SendMessageToChannel makes a request to Slack
 // to send a given message on behalf of the app
-// to the channel matching the given channelId.
+// to the channel matching the given channelId
+// and returns the timestamp of the new message.
 func (client *HttpClient) SendMessageToChannel(
+	ctx context.Context,
+	message string,
+	channelId string,
+) (string, error) {
+	if message == "" {
+		return "", errors.New("missing message")
+	}
+	if channelId == "" {
+		return "", errors.New("missing channel id")
+	}
+	data, err := client.post(
+		ctx,
+		client.botToken,
+		"chat.postMessage",
+		map[string]string{
+			"text":    message,
+			"channel": channelId,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	success, ok := data["ok"].(bool)
+	if !ok || !success {
+		if message, ok := data["error"].(string); ok {
+			return "", errors.New(message)
+		}
+		return "", errors.New("failed to send message")
+	}
+	ts, _ := data["ts"].(string)
+	return ts, nil
+}
+
+// SendEphemeralMessage makes a request to Slack
+// to send a given message on behalf of the app
+// to the channel matching the given channelId
+// that is only visible to the user matching the
+// given userId.
+func (client *HttpClient) SendEphemeralMessage(
+	ctx context.Context,
+	message string,
+	channelId string,
+	userId string,
+) error {
+	if message == "" {
+		return errors.New("missing message")
+	}
+	if channelId == "" {
+		return errors.New("missing channel id")
+	}
+	if userId == "" {
+		return errors.New("missing user id")
+	}
+	data, err := client.post(
+		ctx,
+		client.botToken,
+		"chat.postEphemeral",
+		map[string]string{
+			"text":    message,
+			"channel": channelId,
+			"user":    userId,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	success, ok := data["ok"].(bool)
+	if !ok || !success {
+		if message, ok := data["error"].(string); ok {
+			return errors.New(message)
+		}
+		return errors.New("failed to send ephemeral message")
+	}
+	return nil
+}
+
+// UpdateMessage makes a request to Slack to
+// replace the text of the message matching the
+// given timestamp in the channel matching the
+// given channelId. Any blocks of the message
+// are removed.
+func (client *HttpClient) UpdateMessage(
+	ctx context.Context,
 	message string,
 	channelId string,
+	ts string,
 ) error {
 	if message == "" {
 		return errors.New("missing message")
@@ -159,32 +401,373 @@ func (client *HttpClient) SendMessageToChannel(
 	if channelId == "" {
 		return errors.New("missing channel id")
 	}
+	if ts == "" {
+		return errors.New("missing message timestamp")
+	}
+	data, err := client.post(
+		ctx,
+		client.botToken,
+		"chat.update",
+		map[string]string{
+			"text":    message,
+			"blocks":  "[]",
+			"channel": channelId,
+			"ts":      ts,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	success, ok := data["ok"].(bool)
+	if !ok || !success {
+		if message, ok := data["error"].(string); ok {
+			return errors.New(message)
+		}
+		return errors.New("failed to update message")
+	}
+	return nil
+}
+
+// DeleteMessage makes a request to Slack to
+// delete the message matching the given
+// timestamp in the channel matching the
+// given channelId.
+func (client *HttpClient) DeleteMessage(
+	ctx context.Context,
+	channelId string,
+	ts string,
+) error {
+	if channelId == "" {
+		return errors.New("missing channel id")
+	}
+	if ts == "" {
+		return errors.New("missing message timestamp")
+	}
 	data, err := client.post(
+		ctx,
+		client.botToken,
+		"chat.delete",
+		map[string]string{
+			"channel": channelId,
+			"ts":      ts,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	success, ok := data["ok"].(bool)
+	if !ok || !success {
+		if message, ok := data["error"].(string); ok {
+			return errors.New(message)
+		}
+		return errors.New("failed to delete message")
+	}
+	return nil
+}
+
+// SendBlocksToChannel makes a request to Slack
+// to send the given Block Kit blocks on behalf
+// of the app to the channel matching the given
+// channelId. The message is used as fallback
+// text for notifications. The timestamp of the
+// new message is returned.
+func (client *HttpClient) SendBlocksToChannel(
+	ctx context.Context,
+	message string,
+	blocks []json.RawMessage,
+	channelId string,
+) (string, error) {
+	if len(blocks) == 0 {
+		return "", errors.New("missing blocks")
+	}
+	if channelId == "" {
+		return "", errors.New("missing channel id")
+	}
+	blocksJson, err := json.Marshal(blocks)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode blocks: %w", err)
+	}
+	data, err := client.post(
+		ctx,
 		client.botToken,
 		"chat.postMessage",
 		map[string]string{
 			"text":    message,
+			"blocks":  string(blocksJson),
 			"channel": channelId,
 		},
 	)
+	if err != nil {
+		return "", err
+	}
+	success, ok := data["ok"].(bool)
+	if !ok || !success {
+		if message, ok := data["error"].(string); ok {
+			return "", errors.New(message)
+		}
+		return "", errors.New("failed to send message")
+	}
+	ts, _ := data["ts"].(string)
+	return ts, nil
+}
+
+// AddReaction makes a request to Slack to add
+// the reaction matching the given emoji name to
+// the message matching the given timestamp in
+// the channel matching the given channelId.
+func (client *HttpClient) AddReaction(
+	ctx context.Context,
+	name string,
+	channelId string,
+	ts string,
+) error {
+	if name == "" {
+		return errors.New("missing reaction name")
+	}
+	if channelId == "" {
+		return errors.New("missing channel id")
+	}
+	if ts == "" {
+		return errors.New("missing timestamp")
+	}
+	data, err := client.post(
+		ctx,
+		client.botToken,
+		"reactions.add",
+		map[string]string{
+			"name":      strings.Trim(name, ":"),
+			"channel":   channelId,
+			"timestamp": ts,
+		},
+	)
 	if err != nil {
 		return err
 	}
 	success, ok := data["ok"].(bool)
 	if !ok || !success {
-		if data["error"] != nil {
-			return errors.New(data["error"].(string))
+		if message, ok := data["error"].(string); ok {
+			return errors.New(message)
 		}
-		return errors.New("failed to send message")
+		return errors.New("failed to add reaction")
 	}
 	return nil
 }
 
+// UserInfo makes a request to Slack for the
+// details of the user matching the given userId.
+func (client *HttpClient) UserInfo(
+	ctx context.Context,
+	userId string,
+) (*User, error) {
+	if userId == "" {
+		return nil, errors.New("missing user id")
+	}
+	data, err := client.get(
+		ctx,
+		client.botToken,
+		"users.info",
+		map[string]string{
+			"user":           userId,
+			"include_locale": "true",
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	success, ok := data["ok"].(bool)
+	if !ok || !success {
+		if message, ok := data["error"].(string); ok {
+			return nil, errors.New(message)
+		}
+		return nil, errors.New("failed to get user info")
+	}
+	userData, ok := data["user"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("no user in response")
+	}
+	user := &User{Id: userId}
+	user.Name, _ = userData["name"].(string)
+	user.Locale, _ = userData["locale"].(string)
+	if profile, ok := userData["profile"].(map[string]interface{}); ok {
+		user.DisplayName, _ = profile["display_name"].(string)
+		if user.DisplayName == "" {
+			user.DisplayName, _ = profile["real_name"].(string)
+		}
+	}
+	if user.DisplayName == "" {
+		user.DisplayName = user.Name
+	}
+	return user, nil
+}
+
+// ChannelInfo makes a request to Slack for the
+// details of the channel matching the given
+// channelId.
+func (client *HttpClient) ChannelInfo(
+	ctx context.Context,
+	channelId string,
+) (*Channel, error) {
+	if channelId == "" {
+		return nil, errors.New("missing channel id")
+	}
+	data, err := client.get(
+		ctx,
+		client.botToken,
+		"conversations.info",
+		map[string]string{
+			"channel": channelId,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	success, ok := data["ok"].(bool)
+	if !ok || !success {
+		if message, ok := data["error"].(string); ok {
+			return nil, errors.New(message)
+		}
+		return nil, errors.New("failed to get channel info")
+	}
+	channelData, ok := data["channel"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("no channel in response")
+	}
+	name, _ := channelData["name"].(string)
+	channel := &Channel{
+		Id:   channelId,
+		Name: name,
+		Type: "channel",
+	}
+	if isIm, _ := channelData["is_im"].(bool); isIm {
+		channel.Type = "im"
+	} else if isMpim, _ := channelData["is_mpim"].(bool); isMpim {
+		channel.Type = "mpim"
+	} else if isPrivate, _ := channelData["is_private"].(bool); isPrivate {
+		channel.Type = "private_channel"
+	}
+	return channel, nil
+}
+
+// RecentMessages makes a request to Slack for
+// up to limit of the most recent messages in
+// the thread matching the given threadTs, or in
+// the channel matching the given channelId if
+// threadTs is empty. Replies are listed oldest
+// first, so every page of a thread is read to
+// reach the most recent. Messages are returned
+// oldest first.
+func (client *HttpClient) RecentMessages(
+	ctx context.Context,
+	channelId string,
+	threadTs string,
+	limit int,
+) ([]*Message, error) {
+	if channelId == "" {
+		return nil, errors.New("missing channel id")
+	}
+	if limit < 1 {
+		return nil, errors.New("limit must be at least 1")
+	}
+	if threadTs == "" {
+		messages, _, err := client.messagesPage(
+			ctx,
+			"conversations.history",
+			map[string]string{
+				"channel": channelId,
+				"limit":   strconv.Itoa(limit),
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+		if len(messages) > limit {
+			messages = messages[len(messages)-limit:]
+		}
+		return messages, nil
+	}
+
+	params := map[string]string{
+		"channel": channelId,
+		"ts":      threadTs,
+		"limit":   strconv.Itoa(maxThreadReplies),
+	}
+	var messages []*Message
+	for {
+		page, cursor, err := client.messagesPage(ctx, "conversations.replies", params)
+		if err != nil {
+			return nil, err
+		}
+		for _, message := range page {
+			if params["cursor"] != "" && message.Ts == threadTs {
+				continue
+			}
+			messages = append(messages, message)
+		}
+		if len(messages) > limit {
+			messages = messages[len(messages)-limit:]
+		}
+		if cursor == "" {
+			return messages, nil
+		}
+		params["cursor"] = cursor
+	}
+}
+
+// messagesPage makes a request to the given
+// Slack endpoint listing messages and returns
+// the messages along with the cursor of the
+// next page, which is empty on the last page.
+func (client *HttpClient) messagesPage(
+	ctx context.Context,
+	endpoint string,
+	params map[string]string,
+) ([]*Message, string, error) {
+	data, err := client.get(ctx, client.botToken, endpoint, params)
+	if err != nil {
+		return nil, "", err
+	}
+	success, ok := data["ok"].(bool)
+	if !ok || !success {
+		if message, ok := data["error"].(string); ok {
+			return nil, "", errors.New(message)
+		}
+		return nil, "", errors.New("failed to get messages")
+	}
+	messagesData, ok := data["messages"].([]interface{})
+	if !ok {
+		return nil, "", errors.New("no messages in response")
+	}
+
+	messages := make([]*Message, 0, len(messagesData))
+	for _, messageData := range messagesData {
+		fields, ok := messageData.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		message := &Message{}
+		message.UserId, _ = fields["user"].(string)
+		message.BotId, _ = fields["bot_id"].(string)
+		message.Text, _ = fields["text"].(string)
+		message.Ts, _ = fields["ts"].(string)
+		messages = append(messages, message)
+	}
+
+	var cursor string
+	if metadata, ok := data["response_metadata"].(map[string]interface{}); ok {
+		cursor, _ = metadata["next_cursor"].(string)
+	}
+	return messages, cursor, nil
+}
+
 // post makes a POST request to the Slack API
 // with the Slack authorization token and
 // returns the decoded response.
 func (client *HttpClient) post(
-	token string,
+	ctx context.Context,
+	token secrets.Secret,
 	endpoint string,
 	params map[string]string,
 ) (map[string]interface{}, error) {
@@ -192,7 +775,8 @@ func (client *HttpClient) post(
 	for key, value := range params {
 		values.Add(key, value)
 	}
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		"POST",
 		client.apiUrl+endpoint,
 		strings.NewReader(values.Encode()),
@@ -200,32 +784,23 @@ func (client *HttpClient) post(
 	if err != nil {
 		return nil, errors.New("failed to init request")
 	}
-	req.Header.Add("Authorization", "Bearer "+token)
+	req.Header.Add("Authorization", "Bearer "+token.Reveal())
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := client.httpClient.Do(req)
-	if err != nil {
-		return nil, errors.New("failed to make request")
-	}
-	defer resp.Body.Close()
-
-	decoded := new(map[string]interface{})
-	err = json.NewDecoder(resp.Body).Decode(decoded)
-	if err != nil {
-		return nil, errors.New("failed to decode response")
-	}
-	return *decoded, nil
+	return client.send(req, endpoint)
 }
 
 // get makes a GET request to the Slack API
 // with the Slack authorization token and
 // returns the decoded response.
 func (client *HttpClient) get(
-	token string,
+	ctx context.Context,
+	token secrets.Secret,
 	endpoint string,
 	params map[string]string,
 ) (map[string]interface{}, error) {
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		"GET",
 		client.apiUrl+endpoint,
 		nil,
@@ -233,23 +808,71 @@ func (client *HttpClient) get(
 	if err != nil {
 		return nil, errors.New("failed to init request")
 	}
-	req.Header.Add("Authorization", "Bearer "+token)
+	req.Header.Add("Authorization", "Bearer "+token.Reveal())
 	query := req.URL.Query()
 	for key, value := range params {
 		query.Add(key, value)
 	}
 	req.URL.RawQuery = query.Encode()
 
+	return client.send(req, endpoint)
+}
+
+// send makes the given request to the Slack API
+// method named by the endpoint and returns the
+// decoded response, recording the result of
+// the call in the metrics.
+func (client *HttpClient) send(
+	req *http.Request,
+	endpoint string,
+) (map[string]interface{}, error) {
+	ctx, span := tracer.Start(
+		req.Context(),
+		"slack "+endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("slack.method", endpoint),
+			attribute.String("http.method", req.Method),
+		),
+	)
+	req = req.WithContext(ctx)
+
+	start := time.Now()
+	code := metrics.CodeOk
+	defer func() {
+		client.metrics.SlackApiCalled(endpoint, code, time.Since(start))
+		span.SetAttributes(attribute.String("slack.code", code))
+		if code != metrics.CodeOk {
+			span.SetStatus(codes.Error, code)
+		}
+		span.End()
+	}()
+
 	resp, err := client.httpClient.Do(req)
 	if err != nil {
-		return nil, errors.New("failed to make request")
+		code = metrics.CodeRequestFailed
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode == http.StatusTooManyRequests {
+		code = metrics.CodeRateLimited
+	}
 
 	decoded := new(map[string]interface{})
 	err = json.NewDecoder(resp.Body).Decode(decoded)
 	if err != nil {
+		if code == metrics.CodeOk {
+			code = metrics.CodeBadResponse
+		}
 		return nil, errors.New("failed to decode response")
 	}
+	if success, _ := (*decoded)["ok"].(bool); !success && code == metrics.CodeOk {
+		code, _ = (*decoded)["error"].(string)
+		if code == "" {
+			code = metrics.CodeBadResponse
+		}
+	}
 	return *decoded, nil
 }
@@ -2,6 +2,7 @@ package slack
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/brianvoe/gofakeit/v6"
 	"go.uber.org/zap"
@@ -194,6 +195,7 @@ func TestClient_RequestWssUrl(t *testing.T) {
 				),
 			}
 			url, err := client.RequestWssUrl(
+				context.Background(),
 				tt.args.debugWssReconnects,
 			)
 			if (err != nil) != tt.wantErr {
@@ -216,6 +218,72 @@ func TestClient_RequestWssUrl(t *testing.T) {
 	}
 }
 
+func TestClient_Identity(t *testing.T) {
+	userId := gofakeit.UUID()
+	botId := gofakeit.UUID()
+
+	tests := []struct {
+		name    string
+		data    map[string]interface{}
+		want    *Identity
+		wantErr bool
+	}{
+		{
+			name: "ReturnsIdentity",
+			data: map[string]interface{}{
+				"ok":      true,
+				"user_id": userId,
+				"bot_id":  botId,
+			},
+			want: &Identity{
+				UserId: userId,
+				BotId:  botId,
+			},
+			wantErr: false,
+		},
+		{
+			name: "InvalidAuth",
+			data: map[string]interface{}{
+				"ok":    false,
+				"error": "invalid_auth",
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingUserId",
+			data: map[string]interface{}{
+				"ok": true,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &HttpClient{
+				logger:     fakeZapLogger(),
+				httpClient: defaultFakeHttpClient(t, tt.data),
+			}
+			identity, err := client.Identity(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf(
+					"Identity() error = %v, wantErr %v",
+					err,
+					tt.wantErr,
+				)
+				return
+			}
+			if !reflect.DeepEqual(identity, tt.want) {
+				t.Errorf(
+					"Identity() = %v, want %v",
+					identity,
+					tt.want,
+				)
+			}
+		})
+	}
+}
+
 func TestClient_JoinChannel(t *testing.T) {
 	type args struct {
 		channelId string
@@ -254,7 +322,7 @@ func TestClient_JoinChannel(t *testing.T) {
 					},
 				),
 			}
-			err := client.JoinChannel(tt.args.channelId)
+			err := client.JoinChannel(context.Background(), tt.args.channelId)
 			if (err != nil) != tt.wantErr {
 				t.Errorf(
 					"JoinChannel() error = %v, wantErr %v",
@@ -299,7 +367,7 @@ func TestClient_PublicChannels(t *testing.T) {
 					},
 				),
 			}
-			channels, err := client.PublicChannels()
+			channels, err := client.PublicChannels(context.Background())
 			if (err != nil) != tt.wantErr {
 				t.Errorf(
 					"JoinPublicChannels() error = %v, wantErr %v",
@@ -402,7 +470,8 @@ func TestClient_SendMessageToChannel(t *testing.T) {
 				logger:     fakeZapLogger(),
 				httpClient: httpClient,
 			}
-			err := client.SendMessageToChannel(
+			_, err := client.SendMessageToChannel(
+				context.Background(),
 				tt.args.message,
 				tt.args.channelId,
 			)
@@ -416,3 +485,697 @@ func TestClient_SendMessageToChannel(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_SendEphemeralMessage(t *testing.T) {
+	type args struct {
+		message   string
+		channelId string
+		userId    string
+		data      map[string]interface{}
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "SendsEphemeralMessage",
+			args: args{
+				message:   gofakeit.LoremIpsumSentence(5),
+				channelId: gofakeit.UUID(),
+				userId:    gofakeit.UUID(),
+				data: map[string]interface{}{
+					"ok": true,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingUser",
+			args: args{
+				message:   gofakeit.LoremIpsumSentence(5),
+				channelId: gofakeit.UUID(),
+			},
+			wantErr: true,
+		},
+		{
+			name: "UserNotInChannel",
+			args: args{
+				message:   gofakeit.LoremIpsumSentence(5),
+				channelId: gofakeit.UUID(),
+				userId:    gofakeit.UUID(),
+				data: map[string]interface{}{
+					"ok":    false,
+					"error": "user_not_in_channel",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &HttpClient{
+				logger:     fakeZapLogger(),
+				httpClient: defaultFakeHttpClient(t, tt.args.data),
+			}
+			err := client.SendEphemeralMessage(
+				context.Background(),
+				tt.args.message,
+				tt.args.channelId,
+				tt.args.userId,
+			)
+			if (err != nil) != tt.wantErr {
+				t.Errorf(
+					"SendEphemeralMessage() error = %v, wantErr %v",
+					err,
+					tt.wantErr,
+				)
+			}
+		})
+	}
+}
+
+func TestClient_UpdateMessage(t *testing.T) {
+	type args struct {
+		message   string
+		channelId string
+		ts        string
+		data      map[string]interface{}
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "UpdatesMessage",
+			args: args{
+				message:   gofakeit.LoremIpsumSentence(5),
+				channelId: gofakeit.UUID(),
+				ts:        "1610000000.000100",
+				data: map[string]interface{}{
+					"ok": true,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingMessage",
+			args: args{
+				channelId: gofakeit.UUID(),
+				ts:        "1610000000.000100",
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingTimestamp",
+			args: args{
+				message:   gofakeit.LoremIpsumSentence(5),
+				channelId: gofakeit.UUID(),
+			},
+			wantErr: true,
+		},
+		{
+			name: "MessageNotFound",
+			args: args{
+				message:   gofakeit.LoremIpsumSentence(5),
+				channelId: gofakeit.UUID(),
+				ts:        "1610000000.000100",
+				data: map[string]interface{}{
+					"ok":    false,
+					"error": "message_not_found",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &HttpClient{
+				logger:     fakeZapLogger(),
+				httpClient: defaultFakeHttpClient(t, tt.args.data),
+			}
+			err := client.UpdateMessage(
+				context.Background(),
+				tt.args.message,
+				tt.args.channelId,
+				tt.args.ts,
+			)
+			if (err != nil) != tt.wantErr {
+				t.Errorf(
+					"UpdateMessage() error = %v, wantErr %v",
+					err,
+					tt.wantErr,
+				)
+			}
+		})
+	}
+}
+
+func TestClient_DeleteMessage(t *testing.T) {
+	type args struct {
+		channelId string
+		ts        string
+		data      map[string]interface{}
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "DeletesMessage",
+			args: args{
+				channelId: gofakeit.UUID(),
+				ts:        "1610000000.000100",
+				data: map[string]interface{}{
+					"ok": true,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingChannel",
+			args: args{
+				ts: "1610000000.000100",
+			},
+			wantErr: true,
+		},
+		{
+			name: "MissingTimestamp",
+			args: args{
+				channelId: gofakeit.UUID(),
+			},
+			wantErr: true,
+		},
+		{
+			name: "CannotDelete",
+			args: args{
+				channelId: gofakeit.UUID(),
+				ts:        "1610000000.000100",
+				data: map[string]interface{}{
+					"ok":    false,
+					"error": "cant_delete_message",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &HttpClient{
+				logger:     fakeZapLogger(),
+				httpClient: defaultFakeHttpClient(t, tt.args.data),
+			}
+			err := client.DeleteMessage(
+				context.Background(),
+				tt.args.channelId,
+				tt.args.ts,
+			)
+			if (err != nil) != tt.wantErr {
+				t.Errorf(
+					"DeleteMessage() error = %v, wantErr %v",
+					err,
+					tt.wantErr,
+				)
+			}
+		})
+	}
+}
+
+func TestClient_SendBlocksToChannel(t *testing.T) {
+	type args struct {
+		blocks    []json.RawMessage
+		channelId string
+		data      map[string]interface{}
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "SendsBlocks",
+			args: args{
+				blocks:    []json.RawMessage{json.RawMessage(`{"type":"divider"}`)},
+				channelId: gofakeit.UUID(),
+				data: map[string]interface{}{
+					"ok": true,
+					"ts": "1610000000.000200",
+				},
+			},
+			want:    "1610000000.000200",
+			wantErr: false,
+		},
+		{
+			name: "MissingBlocks",
+			args: args{
+				channelId: gofakeit.UUID(),
+			},
+			wantErr: true,
+		},
+		{
+			name: "InvalidBlocks",
+			args: args{
+				blocks:    []json.RawMessage{json.RawMessage(`{"type":"divider"}`)},
+				channelId: gofakeit.UUID(),
+				data: map[string]interface{}{
+					"ok":    false,
+					"error": "invalid_blocks",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &HttpClient{
+				logger:     fakeZapLogger(),
+				httpClient: defaultFakeHttpClient(t, tt.args.data),
+			}
+			got, err := client.SendBlocksToChannel(
+				context.Background(),
+				gofakeit.LoremIpsumSentence(5),
+				tt.args.blocks,
+				tt.args.channelId,
+			)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SendBlocksToChannel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("SendBlocksToChannel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_AddReaction(t *testing.T) {
+	type args struct {
+		name      string
+		channelId string
+		ts        string
+		data      map[string]interface{}
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "AddsReaction",
+			args: args{
+				name:      ":dog:",
+				channelId: gofakeit.UUID(),
+				ts:        "1610000000.000100",
+				data: map[string]interface{}{
+					"ok": true,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingName",
+			args: args{
+				channelId: gofakeit.UUID(),
+				ts:        "1610000000.000100",
+			},
+			wantErr: true,
+		},
+		{
+			name: "InvalidName",
+			args: args{
+				name:      "not-an-emoji",
+				channelId: gofakeit.UUID(),
+				ts:        "1610000000.000100",
+				data: map[string]interface{}{
+					"ok":    false,
+					"error": "invalid_name",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &HttpClient{
+				logger:     fakeZapLogger(),
+				httpClient: defaultFakeHttpClient(t, tt.args.data),
+			}
+			err := client.AddReaction(
+				context.Background(),
+				tt.args.name,
+				tt.args.channelId,
+				tt.args.ts,
+			)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AddReaction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_UserInfo(t *testing.T) {
+	type args struct {
+		userId string
+		data   map[string]interface{}
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *User
+		wantErr bool
+	}{
+		{
+			name: "ReturnsDisplayName",
+			args: args{
+				userId: "U0123",
+				data: map[string]interface{}{
+					"ok": true,
+					"user": map[string]interface{}{
+						"name":   "jimmy",
+						"locale": "en-US",
+						"profile": map[string]interface{}{
+							"display_name": "Jimmy",
+							"real_name":    "Jimmy Thunder",
+						},
+					},
+				},
+			},
+			want: &User{
+				Id:          "U0123",
+				Name:        "jimmy",
+				DisplayName: "Jimmy",
+				Locale:      "en-US",
+			},
+			wantErr: false,
+		},
+		{
+			name: "FallsBackToRealName",
+			args: args{
+				userId: "U0123",
+				data: map[string]interface{}{
+					"ok": true,
+					"user": map[string]interface{}{
+						"name": "jimmy",
+						"profile": map[string]interface{}{
+							"real_name": "Jimmy Thunder",
+						},
+					},
+				},
+			},
+			want: &User{
+				Id:          "U0123",
+				Name:        "jimmy",
+				DisplayName: "Jimmy Thunder",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "MissingUserId",
+			args:    args{},
+			wantErr: true,
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				userId: "U0123",
+				data: map[string]interface{}{
+					"ok":    false,
+					"error": "user_not_found",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &HttpClient{
+				logger:     fakeZapLogger(),
+				httpClient: defaultFakeHttpClient(t, tt.args.data),
+			}
+			got, err := client.UserInfo(context.Background(), tt.args.userId)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UserInfo() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && *got != *tt.want {
+				t.Errorf("UserInfo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_ChannelInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel map[string]interface{}
+		want    string
+	}{
+		{
+			name:    "PublicChannel",
+			channel: map[string]interface{}{"is_channel": true, "name": "general"},
+			want:    "channel",
+		},
+		{
+			name:    "PrivateChannel",
+			channel: map[string]interface{}{"is_private": true},
+			want:    "private_channel",
+		},
+		{
+			name:    "DirectMessage",
+			channel: map[string]interface{}{"is_im": true, "is_private": true},
+			want:    "im",
+		},
+		{
+			name:    "GroupDirectMessage",
+			channel: map[string]interface{}{"is_mpim": true, "is_private": true},
+			want:    "mpim",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &HttpClient{
+				logger: fakeZapLogger(),
+				httpClient: defaultFakeHttpClient(
+					t,
+					map[string]interface{}{
+						"ok":      true,
+						"channel": tt.channel,
+					},
+				),
+			}
+			got, err := client.ChannelInfo(context.Background(), "C0123")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Type != tt.want {
+				t.Errorf("ChannelInfo() = %v, want %v", got.Type, tt.want)
+			}
+			if name, _ := tt.channel["name"].(string); got.Name != name {
+				t.Errorf("ChannelInfo() name = %v, want %v", got.Name, name)
+			}
+		})
+	}
+}
+
+func TestClient_JoinedChannels(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]interface{}
+		want    []Channel
+		wantErr bool
+	}{
+		{
+			name: "ListsChannels",
+			data: map[string]interface{}{
+				"ok": true,
+				"channels": []interface{}{
+					map[string]interface{}{"id": "C0123", "name": "general"},
+					map[string]interface{}{"id": "C4567", "name": "kennel", "is_private": true},
+				},
+			},
+			want: []Channel{
+				{Id: "C0123", Name: "general", Type: "channel"},
+				{Id: "C4567", Name: "kennel", Type: "private_channel"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "NotAuthed",
+			data: map[string]interface{}{
+				"ok":    false,
+				"error": "not_authed",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &HttpClient{
+				logger:     fakeZapLogger(),
+				httpClient: defaultFakeHttpClient(t, tt.data),
+			}
+			got, err := client.JoinedChannels(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("JoinedChannels() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("JoinedChannels() = %v, want %v", got, tt.want)
+				return
+			}
+			for i, channel := range got {
+				if *channel != tt.want[i] {
+					t.Errorf("JoinedChannels() = %v, want %v", *channel, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestClient_RecentMessages(t *testing.T) {
+	type args struct {
+		threadTs string
+		limit    int
+		data     map[string]interface{}
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "ChannelHistoryOldestFirst",
+			args: args{
+				limit: 2,
+				data: map[string]interface{}{
+					"ok": true,
+					"messages": []interface{}{
+						map[string]interface{}{"user": "U1", "text": "second", "ts": "2"},
+						map[string]interface{}{"user": "U2", "text": "first", "ts": "1"},
+					},
+				},
+			},
+			want:    []string{"first", "second"},
+			wantErr: false,
+		},
+		{
+			name: "ThreadRepliesMostRecent",
+			args: args{
+				threadTs: "1",
+				limit:    2,
+				data: map[string]interface{}{
+					"ok": true,
+					"messages": []interface{}{
+						map[string]interface{}{"user": "U1", "text": "first", "ts": "1"},
+						map[string]interface{}{"bot_id": "B1", "text": "second", "ts": "2"},
+						map[string]interface{}{"user": "U1", "text": "third", "ts": "3"},
+					},
+				},
+			},
+			want:    []string{"second", "third"},
+			wantErr: false,
+		},
+		{
+			name: "InvalidLimit",
+			args: args{
+				limit: 0,
+			},
+			wantErr: true,
+		},
+		{
+			name: "ChannelNotFound",
+			args: args{
+				limit: 2,
+				data: map[string]interface{}{
+					"ok":    false,
+					"error": "channel_not_found",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &HttpClient{
+				logger:     fakeZapLogger(),
+				httpClient: defaultFakeHttpClient(t, tt.args.data),
+			}
+			got, err := client.RecentMessages(context.Background(), "C0123", tt.args.threadTs, tt.args.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RecentMessages() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("RecentMessages() = %v, want %v", got, tt.want)
+			}
+			for i, message := range got {
+				if message.Text != tt.want[i] {
+					t.Errorf("RecentMessages() = %v, want %v", message.Text, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestClient_RecentMessagesPagesThroughThread(t *testing.T) {
+	pages := map[string]map[string]interface{}{
+		"": {
+			"ok": true,
+			"messages": []interface{}{
+				map[string]interface{}{"user": "U1", "text": "first", "ts": "1"},
+				map[string]interface{}{"user": "U2", "text": "second", "ts": "2"},
+			},
+			"response_metadata": map[string]interface{}{"next_cursor": "page2"},
+		},
+		"page2": {
+			"ok": true,
+			"messages": []interface{}{
+				map[string]interface{}{"user": "U1", "text": "first", "ts": "1"},
+				map[string]interface{}{"user": "U2", "text": "third", "ts": "3"},
+				map[string]interface{}{"user": "U1", "text": "fourth", "ts": "4"},
+			},
+			"response_metadata": map[string]interface{}{"next_cursor": ""},
+		},
+	}
+	var cursors []string
+	client := &HttpClient{
+		logger: fakeZapLogger(),
+		httpClient: fakeHttpClient(
+			func(req *http.Request) *http.Response {
+				cursor := req.URL.Query().Get("cursor")
+				cursors = append(cursors, cursor)
+				bodyJson, err := json.Marshal(pages[cursor])
+				if err != nil {
+					t.Fatal(err)
+				}
+				header := http.Header{}
+				header.Add("Content-Type", "application/json")
+				return &http.Response{
+					StatusCode: 200,
+					Header:     header,
+					Body:       ioutil.NopCloser(bytes.NewBuffer(bodyJson)),
+				}
+			},
+		),
+	}
+
+	got, err := client.RecentMessages(context.Background(), "C0123", "1", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"second", "third", "fourth"}
+	if len(got) != len(want) {
+		t.Fatalf("RecentMessages() = %v, want %v", got, want)
+	}
+	for i, message := range got {
+		if message.Text != want[i] {
+			t.Errorf("RecentMessages() = %v, want %v", message.Text, want[i])
+		}
+	}
+	if len(cursors) != 2 || cursors[1] != "page2" {
+		t.Errorf("RecentMessages() cursors = %v, want %v", cursors, []string{"", "page2"})
+	}
+}
@@ -0,0 +1,250 @@
+// Package mrkdwn parses Slack's message format
+// into tokens, renders them as plain text, and
+// escapes plain text for use in messages.
+package mrkdwn
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// The kinds of tokens in a message.
+const (
+	KindText = iota
+	KindUser
+	KindChannel
+	KindLink
+	KindSpecial
+	KindEmoji
+)
+
+// A Token is a piece of a message. Raw is the
+// token exactly as it appears in the message.
+// Id is the user or channel id, the URL of a
+// link, the name of a special mention, or the
+// name of an emoji, and Label is the text
+// Slack gave to display in its place, if any.
+type Token struct {
+	Kind  int
+	Raw   string
+	Id    string
+	Label string
+}
+
+// A Resolver looks up the names of the users
+// and channels matching the ids in messages.
+type Resolver interface {
+	UserName(ctx context.Context, userId string) (string, error)
+	ChannelName(ctx context.Context, channelId string) (string, error)
+}
+
+// controlPattern recognizes Slack's control
+// sequences, such as <@U123>, <#C123|general>,
+// <!here> and <https://example.com|label>.
+var controlPattern = regexp.MustCompile(
+	`<([@#!]|[a-zA-Z][a-zA-Z0-9+.\-]*:)([^<>|]*)(?:\|([^<>]*))?>`,
+)
+
+// emojiPattern recognizes emoji codes, such as
+// :wave: or :+1:, that are not part of a word
+// or a time like 10:30:45.
+var emojiPattern = regexp.MustCompile(`:[a-z0-9_+\-']+:`)
+
+// whitespacePattern recognizes runs of
+// whitespace.
+var whitespacePattern = regexp.MustCompile(`\s+`)
+
+// entities replaces the HTML entities Slack
+// uses in messages with the characters they
+// stand for.
+var entities = strings.NewReplacer(
+	"&amp;", "&",
+	"&lt;", "<",
+	"&gt;", ">",
+)
+
+// escapes replaces the characters Slack treats
+// as control characters with HTML entities.
+var escapes = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+)
+
+// Parse splits the given message into tokens.
+func Parse(message string) []Token {
+	var tokens []Token
+	position := 0
+	for _, match := range controlPattern.FindAllStringSubmatchIndex(message, -1) {
+		tokens = appendText(tokens, message[position:match[0]])
+		tokens = append(tokens, controlToken(message, match))
+		position = match[1]
+	}
+	return appendText(tokens, message[position:])
+}
+
+// Render returns the given tokens as plain text,
+// using the given resolver, if any, to name the
+// users and channels that were not labelled.
+// Emoji are rendered as their names.
+func Render(ctx context.Context, tokens []Token, resolver Resolver) string {
+	var builder strings.Builder
+	for _, token := range tokens {
+		switch token.Kind {
+		case KindText:
+			builder.WriteString(entities.Replace(token.Raw))
+		case KindUser:
+			builder.WriteString("@" + resolve(ctx, token, resolver))
+		case KindChannel:
+			builder.WriteString("#" + resolve(ctx, token, resolver))
+		case KindLink:
+			if token.Label != "" {
+				builder.WriteString(entities.Replace(token.Label))
+			} else {
+				builder.WriteString(strings.TrimPrefix(entities.Replace(token.Id), "mailto:"))
+			}
+		case KindSpecial:
+			builder.WriteString(renderSpecial(token))
+		case KindEmoji:
+			builder.WriteString(token.Id)
+		}
+	}
+	return builder.String()
+}
+
+// PlainText returns the given message as plain
+// text with whitespace collapsed, using the given
+// resolver, if any, to name users and channels.
+func PlainText(
+	ctx context.Context,
+	message string,
+	resolver Resolver,
+) string {
+	text := Render(ctx, Parse(message), resolver)
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(text, " "))
+}
+
+// Escape returns the given text with the
+// characters Slack treats as control characters
+// escaped, leaving well-formed user and channel
+// mentions, links and emoji codes intact.
+// Special mentions, such as <!here>, are
+// escaped so they cannot notify anyone.
+func Escape(text string) string {
+	var builder strings.Builder
+	for _, token := range Parse(text) {
+		switch token.Kind {
+		case KindText, KindSpecial:
+			builder.WriteString(escapes.Replace(token.Raw))
+		default:
+			builder.WriteString(token.Raw)
+		}
+	}
+	return builder.String()
+}
+
+// appendText appends the text and emoji tokens
+// found in the given text to the given tokens.
+func appendText(tokens []Token, text string) []Token {
+	position := 0
+	for _, match := range emojiPattern.FindAllStringIndex(text, -1) {
+		if !isEmoji(text, match[0], match[1]) {
+			continue
+		}
+		if match[0] > position {
+			tokens = append(tokens, Token{Kind: KindText, Raw: text[position:match[0]]})
+		}
+		raw := text[match[0]:match[1]]
+		tokens = append(
+			tokens,
+			Token{
+				Kind: KindEmoji,
+				Raw:  raw,
+				Id:   strings.Trim(raw, ":"),
+			},
+		)
+		position = match[1]
+	}
+	if position < len(text) {
+		tokens = append(tokens, Token{Kind: KindText, Raw: text[position:]})
+	}
+	return tokens
+}
+
+// isEmoji returns true if the emoji code found
+// between start and end in the given text is not
+// surrounded by letters or digits.
+func isEmoji(text string, start int, end int) bool {
+	if start > 0 && isAlphanumeric(text[start-1]) {
+		return false
+	}
+	if end < len(text) && isAlphanumeric(text[end]) {
+		return false
+	}
+	return true
+}
+
+// isAlphanumeric returns true if the given byte
+// is an ASCII letter or digit.
+func isAlphanumeric(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// controlToken returns the token for the control
+// sequence at the given match in the given message.
+func controlToken(message string, match []int) Token {
+	token := Token{Raw: message[match[0]:match[1]]}
+	prefix := message[match[2]:match[3]]
+	token.Id = message[match[4]:match[5]]
+	if match[6] >= 0 {
+		token.Label = message[match[6]:match[7]]
+	}
+	switch prefix {
+	case "@":
+		token.Kind = KindUser
+	case "#":
+		token.Kind = KindChannel
+	case "!":
+		token.Kind = KindSpecial
+	default:
+		token.Kind = KindLink
+		token.Id = prefix + token.Id
+	}
+	return token
+}
+
+// resolve returns the name of the user or
+// channel of the given token.
+func resolve(ctx context.Context, token Token, resolver Resolver) string {
+	if token.Label != "" {
+		return token.Label
+	}
+	if resolver == nil {
+		return token.Id
+	}
+	var name string
+	var err error
+	if token.Kind == KindUser {
+		name, err = resolver.UserName(ctx, token.Id)
+	} else {
+		name, err = resolver.ChannelName(ctx, token.Id)
+	}
+	if err != nil || name == "" {
+		return token.Id
+	}
+	return name
+}
+
+// renderSpecial returns the plain text of the
+// given special mention.
+func renderSpecial(token Token) string {
+	if token.Label != "" {
+		return entities.Replace(token.Label)
+	}
+	name := token.Id
+	if index := strings.Index(name, "^"); index >= 0 {
+		name = name[:index]
+	}
+	return "@" + name
+}
@@ -0,0 +1,164 @@
+package mrkdwn
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type fakeResolver struct{}
+
+func (resolver *fakeResolver) UserName(ctx context.Context, userId string) (string, error) {
+	if userId == "U1" {
+		return "rex", nil
+	}
+	return "", errors.New("user_not_found")
+}
+
+func (resolver *fakeResolver) ChannelName(ctx context.Context, channelId string) (string, error) {
+	if channelId == "C1" {
+		return "general", nil
+	}
+	return "", errors.New("channel_not_found")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    []Token
+	}{
+		{
+			name:    "Text",
+			message: "fetch &amp; sit",
+			want: []Token{
+				{Kind: KindText, Raw: "fetch &amp; sit"},
+			},
+		},
+		{
+			name:    "ControlSequences",
+			message: "<@U1> see <#C1|general> and <https://example.com|docs> <!here>",
+			want: []Token{
+				{Kind: KindUser, Raw: "<@U1>", Id: "U1"},
+				{Kind: KindText, Raw: " see "},
+				{Kind: KindChannel, Raw: "<#C1|general>", Id: "C1", Label: "general"},
+				{Kind: KindText, Raw: " and "},
+				{Kind: KindLink, Raw: "<https://example.com|docs>", Id: "https://example.com", Label: "docs"},
+				{Kind: KindText, Raw: " "},
+				{Kind: KindSpecial, Raw: "<!here>", Id: "here"},
+			},
+		},
+		{
+			name:    "Emoji",
+			message: "good boy :dog: :+1::skin-tone-2:",
+			want: []Token{
+				{Kind: KindText, Raw: "good boy "},
+				{Kind: KindEmoji, Raw: ":dog:", Id: "dog"},
+				{Kind: KindText, Raw: " "},
+				{Kind: KindEmoji, Raw: ":+1:", Id: "+1"},
+				{Kind: KindEmoji, Raw: ":skin-tone-2:", Id: "skin-tone-2"},
+			},
+		},
+		{
+			name:    "TimeIsNotEmoji",
+			message: "walk at 10:30:45",
+			want: []Token{
+				{Kind: KindText, Raw: "walk at 10:30:45"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.message)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		resolver Resolver
+		want     string
+	}{
+		{
+			name:     "ResolvesUsersAndChannels",
+			message:  "ask <@U1> in <#C1>",
+			resolver: &fakeResolver{},
+			want:     "ask @rex in #general",
+		},
+		{
+			name:     "FallsBackToIds",
+			message:  "ask <@U2> in <#C2>",
+			resolver: &fakeResolver{},
+			want:     "ask @U2 in #C2",
+		},
+		{
+			name:    "PrefersLabels",
+			message: "<!subteam^S1|@walkers> read <http://example.com|the docs> in <#C2|random>",
+			want:    "@walkers read the docs in #random",
+		},
+		{
+			name:    "Links",
+			message: "see <http://example.com> or <mailto:rex@example.com>",
+			want:    "see http://example.com or rex@example.com",
+		},
+		{
+			name:    "SpecialMentions",
+			message: "<!here> <!channel> the walk is <!date^1392734382^{date}|Feb 18th>",
+			want:    "@here @channel the walk is Feb 18th",
+		},
+		{
+			name:    "NamesEmojiAndDecodesEntities",
+			message: "  treats :bone:  &amp; toys &lt;3 :thumbsup:",
+			want:    "treats bone & toys <3 thumbsup",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PlainText(context.Background(), tt.message, tt.resolver); got != tt.want {
+				t.Errorf("PlainText() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "EscapesControlCharacters",
+			text: "treats & toys <3 > cats",
+			want: "treats &amp; toys &lt;3 &gt; cats",
+		},
+		{
+			name: "KeepsControlSequences",
+			text: "ask <@U1> about <https://example.com|R&D> :dog:",
+			want: "ask <@U1> about <https://example.com|R&D> :dog:",
+		},
+		{
+			name: "EscapesSpecialMentions",
+			text: "<!here> <!channel> <!everyone> <!subteam^S1|@walkers> walk time",
+			want: "&lt;!here&gt; &lt;!channel&gt; &lt;!everyone&gt; &lt;!subteam^S1|@walkers&gt; walk time",
+		},
+		{
+			name: "KeepsMentionsAroundSpecialMentions",
+			text: "<@U1> <!here|here> <#C1|general>",
+			want: "<@U1> &lt;!here|here&gt; <#C1|general>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Escape(tt.text); got != tt.want {
+				t.Errorf("Escape() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
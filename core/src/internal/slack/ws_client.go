@@ -1,9 +1,15 @@
 package slack
 
 import (
+	"context"
 	"errors"
 	"github.com/Jeffail/gabs/v2"
+	"github.com/drewnorman/jt-slackbot/core/internal/metrics"
+	"github.com/drewnorman/jt-slackbot/core/internal/tracing"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"time"
 )
@@ -12,14 +18,17 @@ import (
 // acknowledges them before transferring them
 // for processing.
 type WsClient struct {
-	logger     *zap.Logger
-	connection *websocket.Conn
+	logger           *zap.Logger
+	metrics          *metrics.Metrics
+	connection       *websocket.Conn
+	disconnectReason string
 }
 
 // slack.WsClientParameters describe how to
 // create a new slack.WsClient.
 type WsClientParameters struct {
-	Logger *zap.Logger
+	Logger  *zap.Logger
+	Metrics *metrics.Metrics
 }
 
 // NewWsClient returns a new slack.WsClient
@@ -31,20 +40,22 @@ func NewWsClient(
 		return nil, errors.New("missing logger")
 	}
 	return &WsClient{
-		logger: params.Logger,
+		logger:  params.Logger,
+		metrics: params.Metrics,
 	}, nil
 }
 
 // Connect dials the given WebSocket server URL
 // and stores the resulting connection.
 func (client *WsClient) Connect(
+	ctx context.Context,
 	wssUrl string,
 ) error {
 	if wssUrl == "" {
 		return errors.New("missing wss url")
 	}
 	var err error
-	client.connection, _, err = websocket.DefaultDialer.Dial(wssUrl, nil)
+	client.connection, _, err = websocket.DefaultDialer.DialContext(ctx, wssUrl, nil)
 	if err != nil {
 		return err
 	}
@@ -52,10 +63,13 @@ func (client *WsClient) Connect(
 }
 
 // Close writes a close message to the connection
-// to allow for a graceful disconnection.
+// to allow for a graceful disconnection, then
+// waits for complete to be closed until the
+// given context is done. It returns true if
+// the context was done first.
 func (client *WsClient) Close(
+	ctx context.Context,
 	complete chan struct{},
-	timeout time.Duration,
 ) (bool, error) {
 	client.logger.Debug("sending close message to wss")
 	err := client.connection.WriteMessage(
@@ -73,7 +87,7 @@ func (client *WsClient) Close(
 	select {
 	case <-complete:
 		client.logger.Debug("sent close message to wss")
-	case <-time.After(timeout):
+	case <-ctx.Done():
 		client.logger.Debug("timed out sending close message to wss")
 		timedOut = true
 	}
@@ -86,16 +100,45 @@ func (client *WsClient) Disconnect() error {
 	return client.connection.Close()
 }
 
-// Listen receives Slack events and acknowledges
-// them before sending them into the events channel.
+// DisconnectReason returns the reason given by
+// Slack the last time it asked to disconnect,
+// if any. It must not be called while the
+// WsClient is listening.
+func (client *WsClient) DisconnectReason() string {
+	return client.disconnectReason
+}
+
+// Listen receives Slack events and interactions
+// and acknowledges them before sending their
+// payloads into the events channel, until the
+// given context is done or the connection fails.
+// Events that were acknowledged are always sent,
+// so the receiver must drain the channel.
 func (client *WsClient) Listen(
+	ctx context.Context,
 	events chan map[string]interface{},
 ) {
 	defer close(events)
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = client.connection.SetReadDeadline(time.Now())
+		case <-stopped:
+		}
+	}()
+
 	for {
 		_, message, err := client.connection.ReadMessage()
+		received := time.Now()
 
 		if err != nil {
+			if ctx.Err() != nil {
+				client.logger.Debug("stopped listening to wss")
+				return
+			}
 			client.logger.Error(
 				"failed to read ws message",
 				zap.String("err", err.Error()),
@@ -117,11 +160,19 @@ func (client *WsClient) Listen(
 			client.logger.Warn("failed to determine ws message type")
 			continue
 		} else {
+			client.metrics.EnvelopeReceived(messageType)
 			switch messageType {
-			case "events_api":
+			case "events_api", "interactive":
 			case "hello":
 				client.logger.Info("received greeting from slack")
 				continue
+			case "disconnect":
+				client.disconnectReason, _ = decoded.Path("reason").Data().(string)
+				client.logger.Info(
+					"received disconnect from slack",
+					zap.String("reason", client.disconnectReason),
+				)
+				continue
 			default:
 				client.logger.Warn(
 					"unrecognized message type",
@@ -132,29 +183,64 @@ func (client *WsClient) Listen(
 		}
 		client.logger.Debug("received message of type event")
 
-		envelopeId, ok := decoded.Path("envelope_id").Data().(string)
+		event, ok := client.acknowledge(ctx, messageType, decoded, received)
 		if !ok {
-			client.logger.Warn("failed to determine envelope id")
-			continue
-		}
-
-		err = client.connection.WriteJSON(map[string]interface{}{
-			"envelope_id": envelopeId,
-		})
-		if err != nil {
-			client.logger.Warn("failed to acknowledge message")
 			continue
 		}
-		client.logger.Debug("acknowledged message")
-
-		payload := decoded.Path("payload").Data()
-		if payload == nil {
-			client.logger.Warn("failed to determine message payload")
-			continue
-		}
-		event := decoded.Path("payload").Data().(map[string]interface{})
 
 		client.logger.Debug("sending event for processing")
 		events <- event
 	}
 }
+
+// acknowledge acknowledges the given envelope of
+// the given type, received at the given time, and
+// returns its payload carrying the trace context
+// of a span covering its receipt. It returns
+// false if the envelope could not be handled.
+func (client *WsClient) acknowledge(
+	ctx context.Context,
+	messageType string,
+	decoded *gabs.Container,
+	received time.Time,
+) (map[string]interface{}, bool) {
+	ctx, span := tracer.Start(
+		ctx,
+		"slack envelope",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithTimestamp(received),
+		trace.WithAttributes(
+			attribute.String("slack.envelope_type", messageType),
+		),
+	)
+	defer span.End()
+
+	envelopeId, ok := decoded.Path("envelope_id").Data().(string)
+	if !ok {
+		client.logger.Warn("failed to determine envelope id")
+		span.SetStatus(codes.Error, "missing envelope id")
+		return nil, false
+	}
+	span.SetAttributes(attribute.String("slack.envelope_id", envelopeId))
+
+	err := client.connection.WriteJSON(map[string]interface{}{
+		"envelope_id": envelopeId,
+	})
+	if err != nil {
+		client.logger.Warn("failed to acknowledge message")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to acknowledge message")
+		return nil, false
+	}
+	client.metrics.EnvelopeAcknowledged(time.Since(received))
+	client.logger.Debug("acknowledged message")
+
+	event, ok := decoded.Path("payload").Data().(map[string]interface{})
+	if !ok {
+		client.logger.Warn("failed to determine message payload")
+		span.SetStatus(codes.Error, "missing payload")
+		return nil, false
+	}
+	tracing.Inject(ctx, event)
+	return event, true
+}
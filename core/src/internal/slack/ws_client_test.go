@@ -1,6 +1,7 @@
 package slack
 
 import (
+	"context"
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/gorilla/websocket"
 	"net/http"
@@ -113,7 +114,7 @@ func TestClient_Connect(t *testing.T) {
 				t.Errorf("Connect() error = %v, wantErr %v", err, false)
 			}
 
-			err = client.Connect(tt.args.wssUrl)
+			err = client.Connect(context.Background(), tt.args.wssUrl)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Connect() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -124,10 +125,12 @@ func TestClient_Connect(t *testing.T) {
 func TestClient_Close(t *testing.T) {
 	fakeServer, wssUrl := fakeWebsocketServer(
 		func(w http.ResponseWriter, r *http.Request) {
-			_, err := wsUpgrader.Upgrade(w, r, nil)
+			conn, err := wsUpgrader.Upgrade(w, r, nil)
 			if err != nil {
 				t.Errorf("Connect() error = %v, wantErr %v", err, false)
+				return
 			}
+			_, _, _ = conn.ReadMessage()
 		},
 	)
 	defer fakeServer.Close()
@@ -173,7 +176,7 @@ func TestClient_Close(t *testing.T) {
 				t.Errorf("Close() error = %v, wantErr %v", err, false)
 			}
 
-			err = client.Connect(wssUrl)
+			err = client.Connect(context.Background(), wssUrl)
 			if err != nil {
 				t.Errorf("Close() error = %v, wantErr %v", err, false)
 			}
@@ -184,7 +187,13 @@ func TestClient_Close(t *testing.T) {
 				defer close(tt.args.complete)
 			}
 
-			timedOut, err := client.Close(tt.args.complete, 0)
+			ctx, cancel := context.WithCancel(context.Background())
+			if !tt.args.closeComplete {
+				cancel()
+			} else {
+				defer cancel()
+			}
+			timedOut, err := client.Close(ctx, tt.args.complete)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Close() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -215,9 +224,10 @@ func TestClient_Listen(t *testing.T) {
 		closeAfterWrite bool
 	}
 	tests := []struct {
-		name string
-		args args
-		want map[string]interface{}
+		name                 string
+		args                 args
+		want                 map[string]interface{}
+		wantDisconnectReason string
 	}{
 		{
 			name: "Listens",
@@ -233,6 +243,20 @@ func TestClient_Listen(t *testing.T) {
 				closeAfterWrite: false,
 			},
 		},
+		{
+			name: "ListensForInteractions",
+			args: args{
+				events: make(chan map[string]interface{}),
+				fakeEvents: []map[string]interface{}{
+					{
+						"type":        "interactive",
+						"envelope_id": gofakeit.UUID(),
+						"payload":     map[string]interface{}{},
+					},
+				},
+				closeAfterWrite: false,
+			},
+		},
 		{
 			name: "InvalidJsonResponse",
 			args: args{
@@ -267,6 +291,20 @@ func TestClient_Listen(t *testing.T) {
 				closeAfterWrite: true,
 			},
 		},
+		{
+			name: "MessageTypeDisconnect",
+			args: args{
+				events: make(chan map[string]interface{}),
+				fakeEvents: []map[string]interface{}{
+					{
+						"type":   "disconnect",
+						"reason": "refresh_requested",
+					},
+				},
+				closeAfterWrite: true,
+			},
+			wantDisconnectReason: "refresh_requested",
+		},
 		{
 			name: "MissingEnvelopeId",
 			args: args{
@@ -306,12 +344,12 @@ func TestClient_Listen(t *testing.T) {
 				t.Errorf("Close() error = %v, wantErr %v", err, false)
 			}
 
-			err = client.Connect(wssUrl)
+			err = client.Connect(context.Background(), wssUrl)
 			if err != nil {
 				t.Errorf("Close() error = %v, wantErr %v", err, false)
 			}
 
-			go client.Listen(tt.args.events)
+			go client.Listen(context.Background(), tt.args.events)
 
 			if tt.args.invalidResponse {
 				err = conn.WriteMessage(websocket.TextMessage, []byte(""))
@@ -334,6 +372,51 @@ func TestClient_Listen(t *testing.T) {
 			case <-time.After(10 * time.Millisecond):
 				t.Errorf("Close() got = %v, want %v", event, tt.want)
 			}
+
+			if tt.wantDisconnectReason != "" && client.DisconnectReason() != tt.wantDisconnectReason {
+				t.Errorf("DisconnectReason() = %v, want %v", client.DisconnectReason(), tt.wantDisconnectReason)
+			}
 		})
 	}
 }
+
+func TestClient_ListenStopsWhenCancelled(t *testing.T) {
+	fakeServer, wssUrl := fakeWebsocketServer(
+		func(w http.ResponseWriter, r *http.Request) {
+			conn, err := wsUpgrader.Upgrade(w, r, nil)
+			if err != nil {
+				t.Errorf("Connect() error = %v, wantErr %v", err, false)
+				return
+			}
+			_, _, _ = conn.ReadMessage()
+		},
+	)
+	defer fakeServer.Close()
+
+	client, err := NewWsClient(
+		WsClientParameters{
+			Logger: fakeZapLogger(),
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.Connect(context.Background(), wssUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan map[string]interface{})
+	go client.Listen(ctx, events)
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Errorf("Listen() sent an event, want closed events")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Listen() did not stop after cancellation")
+	}
+}
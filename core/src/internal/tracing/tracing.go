@@ -0,0 +1,181 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"os"
+	"path/filepath"
+)
+
+// A tracing.Provider exports the spans recorded
+// through the global OpenTelemetry tracer
+// provider until it is shut down.
+type Provider struct {
+	tracerProvider *sdktrace.TracerProvider
+	file           *os.File
+}
+
+// tracing.ProviderParameters describe how to
+// create a new tracing.Provider. The OTLP
+// exporter is configured by the standard
+// OTEL_EXPORTER_OTLP_* environment variables.
+type ProviderParameters struct {
+	Exporter    string
+	FilePath    string
+	ServiceName string
+}
+
+// Names of the exporters that spans may be
+// sent to.
+const (
+	ExporterNone   = "none"
+	ExporterOtlp   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// defaultServiceName names the service that
+// records spans if none is specified.
+const defaultServiceName = "jt-slackbot-core"
+
+// carrierKey is the key under which the trace
+// context of an event is carried in its payload
+// from the WebSocket client to the handler.
+const carrierKey = "jt_trace_context"
+
+// NewProvider returns a new tracing.Provider
+// according to the given parameters and installs
+// it, along with the W3C trace context
+// propagator, as the global tracer provider.
+// With no exporter, spans are not recorded.
+func NewProvider(params *ProviderParameters) (*Provider, error) {
+	provider := &Provider{}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch params.Exporter {
+	case "", ExporterNone:
+		return provider, nil
+	case ExporterOtlp:
+		exporter, err = otlptracehttp.New(context.Background())
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		if params.FilePath == "" {
+			return nil, errors.New("missing tracing file path")
+		}
+		err = os.MkdirAll(filepath.Dir(params.FilePath), 0755)
+		if err != nil {
+			return nil, err
+		}
+		provider.file, err = os.OpenFile(
+			params.FilePath,
+			os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+			0644,
+		)
+		if err != nil {
+			return nil, err
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(provider.file))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", params.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := defaultServiceName
+	if params.ServiceName != "" {
+		serviceName = params.ServiceName
+	}
+	provider.tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(
+			resource.NewWithAttributes(
+				semconv.SchemaURL,
+				semconv.ServiceNameKey.String(serviceName),
+			),
+		),
+	)
+	otel.SetTracerProvider(provider.tracerProvider)
+	otel.SetTextMapPropagator(
+		propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		),
+	)
+	return provider, nil
+}
+
+// Shutdown exports any remaining spans and
+// stops the Provider.
+func (provider *Provider) Shutdown(ctx context.Context) error {
+	if provider.tracerProvider == nil {
+		return nil
+	}
+	err := provider.tracerProvider.Shutdown(ctx)
+	if provider.file != nil {
+		closeErr := provider.file.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Inject stores the trace context of the given
+// context in the given event payload.
+func Inject(ctx context.Context, event map[string]interface{}) {
+	carrier := eventCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return
+	}
+	event[carrierKey] = map[string]interface{}(carrier)
+}
+
+// Extract returns a copy of the given context
+// carrying the trace context stored in the given
+// event payload, if any.
+func Extract(ctx context.Context, event map[string]interface{}) context.Context {
+	encoded, ok := event[carrierKey].(map[string]interface{})
+	if !ok {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, eventCarrier(encoded))
+}
+
+// An eventCarrier carries a trace context in
+// an event payload, which keeps its values as
+// interfaces so that it survives being stored
+// as JSON.
+type eventCarrier map[string]interface{}
+
+// Get returns the value associated with
+// the given key.
+func (carrier eventCarrier) Get(key string) string {
+	value, _ := carrier[key].(string)
+	return value
+}
+
+// Set stores the given key-value pair.
+func (carrier eventCarrier) Set(key string, value string) {
+	carrier[key] = value
+}
+
+// Keys lists the keys stored in the carrier.
+func (carrier eventCarrier) Keys() []string {
+	keys := make([]string, 0, len(carrier))
+	for key := range carrier {
+		keys = append(keys, key)
+	}
+	return keys
+}
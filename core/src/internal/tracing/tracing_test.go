@@ -0,0 +1,141 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewProvider(t *testing.T) {
+	type args struct {
+		params *ProviderParameters
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "NoExporter",
+			args: args{
+				params: &ProviderParameters{},
+			},
+			wantErr: false,
+		},
+		{
+			name: "OtlpExporter",
+			args: args{
+				params: &ProviderParameters{
+					Exporter: ExporterOtlp,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "MissingFilePath",
+			args: args{
+				params: &ProviderParameters{
+					Exporter: ExporterFile,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "UnknownExporter",
+			args: args{
+				params: &ProviderParameters{
+					Exporter: "carrier-pigeon",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewProvider(tt.args.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewProvider() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			err = provider.Shutdown(context.Background())
+			if err != nil {
+				t.Errorf("Shutdown() error = %v, wantErr %v", err, false)
+			}
+		})
+	}
+}
+
+func TestProvider_ExportsToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces", "spans.jsonl")
+	provider, err := NewProvider(
+		&ProviderParameters{
+			Exporter: ExporterFile,
+			FilePath: path,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "fetch")
+	span.End()
+
+	err = provider.Shutdown(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(contents), `"Name":"fetch"`) {
+		t.Errorf("Shutdown() exported = %v, want %v", string(contents), "fetch")
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	provider, err := NewProvider(
+		&ProviderParameters{
+			Exporter: ExporterFile,
+			FilePath: filepath.Join(t.TempDir(), "spans.jsonl"),
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer provider.Shutdown(context.Background())
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "envelope")
+	defer span.End()
+
+	event := map[string]interface{}{
+		"event_id": "Ev0123",
+	}
+	Inject(ctx, event)
+
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	err = json.Unmarshal(encoded, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	extracted := trace.SpanContextFromContext(Extract(context.Background(), decoded))
+	if extracted.TraceID() != span.SpanContext().TraceID() {
+		t.Errorf("Extract() = %v, want %v", extracted.TraceID(), span.SpanContext().TraceID())
+	}
+	if !extracted.IsRemote() {
+		t.Errorf("Extract() remote = %v, want %v", extracted.IsRemote(), true)
+	}
+}
@@ -2,6 +2,7 @@
 import os
 
 from chatterbot import ChatBot
+from chatterbot.conversation import Statement
 from chatterbot.trainers import ChatterBotCorpusTrainer
 from flask import Flask, request, jsonify
 
@@ -16,15 +17,70 @@ trainer = ChatterBotCorpusTrainer(english_bot)
 trainer.train('chatterbot-corpus.chatterbot_corpus.data.english')
 
 
+@server.route('/healthz', methods=['GET'])
+def healthz():
+    return jsonify({"ok": True})
+
+
 @server.route('/converse', methods=['POST'])
 def converse():
+    # Version 2 requests also carry a session_id, user,
+    # channel, history and locale; only the message is
+    # needed to find a response.
     user_text = request.json['message']
+    response = english_bot.get_response(user_text)
     return jsonify(
         {
-            "reply": str(english_bot.get_response(user_text)),
+            "reply": str(response),
+            "confidence": response.confidence,
         },
     )
 
 
+@server.route('/feedback', methods=['POST'])
+def feedback():
+    # Well received replies are learned as responses
+    # to the message; poorly received replies are left
+    # in the bot's log for corpus curation.
+    body = request.json
+    if body['label'] == 'positive':
+        english_bot.learn_response(
+            Statement(text=body['reply']),
+            Statement(text=body['message']),
+        )
+    return jsonify({"ok": True})
+
+
+def forget_responses(statement_text):
+    # Taught answers are stored as responses to the
+    # statement, so forgetting the statement removes
+    # every response to it. Returns how many were
+    # removed.
+    storage = english_bot.storage
+    StatementModel = storage.get_model('statement')
+    session = storage.Session()
+    removed = session.query(StatementModel).filter(
+        StatementModel.in_response_to == statement_text,
+    ).delete(synchronize_session=False)
+    storage._session_finish(session)
+    return removed
+
+
+@server.route('/train', methods=['POST'])
+def train():
+    body = request.json
+    if body['action'] == 'teach':
+        english_bot.learn_response(
+            Statement(text=body['response']),
+            Statement(text=body['statement']),
+        )
+    elif body['action'] == 'forget':
+        if forget_responses(body['statement']) == 0:
+            return jsonify({"ok": False, "error": "not_found"}), 404
+    else:
+        return jsonify({"ok": False, "error": "unknown_action"}), 400
+    return jsonify({"ok": True})
+
+
 if __name__ == "__main__":
     server.run(debug=(os.getenv('DEBUG_SERVER', False)))